@@ -0,0 +1,50 @@
+package bookshelf
+
+import "time"
+
+// BackupVersion is the version of the Backup format. Restoring rejects
+// backups of other versions.
+const BackupVersion = 1
+
+// Backup holds everything a user has access to. IDs are the ones of the
+// instance the backup was taken on, entries and members refer to them.
+type Backup struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Lists     []BackupList   `json:"lists"`
+	Books     []Book         `json:"books"`
+	Entries   []BackupEntry  `json:"entries"`
+	Members   []BackupMember `json:"members"`
+}
+
+// BackupList is a list together with the role the user had in it.
+type BackupList struct {
+	List
+	Role string `json:"role"`
+}
+
+// BackupEntry puts a book into a list.
+type BackupEntry struct {
+	ListID int `json:"list_id"`
+	BookID int `json:"book_id"`
+}
+
+// BackupMember is another member of a list. Users are referred to by name,
+// their IDs differ between instances.
+type BackupMember struct {
+	ListID   int    `json:"list_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// RestoreResult maps the IDs of the backup to the IDs the lists and books
+// have after the restore. Lists and books that already existed are reused.
+type RestoreResult struct {
+	ListIDs        map[int]int `json:"list_ids"`
+	BookIDs        map[int]int `json:"book_ids"`
+	ListsCreated   int         `json:"lists_created"`
+	BooksCreated   int         `json:"books_created"`
+	Entries        int         `json:"entries"`
+	Members        int         `json:"members"`
+	SkippedMembers []string    `json:"skipped_members"`
+}
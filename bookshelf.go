@@ -1,7 +1,5 @@
 package bookshelf
 
-import "errors"
-
 type List struct {
 	ID          int    `json:"id" db:"id"`
 	Title       string `json:"title" db:"title" validate:"required"`
@@ -12,15 +10,84 @@ type UsersList struct {
 	ID     int
 	UserID int
 	ListID int
+	Role   string
+}
+
+// List member roles. Viewers can only read a list and its books, editors can
+// also add and change books, owners can delete the list and manage members.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// HasRole reports whether role grants at least the permissions of required.
+func HasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required] && roleRanks[role] > 0
+}
+
+type ListMember struct {
+	UserID   int    `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Role     string `json:"role" db:"role"`
+}
+
+type AddListMemberInput struct {
+	Username string `json:"username" validate:"required"`
+	Role     string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+type UpdateListMemberInput struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
 }
 
+// Book is a book in one or more lists. UserID is the user who created the
+// book, ISBN is a normalized ISBN-13 unique among the books of that user.
 type Book struct {
 	ID              int    `json:"id" db:"id"`
+	UserID          int    `json:"-" db:"user_id"`
 	Title           string `json:"title" db:"title"`
 	Author          string `json:"author" db:"author"`
 	Publisher       string `json:"publisher" db:"publisher"`
 	PublicationYear int    `json:"publication_year" db:"publication_year"`
 	PageCount       int    `json:"page_count" db:"page_count"`
+	ISBN            string `json:"isbn,omitempty" db:"isbn"`
+}
+
+// BookSuggestion is a book resembling a possibly misspelled title or author.
+// Similarity ranges from 0 to 1.
+type BookSuggestion struct {
+	Book
+	Similarity float64 `json:"similarity"`
+}
+
+const DefaultSuggestLimit = 10
+
+// DuplicateGroup holds books that look like copies of the same book. Key is
+// the normalized value they were matched by, either the title and author or
+// the ISBN prefixed with "isbn:".
+type DuplicateGroup struct {
+	Key   string `json:"key"`
+	Books []Book `json:"books"`
+}
+
+// MergeBooksInput lists the books to merge. CanonicalID must be one of
+// BookIDs, the oldest book is kept when it is omitted.
+type MergeBooksInput struct {
+	CanonicalID int   `json:"canonical_id"`
+	BookIDs     []int `json:"book_ids" validate:"required,min=2"`
+}
+
+type MergeResult struct {
+	CanonicalID int   `json:"canonical_id"`
+	MergedIDs   []int `json:"merged_ids"`
+	ListIDs     []int `json:"list_ids"`
 }
 
 type ListsBook struct {
@@ -36,15 +103,20 @@ type UpdateListInput struct {
 
 func (i UpdateListInput) Validate() error {
 	if i.Title == nil && i.Description == nil {
-		return errors.New("update structure has no values")
+		return NewError(ErrValidation, "empty_update", "update structure has no values")
 	}
 	return nil
 }
 
+type MoveBookInput struct {
+	ListID int `json:"list_id" validate:"required"`
+}
+
 type UpdateBookInput struct {
 	Title           *string `json:"title"`
 	Author          *string `json:"author"`
 	Publisher       *string `json:"publisher"`
 	PublicationYear *int    `json:"publication_year"`
 	PageCount       *int    `json:"page_count"`
+	ISBN            *string `json:"isbn"`
 }
@@ -5,8 +5,6 @@ import (
 	"bookshelf-api/pkg/config"
 	"bookshelf-api/pkg/handler"
 	"bookshelf-api/pkg/service"
-	"bookshelf-api/pkg/storage"
-	"bookshelf-api/pkg/storage/postgres"
 	"context"
 	"log/slog"
 	"os"
@@ -34,24 +32,30 @@ const (
 func main() {
 	cfg := config.MustLoad()
 	log := setupLogger(cfg.Env)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, log, os.Args[2:]))
+	}
 	log.Info(
 		"starting bookshelf",
 		slog.String("env", cfg.Env),
 		slog.String("version", "1.0"),
 	)
-	db, err := postgres.New(cfg)
+	repos, closeStorage, err := openStorage(cfg, log)
 	if err != nil {
 		log.Error("failed to init storage", slog.String("err", err.Error()))
 		os.Exit(1)
 	}
-	repos := storage.New(db)
-	services := service.New(repos)
+	services, err := service.New(repos, cfg)
+	if err != nil {
+		log.Error("failed to init services", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
 	handlers := handler.New(services)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 	srv := new(bookshelf.Server)
 	go func() {
-		if err := srv.Run(cfg, handlers.InitRoutes(log)); err != nil {
+		if err := srv.Run(cfg, handlers.InitRoutes(log, cfg.HTTPServer)); err != nil {
 			log.Error("failed to start server")
 		}
 	}()
@@ -67,7 +71,7 @@ func main() {
 		log.Error("server shutdown failed", slog.String("err", err.Error()))
 	}
 
-	if err := db.Close(); err != nil {
+	if err := closeStorage(); err != nil {
 		log.Error("failed to close database", slog.String("err", err.Error()))
 	}
 }
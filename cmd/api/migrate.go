@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bookshelf-api/db"
+	"bookshelf-api/pkg/config"
+	"bookshelf-api/pkg/storage/postgres"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: api migrate up|down|status|to N|force N"
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(cfg config.Config, log *slog.Logger, args []string) int {
+	if cfg.Storage.Driver != "postgres" {
+		log.Error("migrate only manages the postgres storage, sqlite migrates on start", slog.String("driver", cfg.Storage.Driver))
+		return 1
+	}
+	conn, err := postgres.New(cfg)
+	if err != nil {
+		log.Error("failed to init storage", slog.String("err", err.Error()))
+		return 1
+	}
+	defer conn.Close()
+
+	migrator, err := newMigrator(conn)
+	if err != nil {
+		log.Error("failed to load migrations", slog.String("err", err.Error()))
+		return 1
+	}
+
+	var done []db.Migration
+	action := "migrated"
+	switch {
+	case len(args) == 1 && args[0] == "up":
+		done, err = migrator.Up()
+	case len(args) == 1 && args[0] == "down":
+		done, err = migrator.Down()
+	case len(args) == 1 && args[0] == "status":
+		err = printMigrationStatus(os.Stdout, migrator)
+	case len(args) == 2 && (args[0] == "to" || args[0] == "force"):
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			err = errors.New("version must be a non-negative number")
+			break
+		}
+		if args[0] == "force" {
+			action = "recorded"
+			done, err = migrator.Force(version)
+			break
+		}
+		done, err = migrator.To(version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	for _, m := range done {
+		log.Info(action, slog.Int("version", m.Version), slog.String("name", m.Name))
+	}
+	if err != nil {
+		log.Error("migration failed", slog.String("err", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// migrateUp applies the pending migrations on start when auto_migrate is
+// set.
+func migrateUp(log *slog.Logger, conn *sql.DB) error {
+	migrator, err := newMigrator(conn)
+	if err != nil {
+		return err
+	}
+	done, err := migrator.Up()
+	for _, m := range done {
+		log.Info("migrated", slog.Int("version", m.Version), slog.String("name", m.Name))
+	}
+	return err
+}
+
+func newMigrator(conn *sql.DB) (*postgres.Migrator, error) {
+	migrations, err := db.Load(db.Migrations)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewMigrator(conn, migrations), nil
+}
+
+func printMigrationStatus(w io.Writer, migrator *postgres.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, s := range status {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bookshelf-api/pkg/config"
+	"bookshelf-api/pkg/storage"
+	"bookshelf-api/pkg/storage/memory"
+	"bookshelf-api/pkg/storage/postgres"
+	"bookshelf-api/pkg/storage/sqlite"
+	"fmt"
+	"log/slog"
+)
+
+// openStorage sets up the storage driver of the config. The returned function
+// releases the database connection.
+func openStorage(cfg config.Config, log *slog.Logger) (*storage.Storage, func() error, error) {
+	switch cfg.Storage.Driver {
+	case "postgres":
+		db, err := postgres.New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.AutoMigrate {
+			if err := migrateUp(log, db); err != nil {
+				db.Close()
+				return nil, nil, fmt.Errorf("migrate: %w", err)
+			}
+		}
+		return storage.New(db), db.Close, nil
+	case "sqlite":
+		db, err := sqlite.New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		done, err := sqlite.Migrate(db)
+		for _, m := range done {
+			log.Info("migrated", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+		return storage.NewSQLite(db), db.Close, nil
+	case "memory":
+		log.Warn("using in-memory storage, data is lost on shutdown")
+		return storage.NewMemory(memory.New()), func() error { return nil }, nil
+	}
+	return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+}
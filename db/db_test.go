@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "OK",
+			fsys: fstest.MapFS{
+				"migrations/000002_books.up.sql":   {Data: []byte("CREATE TABLE books();")},
+				"migrations/000001_init.down.sql":  {Data: []byte("DROP TABLE users;")},
+				"migrations/000001_init.up.sql":    {Data: []byte("CREATE TABLE users();")},
+				"migrations/000010_later.up.sql":   {Data: []byte("SELECT 1;")},
+				"migrations/000010_later.down.sql": {Data: []byte("SELECT 2;")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "init", Up: "CREATE TABLE users();", Down: "DROP TABLE users;"},
+				{Version: 2, Name: "books", Up: "CREATE TABLE books();"},
+				{Version: 10, Name: "later", Up: "SELECT 1;", Down: "SELECT 2;"},
+			},
+		},
+		{
+			name: "Invalid name",
+			fsys: fstest.MapFS{
+				"migrations/init.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "migration migrations/init.sql: invalid file name",
+		},
+		{
+			name: "Missing up",
+			fsys: fstest.MapFS{
+				"migrations/000001_init.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "migration 1_init: missing up file",
+		},
+		{
+			name: "Conflicting names",
+			fsys: fstest.MapFS{
+				"migrations/000001_init.up.sql":  {Data: []byte("SELECT 1;")},
+				"migrations/000001_other.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "migration migrations/000001_other.up.sql: version 1 is also named init",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	migrations, err := Load(Migrations)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Down, "migration %d has no down file", m.Version)
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE users ADD CONSTRAINT users_password_hash_key UNIQUE (password_hash);
@@ -0,0 +1,20 @@
+CREATE TABLE refresh_tokens
+(
+    id serial primary key,
+    user_id int not null,
+    family_id varchar(64) not null,
+    token_hash varchar(64) not null unique,
+    expires_at timestamptz not null,
+    used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now(),
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+
+CREATE TABLE revoked_tokens
+(
+    jti varchar(64) primary key,
+    expires_at timestamptz not null
+);
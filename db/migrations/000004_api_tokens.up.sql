@@ -0,0 +1,13 @@
+CREATE TABLE api_tokens
+(
+    id serial primary key,
+    user_id int not null,
+    name varchar(255) not null,
+    prefix varchar(16) not null,
+    token_hash varchar(64) not null unique,
+    scopes text[] not null,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    created_at timestamptz not null default now(),
+    foreign key (user_id) references users(id) on delete cascade
+);
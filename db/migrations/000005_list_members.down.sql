@@ -0,0 +1,3 @@
+DELETE FROM users_lists WHERE role <> 'owner';
+
+ALTER TABLE users_lists DROP COLUMN role;
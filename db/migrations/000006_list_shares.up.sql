@@ -0,0 +1,12 @@
+CREATE TABLE list_shares
+(
+    id serial primary key,
+    list_id int not null,
+    created_by int not null,
+    token_hash varchar(64) not null unique,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now(),
+    foreign key (list_id) references lists(id) on delete cascade,
+    foreign key (created_by) references users(id) on delete cascade
+);
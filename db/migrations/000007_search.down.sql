@@ -0,0 +1,7 @@
+DROP INDEX lists_search_idx;
+
+ALTER TABLE lists DROP COLUMN search;
+
+DROP INDEX books_search_idx;
+
+ALTER TABLE books DROP COLUMN search;
@@ -0,0 +1,3 @@
+DROP INDEX books_author_trgm_idx;
+
+DROP INDEX books_title_trgm_idx;
@@ -0,0 +1,7 @@
+DROP INDEX books_isbn_idx;
+
+DROP INDEX books_user_isbn_key;
+
+ALTER TABLE books DROP COLUMN isbn;
+
+ALTER TABLE books DROP COLUMN user_id;
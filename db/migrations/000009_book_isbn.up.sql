@@ -0,0 +1,14 @@
+ALTER TABLE books ADD COLUMN user_id int references users(id) on delete set null;
+
+UPDATE books b SET user_id = (
+    SELECT ul.user_id FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id
+    WHERE lb.book_id = b.id
+    ORDER BY CASE ul.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, ul.user_id
+    LIMIT 1
+);
+
+ALTER TABLE books ADD COLUMN isbn char(13) CHECK (isbn ~ '^97[89][0-9]{10}$');
+
+CREATE UNIQUE INDEX books_user_isbn_key ON books (user_id, isbn);
+
+CREATE INDEX books_isbn_idx ON books (isbn);
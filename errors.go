@@ -0,0 +1,71 @@
+package bookshelf
+
+import "errors"
+
+// Error kinds shared by the storage and service layers. Handlers translate
+// them into HTTP status codes, anything else is an internal error.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrForbidden  = errors.New("access denied")
+	ErrConflict   = errors.New("already exists")
+	ErrValidation = errors.New("invalid input")
+	ErrUpstream   = errors.New("upstream service failed")
+)
+
+var kindCodes = map[error]string{
+	ErrNotFound:   "not_found",
+	ErrForbidden:  "forbidden",
+	ErrConflict:   "conflict",
+	ErrValidation: "validation_failed",
+	ErrUpstream:   "upstream_failed",
+}
+
+// Error is an error of one of the kinds above with a stable machine readable
+// code and a message that is safe to show to the client.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Message returns the client facing message of err if it is one of the
+// known kinds.
+func Message(err error) (string, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message, true
+	}
+	for kind := range kindCodes {
+		if errors.Is(err, kind) {
+			return kind.Error(), true
+		}
+	}
+	return "", false
+}
+
+// Code returns the machine readable code of err, or an empty string if err
+// is not one of the known kinds.
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		return e.Code
+	}
+	for kind, code := range kindCodes {
+		if errors.Is(err, kind) {
+			return code
+		}
+	}
+	return ""
+}
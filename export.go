@@ -0,0 +1,23 @@
+package bookshelf
+
+// ExportVersion is the version of the ExportDocument format. Importers reject
+// documents of other versions.
+const ExportVersion = 1
+
+// ExportRow is a list together with one of its books. A list with several
+// books yields one row per book, an empty list a single row without Book.
+type ExportRow struct {
+	List List  `json:"list"`
+	Book *Book `json:"book,omitempty"`
+}
+
+// ExportDocument is the JSON export of an account, it can be imported again.
+type ExportDocument struct {
+	Version int          `json:"version"`
+	Lists   []ExportList `json:"lists"`
+}
+
+type ExportList struct {
+	List
+	Books []Book `json:"books"`
+}
@@ -0,0 +1,36 @@
+package bookshelf
+
+import "time"
+
+// Import job states. A job is failed only when the file as a whole could not
+// be processed, errors of single rows are reported in Errors.
+const (
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// ImportJob reports the progress of an import running in the background.
+// Rows that added an existing book to lists instead of creating it are
+// counted as skipped.
+type ImportJob struct {
+	ID         string           `json:"id"`
+	UserID     int              `json:"-"`
+	Source     string           `json:"source"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Processed  int              `json:"processed"`
+	Created    int              `json:"created"`
+	Skipped    int              `json:"skipped"`
+	Failed     int              `json:"failed"`
+	Errors     []ImportRowError `json:"errors"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// ImportRowError is the error of a single row. Row counts from 1 for the
+// first record after the header, row 0 is an error of the whole import.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
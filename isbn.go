@@ -0,0 +1,75 @@
+package bookshelf
+
+import "strings"
+
+var ErrInvalidISBN = NewError(ErrValidation, "invalid_isbn", "invalid ISBN")
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13 and returns it as a bare
+// ISBN-13. Hyphens, spaces, an "ISBN" prefix and the 2 or 5 digit add-on of
+// an EAN barcode are accepted and dropped.
+func NormalizeISBN(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if rest, ok := strings.CutPrefix(s, "ISBN"); ok {
+		rest = strings.TrimPrefix(rest, "-10")
+		rest = strings.TrimPrefix(rest, "-13")
+		s = strings.TrimPrefix(strings.TrimSpace(rest), ":")
+	}
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, s)
+
+	if (len(s) == 15 || len(s) == 18) && isDigits(s) {
+		s = s[:13]
+	}
+	switch len(s) {
+	case 10:
+		if !isDigits(s[:9]) || !(isDigits(s[9:]) || s[9] == 'X') || !validISBN10(s) {
+			return "", ErrInvalidISBN
+		}
+		isbn := "978" + s[:9]
+		return isbn + string(isbn13Check(isbn)), nil
+	case 13:
+		if !isDigits(s) || !(strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) || isbn13Check(s[:12]) != s[12] {
+			return "", ErrInvalidISBN
+		}
+		return s, nil
+	}
+	return "", ErrInvalidISBN
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		d := int(s[i] - '0')
+		if s[i] == 'X' {
+			d = 10
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+// isbn13Check returns the check digit of the first 12 digits of an ISBN-13.
+func isbn13Check(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,39 @@
+package bookshelf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "ISBN-13", input: "9780306406157", want: "9780306406157"},
+		{name: "Hyphenated ISBN-13", input: "978-0-306-40615-7", want: "9780306406157"},
+		{name: "ISBN-10", input: "0306406152", want: "9780306406157"},
+		{name: "ISBN-10 with X check digit", input: "0-8044-2957-x", want: "9780804429573"},
+		{name: "Prefixed", input: "ISBN-13: 978 0 306 40615 7", want: "9780306406157"},
+		{name: "EAN with add-on", input: "978030640615790000", want: "9780306406157"},
+		{name: "Wrong ISBN-10 checksum", input: "0306406153", wantErr: true},
+		{name: "Wrong ISBN-13 checksum", input: "9780306406158", wantErr: true},
+		{name: "X inside ISBN-10", input: "03064X6152", wantErr: true},
+		{name: "Not a book EAN", input: "9771234567003", wantErr: true},
+		{name: "Too short", input: "978030640", wantErr: true},
+		{name: "Empty", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
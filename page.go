@@ -0,0 +1,114 @@
+package bookshelf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+var (
+	ListSortFields = []string{"title"}
+	BookSortFields = []string{"title", "author", "publication_year", "page_count"}
+)
+
+// Cursor points at the last row of a page. Value holds the sort key of that
+// row, Kind and ID break ties between rows with equal keys.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v,omitempty"`
+	Kind  string `json:"k,omitempty"`
+	ID    int    `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseCursor(s string) (*Cursor, error) {
+	invalid := NewError(ErrValidation, "invalid_cursor", "invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalid
+	}
+	// JSON numbers decode as float64, only search ranks are fractional.
+	switch v := c.Value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			c.Value = int(v)
+		}
+	case string, nil:
+	default:
+		return nil, invalid
+	}
+	return &c, nil
+}
+
+// ListFilter orders and pages the lists of a user. A Limit of zero returns
+// every list.
+type ListFilter struct {
+	Sort  string
+	Limit int
+	After *Cursor
+}
+
+func (f ListFilter) Validate() error {
+	return validatePage(f.Sort, ListSortFields, f.Limit, f.After)
+}
+
+// BookFilter narrows, orders and pages the books of a list. Sort is one of
+// BookSortFields, prefixed with "-" for descending order. A Limit of zero
+// returns every book.
+type BookFilter struct {
+	Sort      string
+	Author    string
+	Publisher string
+	YearFrom  *int
+	YearTo    *int
+	Limit     int
+	After     *Cursor
+}
+
+func (f BookFilter) Validate() error {
+	if f.YearFrom != nil && f.YearTo != nil && *f.YearFrom > *f.YearTo {
+		return NewError(ErrValidation, "invalid_filter", "year_from is after year_to")
+	}
+	return validatePage(f.Sort, BookSortFields, f.Limit, f.After)
+}
+
+// SortField splits sort into the field name and the direction.
+func SortField(sort string) (field string, desc bool) {
+	return strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+}
+
+func validatePage(sort string, fields []string, limit int, after *Cursor) error {
+	if sort != "" {
+		field, _ := SortField(sort)
+		valid := false
+		for _, f := range fields {
+			if f == field {
+				valid = true
+			}
+		}
+		if !valid {
+			return NewError(ErrValidation, "invalid_sort", "cannot sort by "+field)
+		}
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return NewError(ErrValidation, "invalid_limit", "limit is out of range")
+	}
+	if after != nil && after.Sort != sort {
+		return NewError(ErrValidation, "invalid_cursor", "cursor does not match sort")
+	}
+	return nil
+}
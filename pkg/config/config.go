@@ -11,6 +11,9 @@ type Config struct {
 	Env string `yaml:"env"`
 	HTTPServer
 	Database
+	Storage  Storage `yaml:"storage"`
+	Auth     `yaml:"auth"`
+	Metadata Metadata `yaml:"metadata"`
 }
 
 type HTTPServer struct {
@@ -26,6 +29,53 @@ type Database struct {
 	Port     int    `yaml:"port"`
 	DBName   string `yaml:"db_name"`
 	SSLMode  string `yaml:"ssl_mode"`
+	// AutoMigrate applies pending migrations on start. Meant for local runs,
+	// deployments run "api migrate up" instead.
+	AutoMigrate bool `yaml:"auto_migrate" env:"AUTO_MIGRATE"`
+}
+
+// Storage selects where the data is kept: "postgres", "sqlite" or "memory".
+// The memory storage loses all data on shutdown and is meant for local runs
+// and tests.
+type Storage struct {
+	Driver string `yaml:"driver" env:"STORAGE_DRIVER" env-default:"postgres"`
+	SQLite SQLite `yaml:"sqlite"`
+}
+
+// SQLite configures the SQLite storage. Its migrations are always applied on
+// start.
+type SQLite struct {
+	Path string `yaml:"path" env:"SQLITE_PATH" env-default:"bookshelf.db"`
+}
+
+// Auth configures tokens. Access tokens are short-lived since clients renew
+// them with a refresh token: TokenTTL defaults to 15m, it used to be 12h.
+type Auth struct {
+	PasswordCost    int           `yaml:"password_cost" env-default:"10"`
+	TokenTTL        time.Duration `yaml:"token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	SigningKeyID    string        `yaml:"signing_key_id" env:"JWT_SIGNING_KEY_ID"`
+	SigningKeys     []SigningKey  `yaml:"signing_keys"`
+}
+
+// SigningKey describes a JWT key. HMAC keys use Secret, RSA and Ed25519
+// keys are loaded from PEM files. Keys without private material can only
+// verify tokens, which allows to retire a key after rotation.
+type SigningKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	Secret         string `yaml:"secret"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+// Metadata configures where book details are looked up by ISBN. Provider is
+// "openlibrary", "stub" for a fixed local set of books, or empty to disable
+// lookups.
+type Metadata struct {
+	Provider string        `yaml:"provider"`
+	BaseURL  string        `yaml:"base_url" env-default:"https://openlibrary.org"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"5s"`
 }
 
 func MustLoad() Config {
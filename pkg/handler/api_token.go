@@ -0,0 +1,107 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type createAPITokenResponse struct {
+	Token string             `json:"token"`
+	Data  bookshelf.APIToken `json:"data"`
+}
+
+func (h *Handler) createAPIToken(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		var input bookshelf.CreateAPITokenInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		token, raw, err := h.services.APIToken.Create(r.Context(), userID, input)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot create token")
+			return
+		}
+
+		log.Info("api token has been created")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, createAPITokenResponse{
+			Token: raw,
+			Data:  token,
+		})
+	}
+}
+
+type getAllAPITokensResponse struct {
+	Data []bookshelf.APIToken `json:"data"`
+}
+
+func (h *Handler) getAllAPITokens(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		tokens, err := h.services.APIToken.GetAll(r.Context(), userID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get tokens")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getAllAPITokensResponse{
+			Data: tokens,
+		})
+	}
+}
+
+func (h *Handler) deleteAPIToken(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		if err := h.services.APIToken.Delete(r.Context(), userID, id); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot revoke token")
+			return
+		}
+
+		log.Info("api token has been revoked")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
@@ -2,23 +2,36 @@ package handler
 
 import (
 	bookshelf "bookshelf-api"
-	"database/sql"
+	"bookshelf-api/pkg/service"
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
 )
 
 type signUpResponse struct {
-	Response
 	ID int `json:"id"`
 }
 
 type signInResponse struct {
-	Response
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// signInInput is bookshelf.User without the length cap of sign-up, so users
+// with longer legacy passwords can still sign in and get their hash upgraded.
+type signInInput struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type logoutInput struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (h *Handler) SignUp(log *slog.Logger) http.HandlerFunc {
@@ -32,23 +45,20 @@ func (h *Handler) SignUp(log *slog.Logger) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &input)
 		if err != nil {
 			log.Error("invalid request")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
 
-		if err := validator.New().Struct(input); err != nil {
-			log.Error("invalid request")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
 			return
 		}
-		id, err := h.services.Authorization.CreateUser(input)
+		id, err := h.services.Authorization.CreateUser(r.Context(), input)
 		if err != nil {
 			log.Error(err.Error())
 
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot create user"))
+			serviceError(w, r, err, "cannot create user")
 			return
 		}
 
@@ -65,38 +75,124 @@ func (h *Handler) SignIn(log *slog.Logger) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		var input bookshelf.User
+		var input signInInput
 
 		err := render.DecodeJSON(r.Body, &input)
 		if err != nil {
 			log.Error("invalid request")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
-		if err := validator.New().Struct(input); err != nil {
-			log.Error("invalid request")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
 			return
 		}
-		token, err := h.services.Authorization.GenerateToken(input.Username, input.Password)
-		if errors.Is(err, sql.ErrNoRows) {
+		tokens, err := h.services.Authorization.GenerateToken(r.Context(), input.Username, input.Password)
+		if errors.Is(err, service.ErrInvalidCredentials) {
 			log.Error(err.Error())
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("no such user"))
+			problem(w, r, http.StatusBadRequest, codeInvalidCredentials, "invalid username or password")
 			return
 		}
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot generate token"))
+			serviceError(w, r, err, "cannot generate token")
 			return
 		}
 
 		log.Info("token has been generated")
 		render.JSON(w, r, signInResponse{
-			Token: token,
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+		})
+	}
+}
+
+func (h *Handler) refresh(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var input refreshInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error("invalid request")
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		tokens, err := h.services.Authorization.RefreshTokens(r.Context(), input.RefreshToken)
+		if errors.Is(err, service.ErrRevokedToken) {
+			log.Error(err.Error())
+			problem(w, r, http.StatusUnauthorized, codeTokenRevoked, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidToken) {
+			log.Error(err.Error())
+			problem(w, r, http.StatusUnauthorized, codeInvalidToken, err.Error())
+			return
+		}
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot refresh token")
+			return
+		}
+
+		log.Info("token has been refreshed")
+		render.JSON(w, r, signInResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
 		})
 	}
 }
+
+func (h *Handler) logout(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		accessToken, err := bearerToken(r)
+		if err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusUnauthorized, codeUnauthorized, err.Error())
+			return
+		}
+
+		var input logoutInput
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &input); err != nil {
+				log.Error("invalid request")
+				problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+				return
+			}
+		}
+
+		err = h.services.Authorization.Logout(r.Context(), accessToken, input.RefreshToken)
+		if errors.Is(err, service.ErrInvalidToken) {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidToken, "invalid refresh token")
+			return
+		}
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot logout")
+			return
+		}
+
+		log.Info("user has been logged out")
+		render.JSON(w, r, OK())
+	}
+}
+
+func (h *Handler) jwks(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, h.services.Authorization.JWKS())
+	}
+}
@@ -2,16 +2,19 @@ package handler
 
 import (
 	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
 	"bookshelf-api/pkg/lib/slogdiscard"
 	"bookshelf-api/pkg/service"
 	"bookshelf-api/pkg/service/mocks"
 	"bytes"
-	"database/sql"
 	"errors"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -35,7 +38,7 @@ func TestHandler_SignUp(t *testing.T) {
 			},
 			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
 				auth.
-					On("CreateUser", user).
+					On("CreateUser", mock.Anything, user).
 					Return(1, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -48,8 +51,19 @@ func TestHandler_SignUp(t *testing.T) {
 				Username: "test",
 			},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"password\",\"rule\":\"required\",\"message\":\"password is required\"}]}\n",
+		},
+		{
+			name:      "Long password",
+			inputBody: `{"username":"test","password":"` + strings.Repeat("a", 73) + `"}`,
+			inputUser: bookshelf.User{
+				Username: "test",
+				Password: strings.Repeat("a", 73),
+			},
+			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"password\",\"rule\":\"max\",\"message\":\"password must be at most 72\"}]}\n",
 		},
 		{
 			name:      "No username",
@@ -58,16 +72,16 @@ func TestHandler_SignUp(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"username\",\"rule\":\"required\",\"message\":\"username is required\"}]}\n",
 		},
 		{
 			name:           "Empty request",
 			inputBody:      "{}",
 			inputUser:      bookshelf.User{},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"username\",\"rule\":\"required\",\"message\":\"username is required\"},{\"field\":\"password\",\"rule\":\"required\",\"message\":\"password is required\"}]}\n",
 		},
 		{
 			name:      "Service error",
@@ -78,11 +92,11 @@ func TestHandler_SignUp(t *testing.T) {
 			},
 			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
 				auth.
-					On("CreateUser", user).
+					On("CreateUser", mock.Anything, user).
 					Return(0, errors.New("some error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "{\"error\":\"cannot create user\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"cannot create user\",\"code\":\"internal_error\"}\n",
 		},
 	}
 	for _, tt := range tests {
@@ -125,11 +139,11 @@ func TestHandler_SignIn(t *testing.T) {
 			},
 			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
 				auth.
-					On("GenerateToken", user.Username, user.Password).
-					Return("token", nil)
+					On("GenerateToken", mock.Anything, user.Username, user.Password).
+					Return(bookshelf.TokenPair{AccessToken: "token", RefreshToken: "refresh"}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   "{\"token\":\"token\"}\n",
+			expectedBody:   "{\"token\":\"token\",\"refresh_token\":\"refresh\"}\n",
 		},
 		{
 			name:      "No password",
@@ -138,8 +152,8 @@ func TestHandler_SignIn(t *testing.T) {
 				Username: "test",
 			},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"password\",\"rule\":\"required\",\"message\":\"password is required\"}]}\n",
 		},
 		{
 			name:      "No username",
@@ -148,30 +162,45 @@ func TestHandler_SignIn(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"username\",\"rule\":\"required\",\"message\":\"username is required\"}]}\n",
 		},
 		{
 			name:           "Empty request",
 			inputBody:      `{}`,
 			inputUser:      bookshelf.User{},
 			mockBehaviour:  func(auth *mocks.Authorization, user bookshelf.User) {},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"username\",\"rule\":\"required\",\"message\":\"username is required\"},{\"field\":\"password\",\"rule\":\"required\",\"message\":\"password is required\"}]}\n",
 		},
 		{
-			name:      "No such user",
+			name:      "Long password",
+			inputBody: `{"username":"test","password":"` + strings.Repeat("a", 73) + `"}`,
+			inputUser: bookshelf.User{
+				Username: "test",
+				Password: strings.Repeat("a", 73),
+			},
+			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
+				auth.
+					On("GenerateToken", mock.Anything, user.Username, user.Password).
+					Return(bookshelf.TokenPair{AccessToken: "token", RefreshToken: "refresh"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"token\":\"token\",\"refresh_token\":\"refresh\"}\n",
+		},
+		{
+			name:      "Invalid credentials",
 			inputBody: `{"username":"test","password":"qwerty"}`,
 			inputUser: bookshelf.User{
 				Username: "test",
 				Password: "qwerty",
 			},
 			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
-				auth.On("GenerateToken", user.Username, user.Password).
-					Return("", sql.ErrNoRows)
+				auth.On("GenerateToken", mock.Anything, user.Username, user.Password).
+					Return(bookshelf.TokenPair{}, service.ErrInvalidCredentials)
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"no such user\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"invalid username or password\",\"code\":\"invalid_credentials\"}\n",
 		},
 		{
 			name:      "Service error",
@@ -181,11 +210,11 @@ func TestHandler_SignIn(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehaviour: func(auth *mocks.Authorization, user bookshelf.User) {
-				auth.On("GenerateToken", user.Username, user.Password).
-					Return("", errors.New("some error"))
+				auth.On("GenerateToken", mock.Anything, user.Username, user.Password).
+					Return(bookshelf.TokenPair{}, errors.New("some error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "{\"error\":\"cannot generate token\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"cannot generate token\",\"code\":\"internal_error\"}\n",
 		},
 	}
 	for _, tt := range tests {
@@ -207,3 +236,145 @@ func TestHandler_SignIn(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_JWKS(t *testing.T) {
+	auth := mocks.NewAuthorization(t)
+	auth.On("JWKS").Return(bookshelf.JWKS{Keys: []bookshelf.JWK{
+		{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "key"},
+	}})
+	h := Handler{&service.Service{Authorization: auth}}
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	h.InitRoutes(slogdiscard.NewDiscardLogger(), config.HTTPServer{}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"keys\":[{\"kty\":\"OKP\",\"kid\":\"ed\",\"use\":\"sig\",\"alg\":\"EdDSA\",\"crv\":\"Ed25519\",\"x\":\"key\"}]}\n", w.Body.String())
+}
+
+func TestHandler_logout_APIToken(t *testing.T) {
+	apiToken := mocks.NewAPIToken(t)
+	apiToken.On("Authenticate", mock.Anything, "bsk_token").
+		Return(bookshelf.APIToken{ID: 1, UserID: 2, Scopes: bookshelf.Scopes}, nil)
+	h := Handler{&service.Service{APIToken: apiToken}}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer bsk_token")
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	h.InitRoutes(slogdiscard.NewDiscardLogger(), config.HTTPServer{}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"about:blank\",\"title\":\"Forbidden\",\"status\":403,\"detail\":\"api tokens are not allowed\",\"instance\":\"req-1\",\"code\":\"session_required\"}\n", w.Body.String())
+}
+
+func TestHandler_refresh(t *testing.T) {
+	type mockBehaviour func(auth *mocks.Authorization)
+	tests := []struct {
+		name           string
+		inputBody      string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(auth *mocks.Authorization) {
+				auth.On("RefreshTokens", mock.Anything, "refresh").
+					Return(bookshelf.TokenPair{AccessToken: "token2", RefreshToken: "refresh2"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"token\":\"token2\",\"refresh_token\":\"refresh2\"}\n",
+		},
+		{
+			name:           "Empty request",
+			inputBody:      `{}`,
+			mockBehaviour:  func(auth *mocks.Authorization) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"refresh_token\",\"rule\":\"required\",\"message\":\"refresh_token is required\"}]}\n",
+		},
+		{
+			name:      "Reused token",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(auth *mocks.Authorization) {
+				auth.On("RefreshTokens", mock.Anything, "refresh").
+					Return(bookshelf.TokenPair{}, service.ErrRevokedToken)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"token has been revoked\",\"code\":\"token_revoked\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := mocks.NewAuthorization(t)
+			tt.mockBehaviour(auth)
+			h := Handler{&service.Service{Authorization: auth}}
+
+			r := chi.NewRouter()
+			r.Post("/refresh", h.refresh(slogdiscard.NewDiscardLogger()))
+			req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader([]byte(tt.inputBody)))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_logout(t *testing.T) {
+	type mockBehaviour func(auth *mocks.Authorization)
+	tests := []struct {
+		name           string
+		inputBody      string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(auth *mocks.Authorization) {
+				auth.On("Logout", mock.Anything, "token", "refresh").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"status\":\"OK\"}\n",
+		},
+		{
+			name:      "Access token only",
+			inputBody: ``,
+			mockBehaviour: func(auth *mocks.Authorization) {
+				auth.On("Logout", mock.Anything, "token", "").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"status\":\"OK\"}\n",
+		},
+		{
+			name:      "Foreign refresh token",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(auth *mocks.Authorization) {
+				auth.On("Logout", mock.Anything, "token", "refresh").Return(service.ErrInvalidToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"invalid refresh token\",\"code\":\"invalid_token\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := mocks.NewAuthorization(t)
+			tt.mockBehaviour(auth)
+			h := Handler{&service.Service{Authorization: auth}}
+
+			r := chi.NewRouter()
+			r.Post("/logout", h.logout(slogdiscard.NewDiscardLogger()))
+			req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader([]byte(tt.inputBody)))
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func (h *Handler) createBackup(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		buf := &bytes.Buffer{}
+		if err := h.services.Backup.Create(r.Context(), userID, buf); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot create backup")
+			return
+		}
+
+		name := "bookshelf-backup-" + time.Now().UTC().Format("20060102") + ".zip"
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+func (h *Handler) restoreBackup(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		members, err := queryBool(r, "members")
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+
+		file, err := importFile(w, r)
+		if err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		defer file.Close()
+		archive, err := io.ReadAll(file)
+		if err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+
+		result, err := h.services.Backup.Restore(r.Context(), userID, archive, members)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot restore backup")
+			return
+		}
+
+		log.Info("backup has been restored")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, result)
+	}
+}
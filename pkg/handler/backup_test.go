@@ -0,0 +1,93 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"bytes"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_restoreBackup(t *testing.T) {
+	archive := []byte("PK\x03\x04")
+
+	tests := []struct {
+		name                 string
+		query                string
+		mockBehaviour        func(s *mocks.Backup)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehaviour: func(s *mocks.Backup) {
+				s.On("Restore", mock.Anything, 1, archive, false).Return(bookshelf.RestoreResult{
+					ListIDs:        map[int]int{10: 1},
+					BookIDs:        map[int]int{20: 2},
+					ListsCreated:   1,
+					BooksCreated:   1,
+					Entries:        1,
+					SkippedMembers: []string{},
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"list_ids\":{\"10\":1},\"book_ids\":{\"20\":2},\"lists_created\":1,\"books_created\":1,\"entries\":1,\"members\":0,\"skipped_members\":[]}\n",
+		},
+		{
+			name:  "With members",
+			query: "?members=true",
+			mockBehaviour: func(s *mocks.Backup) {
+				s.On("Restore", mock.Anything, 1, archive, true).Return(bookshelf.RestoreResult{
+					ListIDs:        map[int]int{10: 1},
+					BookIDs:        map[int]int{},
+					ListsCreated:   1,
+					Members:        1,
+					SkippedMembers: []string{"ghost"},
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"list_ids\":{\"10\":1},\"book_ids\":{},\"lists_created\":1,\"books_created\":0,\"entries\":0,\"members\":1,\"skipped_members\":[\"ghost\"]}\n",
+		},
+		{
+			name:                 "Invalid members",
+			query:                "?members=maybe",
+			mockBehaviour:        func(s *mocks.Backup) {},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"members must be true or false\",\"code\":\"invalid_query\"}\n",
+		},
+		{
+			name: "Invalid backup",
+			mockBehaviour: func(s *mocks.Backup) {
+				s.On("Restore", mock.Anything, 1, archive, false).Return(bookshelf.RestoreResult{}, service.ErrInvalidBackup)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"file is not a valid backup\",\"code\":\"invalid_backup\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewBackup(t)
+			tt.mockBehaviour(s)
+			handler := Handler{&service.Service{Backup: s}}
+
+			r := chi.NewRouter()
+			r.Post("/backup/restore", handler.restoreBackup(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodPost, "/backup/restore"+tt.query, bytes.NewReader(archive))
+			req.Header.Set("Content-Type", "application/zip")
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatusCode, w.Code)
+			assert.Equal(t, tt.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 )
 
 type createBookResponse struct {
-	Response
 	BookID int `json:"id"`
 }
 
@@ -19,32 +18,28 @@ func (h *Handler) createBook(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
 
 		var input bookshelf.Book
 		if err := render.DecodeJSON(r.Body, &input); err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
 
-		id, err := h.services.Book.Create(userID, listID, input)
+		id, err := h.services.Book.Create(r.Context(), userID, listID, input)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot create book"))
+			serviceError(w, r, err, "cannot create book")
 			return
 		}
 
@@ -57,8 +52,8 @@ func (h *Handler) createBook(log *slog.Logger) http.HandlerFunc {
 }
 
 type getAllBooksResponse struct {
-	Response
-	Books []bookshelf.Book `json:"books"`
+	Books      []bookshelf.Book `json:"books"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 }
 
 func (h *Handler) getAllBooks(log *slog.Logger) http.HandlerFunc {
@@ -66,35 +61,39 @@ func (h *Handler) getAllBooks(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
-		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		filter, err := parseBookFilter(r)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
 			return
 		}
 
-		books, err := h.services.Book.GetAll(userID, bookID)
+		books, next, err := h.services.Book.GetAll(r.Context(), userID, listID, filter)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot get books"))
+			serviceError(w, r, err, "cannot get books")
 			return
 		}
 
 		render.JSON(w, r, getAllBooksResponse{
-			Books: books,
+			Books:      books,
+			NextCursor: next,
 		})
 	}
 }
 
 type getBookByIDResponse struct {
-	Response
 	Book bookshelf.Book `json:"book"`
 }
 
@@ -103,24 +102,44 @@ func (h *Handler) getBookByID(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		book, err := h.services.Book.GetByID(r.Context(), userID, bookID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get book")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getBookByIDResponse{
+			Book: book,
+		})
+	}
+}
+
+func (h *Handler) getBookByISBN(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
-		book, err := h.services.Book.GetByID(userID, bookID)
+		book, err := h.services.Book.GetByISBN(r.Context(), userID, chi.URLParam(r, "isbn"))
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot get book"))
+			serviceError(w, r, err, "cannot get book")
 			return
 		}
 
@@ -131,37 +150,135 @@ func (h *Handler) getBookByID(log *slog.Logger) http.HandlerFunc {
 	}
 }
 
+type suggestBooksResponse struct {
+	Data []bookshelf.BookSuggestion `json:"data"`
+}
+
+func (h *Handler) suggestBooks(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		limit, err := queryInt(r, "limit")
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+		n := 0
+		if limit != nil {
+			n = *limit
+		}
+
+		suggestions, err := h.services.Book.Suggest(r.Context(), userID, r.URL.Query().Get("q"), n)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot suggest books")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, suggestBooksResponse{
+			Data: suggestions,
+		})
+	}
+}
+
+type getDuplicateBooksResponse struct {
+	Data []bookshelf.DuplicateGroup `json:"data"`
+}
+
+func (h *Handler) getDuplicateBooks(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		groups, err := h.services.Book.GetDuplicates(r.Context(), userID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot find duplicate books")
+			return
+		}
+		if groups == nil {
+			groups = []bookshelf.DuplicateGroup{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getDuplicateBooksResponse{
+			Data: groups,
+		})
+	}
+}
+
+func (h *Handler) mergeBooks(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		var input bookshelf.MergeBooksInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		result, err := h.services.Book.Merge(r.Context(), userID, input)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot merge books")
+			return
+		}
+
+		log.Info("books have been merged")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, result)
+	}
+}
+
 func (h *Handler) updateBook(log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
 
 		var input bookshelf.UpdateBookInput
 		if err := render.DecodeJSON(r.Body, &input); err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
 
-		err = h.services.Book.Update(userID, bookID, input)
+		err = h.services.Book.Update(r.Context(), userID, bookID, input)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot update book"))
+			serviceError(w, r, err, "cannot update book")
 			return
 		}
 
@@ -170,27 +287,56 @@ func (h *Handler) updateBook(log *slog.Logger) http.HandlerFunc {
 	}
 }
 
+func (h *Handler) refreshBook(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		book, err := h.services.Book.Refresh(r.Context(), userID, bookID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot refresh book")
+			return
+		}
+
+		log.Info("book has been refreshed")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getBookByIDResponse{
+			Book: book,
+		})
+	}
+}
+
 func (h *Handler) deleteBook(log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
 		}
 
 		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
-		err = h.services.Book.Delete(userID, bookID)
+		err = h.services.Book.Delete(r.Context(), userID, bookID)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, err.Error())
+			serviceError(w, r, err, "cannot delete book")
 			return
 		}
 		log.Info("book has been deleted")
@@ -199,3 +345,120 @@ func (h *Handler) deleteBook(log *slog.Logger) http.HandlerFunc {
 		render.JSON(w, r, OK())
 	}
 }
+
+func (h *Handler) attachBook(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		bookID, err := strconv.Atoi(chi.URLParam(r, "bookID"))
+		if err != nil {
+			log.Error("invalid book id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid book id")
+			return
+		}
+
+		if err := h.services.Book.Attach(r.Context(), userID, listID, bookID); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot attach book")
+			return
+		}
+
+		log.Info("book has been attached")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
+
+func (h *Handler) detachBook(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		bookID, err := strconv.Atoi(chi.URLParam(r, "bookID"))
+		if err != nil {
+			log.Error("invalid book id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid book id")
+			return
+		}
+
+		if err := h.services.Book.Detach(r.Context(), userID, listID, bookID); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot detach book")
+			return
+		}
+
+		log.Info("book has been detached")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
+
+func (h *Handler) moveBook(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		bookID, err := strconv.Atoi(chi.URLParam(r, "bookID"))
+		if err != nil {
+			log.Error("invalid book id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid book id")
+			return
+		}
+
+		var input bookshelf.MoveBookInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		if err := h.services.Book.Move(r.Context(), userID, listID, input.ListID, bookID); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot move book")
+			return
+		}
+
+		log.Info("book has been moved")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
@@ -0,0 +1,307 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_getAllBooks(t *testing.T) {
+	type mockBehaviour func(book *mocks.Book)
+
+	year := 1950
+	cursor := bookshelf.Cursor{Sort: "title", Value: "a", ID: 1}
+	tests := []struct {
+		name           string
+		query          string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "OK",
+			query: "",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("GetAll", mock.Anything, 1, 1, bookshelf.BookFilter{}).
+					Return([]bookshelf.Book{{ID: 1, Title: "a"}}, "", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"books\":[{\"id\":1,\"title\":\"a\",\"author\":\"\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0}]}\n",
+		},
+		{
+			name:  "Filtered page",
+			query: "?sort=title&author=Tolkien&year_from=1950&limit=1&cursor=" + cursor.Encode(),
+			mockBehaviour: func(book *mocks.Book) {
+				filter := bookshelf.BookFilter{Sort: "title", Author: "Tolkien", YearFrom: &year, Limit: 1, After: &cursor}
+				book.On("GetAll", mock.Anything, 1, 1, filter).
+					Return([]bookshelf.Book{{ID: 2, Title: "b"}}, "next", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"books\":[{\"id\":2,\"title\":\"b\",\"author\":\"\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0}],\"next_cursor\":\"next\"}\n",
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?limit=abc",
+			mockBehaviour:  func(book *mocks.Book) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"limit must be a number\",\"code\":\"invalid_query\"}\n",
+		},
+		{
+			name:           "Invalid cursor",
+			query:          "?cursor=abc",
+			mockBehaviour:  func(book *mocks.Book) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"invalid cursor\",\"code\":\"invalid_cursor\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := mocks.NewBook(t)
+			tt.mockBehaviour(book)
+			handler := Handler{&service.Service{Book: book}}
+
+			r := chi.NewRouter()
+			r.Get("/lists/{id}/books", handler.getAllBooks(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/lists/1/books"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_suggestBooks(t *testing.T) {
+	type mockBehaviour func(book *mocks.Book)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "OK",
+			query: "?q=dostoyevsky&limit=1",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Suggest", mock.Anything, 1, "dostoyevsky", 1).Return([]bookshelf.BookSuggestion{
+					{Book: bookshelf.Book{ID: 1, Title: "Idiot", Author: "Dostoevsky"}, Similarity: 0.75},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"data\":[{\"id\":1,\"title\":\"Idiot\",\"author\":\"Dostoevsky\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0,\"similarity\":0.75}]}\n",
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?q=dostoyevsky&limit=ten",
+			mockBehaviour:  func(book *mocks.Book) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"limit must be a number\",\"code\":\"invalid_query\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := mocks.NewBook(t)
+			tt.mockBehaviour(book)
+			handler := Handler{&service.Service{Book: book}}
+
+			r := chi.NewRouter()
+			r.Get("/books/suggest", handler.suggestBooks(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/books/suggest"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_mergeBooks(t *testing.T) {
+	type mockBehaviour func(book *mocks.Book)
+
+	tests := []struct {
+		name           string
+		inputBody      string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"book_ids":[1,2]}`,
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Merge", mock.Anything, 1, bookshelf.MergeBooksInput{BookIDs: []int{1, 2}}).
+					Return(bookshelf.MergeResult{CanonicalID: 1, MergedIDs: []int{2}, ListIDs: []int{3}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"canonical_id\":1,\"merged_ids\":[2],\"list_ids\":[3]}\n",
+		},
+		{
+			name:           "Single book",
+			inputBody:      `{"book_ids":[1]}`,
+			mockBehaviour:  func(book *mocks.Book) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"book_ids\",\"rule\":\"min\",\"message\":\"book_ids must be at least 2\"}]}\n",
+		},
+		{
+			name:      "Forbidden",
+			inputBody: `{"canonical_id":2,"book_ids":[1,2]}`,
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Merge", mock.Anything, 1, bookshelf.MergeBooksInput{CanonicalID: 2, BookIDs: []int{1, 2}}).
+					Return(bookshelf.MergeResult{}, bookshelf.ErrForbidden)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Forbidden\",\"status\":403,\"detail\":\"access denied\",\"code\":\"forbidden\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := mocks.NewBook(t)
+			tt.mockBehaviour(book)
+			handler := Handler{&service.Service{Book: book}}
+
+			r := chi.NewRouter()
+			r.Post("/books/merge", handler.mergeBooks(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodPost, "/books/merge", bytes.NewBufferString(tt.inputBody))
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getBookByISBN(t *testing.T) {
+	type mockBehaviour func(book *mocks.Book)
+
+	tests := []struct {
+		name           string
+		isbn           string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "OK",
+			isbn: "0-306-40615-2",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("GetByISBN", mock.Anything, 1, "0-306-40615-2").
+					Return(bookshelf.Book{ID: 1, Title: "a", ISBN: "9780306406157"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"book\":{\"id\":1,\"title\":\"a\",\"author\":\"\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0,\"isbn\":\"9780306406157\"}}\n",
+		},
+		{
+			name: "Invalid ISBN",
+			isbn: "123",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("GetByISBN", mock.Anything, 1, "123").Return(bookshelf.Book{}, bookshelf.ErrInvalidISBN)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"invalid ISBN\",\"code\":\"invalid_isbn\"}\n",
+		},
+		{
+			name: "Not found",
+			isbn: "9780306406157",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("GetByISBN", mock.Anything, 1, "9780306406157").Return(bookshelf.Book{}, bookshelf.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"not found\",\"code\":\"not_found\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := mocks.NewBook(t)
+			tt.mockBehaviour(book)
+			handler := Handler{&service.Service{Book: book}}
+
+			r := chi.NewRouter()
+			r.Get("/books/by-isbn/{isbn}", handler.getBookByISBN(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/books/by-isbn/"+tt.isbn, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_refreshBook(t *testing.T) {
+	type mockBehaviour func(book *mocks.Book)
+
+	tests := []struct {
+		name           string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "OK",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Refresh", mock.Anything, 1, 2).
+					Return(bookshelf.Book{ID: 2, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"book\":{\"id\":2,\"title\":\"Dune\",\"author\":\"Frank Herbert\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0,\"isbn\":\"9780441172719\"}}\n",
+		},
+		{
+			name: "Without ISBN",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Refresh", mock.Anything, 1, 2).Return(bookshelf.Book{}, service.ErrMissingISBN)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"book has no ISBN\",\"code\":\"missing_isbn\"}\n",
+		},
+		{
+			name: "Provider down",
+			mockBehaviour: func(book *mocks.Book) {
+				book.On("Refresh", mock.Anything, 1, 2).
+					Return(bookshelf.Book{}, fmt.Errorf("%w: open library: unexpected status 503", service.ErrMetadataFailed))
+			},
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Bad Gateway\",\"status\":502,\"detail\":\"metadata provider is unavailable\",\"code\":\"metadata_unavailable\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := mocks.NewBook(t)
+			tt.mockBehaviour(book)
+			handler := Handler{&service.Service{Book: book}}
+
+			r := chi.NewRouter()
+			r.Post("/books/{id}/refresh", handler.refreshBook(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodPost, "/books/2/refresh", nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bookshelf-api/pkg/service"
+	"bytes"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) citeBook(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		writeCitations(w, r, log, "book-"+strconv.Itoa(bookID), func(format string, w io.Writer) error {
+			return h.services.Cite.CiteBook(r.Context(), userID, bookID, format, w)
+		})
+	}
+}
+
+func (h *Handler) citeList(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		writeCitations(w, r, log, "list-"+strconv.Itoa(listID), func(format string, w io.Writer) error {
+			return h.services.Cite.CiteList(r.Context(), userID, listID, format, w)
+		})
+	}
+}
+
+// writeCitations responds with the citations written by cite in the format
+// asked for by the format query parameter.
+func writeCitations(w http.ResponseWriter, r *http.Request, log *slog.Logger, file string, cite func(format string, w io.Writer) error) {
+	format, err := service.LookupCitationFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		serviceError(w, r, err, "invalid query")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cite(format.Name, buf); err != nil {
+		log.Error(err.Error())
+		serviceError(w, r, err, "cannot cite books")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file+"."+format.Extension))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+	log.Info("citations have been written", slog.String("format", format.Name))
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_citeBook(t *testing.T) {
+	writeCitation := func(args mock.Arguments) {
+		io.WriteString(args.Get(4).(io.Writer), "citation\n")
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		mockBehaviour       func(s *mocks.Cite)
+		expectedStatus      int
+		expectedContentType string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		{
+			name:  "Default format",
+			query: "",
+			mockBehaviour: func(s *mocks.Cite) {
+				s.On("CiteBook", mock.Anything, 1, 3, "bibtex", mock.Anything).Run(writeCitation).Return(nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-bibtex; charset=utf-8",
+			expectedDisposition: "attachment; filename=\"book-3.bib\"",
+			expectedBody:        "citation\n",
+		},
+		{
+			name:  "CSL-JSON",
+			query: "?format=csl-json",
+			mockBehaviour: func(s *mocks.Cite) {
+				s.On("CiteBook", mock.Anything, 1, 3, "csl-json", mock.Anything).Run(writeCitation).Return(nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/vnd.citationstyles.csl+json",
+			expectedDisposition: "attachment; filename=\"book-3.json\"",
+			expectedBody:        "citation\n",
+		},
+		{
+			name:                "Unknown format",
+			query:               "?format=mla",
+			mockBehaviour:       func(s *mocks.Cite) {},
+			expectedStatus:      http.StatusUnprocessableEntity,
+			expectedContentType: problemContentType,
+			expectedBody:        "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"format must be one of: bibtex csl-json ris\",\"code\":\"invalid_format\"}\n",
+		},
+		{
+			name:  "Not found",
+			query: "?format=ris",
+			mockBehaviour: func(s *mocks.Cite) {
+				s.On("CiteBook", mock.Anything, 1, 3, "ris", mock.Anything).Return(bookshelf.ErrNotFound)
+			},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: problemContentType,
+			expectedBody:        "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"not found\",\"code\":\"not_found\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewCite(t)
+			tt.mockBehaviour(s)
+			handler := Handler{&service.Service{Cite: s}}
+
+			r := chi.NewRouter()
+			r.Get("/books/{id}/cite", handler.citeBook(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/books/3/cite"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_citeList(t *testing.T) {
+	s := mocks.NewCite(t)
+	s.On("CiteList", mock.Anything, 1, 2, "ris", mock.Anything).Run(func(args mock.Arguments) {
+		io.WriteString(args.Get(4).(io.Writer), "TY  - BOOK\r\nER  - \r\n")
+	}).Return(nil)
+	handler := Handler{&service.Service{Cite: s}}
+
+	r := chi.NewRouter()
+	r.Get("/lists/{id}/cite", handler.citeList(slogdiscard.NewDiscardLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/lists/2/cite?format=ris", nil)
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), "userID", 1)
+	r.ServeHTTP(w, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-research-info-systems; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=\"list-2.ris\"", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "TY  - BOOK\r\nER  - \r\n", w.Body.String())
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const defaultExportFormat = "json"
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+var exportCSVHeader = []string{"list_id", "list_title", "list_description", "book_id", "title", "author", "publisher", "publication_year", "page_count", "isbn"}
+
+func (h *Handler) exportAccount(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		writeExport(w, r, log, "bookshelf", func(fn func(bookshelf.ExportRow) error) error {
+			return h.services.Export.Export(r.Context(), userID, fn)
+		})
+	}
+}
+
+func (h *Handler) exportList(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		writeExport(w, r, log, "list-"+strconv.Itoa(listID), func(fn func(bookshelf.ExportRow) error) error {
+			return h.services.Export.ExportList(r.Context(), userID, listID, fn)
+		})
+	}
+}
+
+// writeExport streams the rows produced by export in the format asked for by
+// the format query parameter. The response is only committed with the first
+// row, so errors detected before that are still reported as problems. Every
+// row extends the timeout, so large exports are not cut off.
+func writeExport(w http.ResponseWriter, r *http.Request, log *slog.Logger, name string, export func(func(bookshelf.ExportRow) error) error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = defaultExportFormat
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		err := bookshelf.NewError(bookshelf.ErrValidation, "invalid_format", "format must be one of: csv json ndjson")
+		serviceError(w, r, err, "invalid query")
+		return
+	}
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	ew := newExportWriter(format, w)
+	err := export(func(row bookshelf.ExportRow) error {
+		extendTimeout(w, r)
+		start()
+		return ew.Write(row)
+	})
+	if err != nil {
+		log.Error(err.Error())
+		if !started {
+			serviceError(w, r, err, "cannot export")
+		}
+		return
+	}
+	start()
+	if err := ew.Close(); err != nil {
+		log.Error(err.Error())
+		return
+	}
+	log.Info("export has been written", slog.String("format", format))
+}
+
+type exportWriter interface {
+	Write(row bookshelf.ExportRow) error
+	Close() error
+}
+
+func newExportWriter(format string, w io.Writer) exportWriter {
+	switch format {
+	case "csv":
+		return &csvExportWriter{w: csv.NewWriter(w)}
+	case "ndjson":
+		return &ndjsonExportWriter{enc: json.NewEncoder(w)}
+	}
+	return &jsonExportWriter{w: w, listID: -1}
+}
+
+// csvExportWriter writes one record per row, the book columns of empty lists
+// are left blank.
+type csvExportWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (e *csvExportWriter) Write(row bookshelf.ExportRow) error {
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+	}
+	record := []string{strconv.Itoa(row.List.ID), row.List.Title, row.List.Description, "", "", "", "", "", "", ""}
+	if book := row.Book; book != nil {
+		record = append(record[:3],
+			strconv.Itoa(book.ID), book.Title, book.Author, book.Publisher,
+			strconv.Itoa(book.PublicationYear), strconv.Itoa(book.PageCount), book.ISBN,
+		)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportWriter) Close() error {
+	if !e.header {
+		e.header = true
+		e.w.Write(exportCSVHeader)
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(row bookshelf.ExportRow) error {
+	return e.enc.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// jsonExportWriter writes a bookshelf.ExportDocument piece by piece. Rows
+// arrive ordered by list, so a list is closed once the next one starts.
+type jsonExportWriter struct {
+	w      io.Writer
+	listID int
+	books  int
+}
+
+func (e *jsonExportWriter) Write(row bookshelf.ExportRow) error {
+	buf := &bytes.Buffer{}
+	if row.List.ID != e.listID {
+		if e.listID == -1 {
+			fmt.Fprintf(buf, `{"version":%d,"lists":[`, bookshelf.ExportVersion)
+		} else {
+			buf.WriteString("]},")
+		}
+		list, err := json.Marshal(row.List)
+		if err != nil {
+			return err
+		}
+		buf.Write(list[:len(list)-1])
+		buf.WriteString(`,"books":[`)
+		e.listID = row.List.ID
+		e.books = 0
+	}
+	if row.Book != nil {
+		if e.books > 0 {
+			buf.WriteByte(',')
+		}
+		book, err := json.Marshal(row.Book)
+		if err != nil {
+			return err
+		}
+		buf.Write(book)
+		e.books++
+	}
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+func (e *jsonExportWriter) Close() error {
+	var err error
+	if e.listID == -1 {
+		_, err = fmt.Fprintf(e.w, "{\"version\":%d,\"lists\":[]}\n", bookshelf.ExportVersion)
+	} else {
+		_, err = io.WriteString(e.w, "]}]}\n")
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_exportAccount(t *testing.T) {
+	rows := []bookshelf.ExportRow{
+		{List: bookshelf.List{ID: 1, Title: "Sci-fi"}, Book: &bookshelf.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", PublicationYear: 1965, ISBN: "9780441172719"}},
+		{List: bookshelf.List{ID: 1, Title: "Sci-fi"}, Book: &bookshelf.Book{ID: 2, Title: "Solaris", Author: "Stanislaw Lem"}},
+		{List: bookshelf.List{ID: 2, Title: "Empty", Description: "nothing, yet"}},
+	}
+	export := func(s *mocks.Export) {
+		s.On("Export", mock.Anything, 1, mock.Anything).Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(bookshelf.ExportRow) error)
+			for _, row := range rows {
+				require.NoError(t, fn(row))
+			}
+		}).Return(nil)
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		mockBehaviour       func(s *mocks.Export)
+		expectedStatus      int
+		expectedContentType string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		{
+			name:                "JSON",
+			query:               "",
+			mockBehaviour:       export,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedDisposition: "attachment; filename=\"bookshelf.json\"",
+			expectedBody: `{"version":1,"lists":[` +
+				`{"id":1,"title":"Sci-fi","description":"","books":[` +
+				`{"id":1,"title":"Dune","author":"Frank Herbert","publisher":"","publication_year":1965,"page_count":0,"isbn":"9780441172719"},` +
+				`{"id":2,"title":"Solaris","author":"Stanislaw Lem","publisher":"","publication_year":0,"page_count":0}]},` +
+				`{"id":2,"title":"Empty","description":"nothing, yet","books":[]}]}` + "\n",
+		},
+		{
+			name:                "CSV",
+			query:               "?format=csv",
+			mockBehaviour:       export,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedDisposition: "attachment; filename=\"bookshelf.csv\"",
+			expectedBody: "list_id,list_title,list_description,book_id,title,author,publisher,publication_year,page_count,isbn\n" +
+				"1,Sci-fi,,1,Dune,Frank Herbert,,1965,0,9780441172719\n" +
+				"1,Sci-fi,,2,Solaris,Stanislaw Lem,,0,0,\n" +
+				"2,Empty,\"nothing, yet\",,,,,,,\n",
+		},
+		{
+			name:                "NDJSON",
+			query:               "?format=ndjson",
+			mockBehaviour:       export,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedDisposition: "attachment; filename=\"bookshelf.ndjson\"",
+			expectedBody: `{"list":{"id":1,"title":"Sci-fi","description":""},"book":{"id":1,"title":"Dune","author":"Frank Herbert","publisher":"","publication_year":1965,"page_count":0,"isbn":"9780441172719"}}` + "\n" +
+				`{"list":{"id":1,"title":"Sci-fi","description":""},"book":{"id":2,"title":"Solaris","author":"Stanislaw Lem","publisher":"","publication_year":0,"page_count":0}}` + "\n" +
+				`{"list":{"id":2,"title":"Empty","description":"nothing, yet"}}` + "\n",
+		},
+		{
+			name:  "Empty account",
+			query: "?format=json",
+			mockBehaviour: func(s *mocks.Export) {
+				s.On("Export", mock.Anything, 1, mock.Anything).Return(nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedDisposition: "attachment; filename=\"bookshelf.json\"",
+			expectedBody:        "{\"version\":1,\"lists\":[]}\n",
+		},
+		{
+			name:                "Unknown format",
+			query:               "?format=xml",
+			mockBehaviour:       func(s *mocks.Export) {},
+			expectedStatus:      http.StatusUnprocessableEntity,
+			expectedContentType: problemContentType,
+			expectedBody:        "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"format must be one of: csv json ndjson\",\"code\":\"invalid_format\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewExport(t)
+			tt.mockBehaviour(s)
+			handler := Handler{&service.Service{Export: s}}
+
+			r := chi.NewRouter()
+			r.Get("/export", handler.exportAccount(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/export"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.query == "" {
+				var doc bookshelf.ExportDocument
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+				assert.Len(t, doc.Lists[0].Books, 2)
+			}
+		})
+	}
+}
+
+func TestHandler_exportList(t *testing.T) {
+	s := mocks.NewExport(t)
+	s.On("ExportList", mock.Anything, 1, 2, mock.Anything).Return(bookshelf.ErrNotFound)
+	handler := Handler{&service.Service{Export: s}}
+
+	r := chi.NewRouter()
+	r.Get("/lists/{id}/export", handler.exportList(slogdiscard.NewDiscardLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/lists/2/export?format=csv", nil)
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), "userID", 1)
+	r.ServeHTTP(w, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestHandler_exportAccount_Slow(t *testing.T) {
+	const limit = 50 * time.Millisecond
+	export := mocks.NewExport(t)
+	export.On("Export", mock.Anything, 1, mock.Anything).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+		fn := args.Get(2).(func(bookshelf.ExportRow) error)
+		for i := 1; i <= 5; i++ {
+			time.Sleep(limit / 2)
+			assert.NoError(t, ctx.Err())
+			assert.NoError(t, fn(bookshelf.ExportRow{List: bookshelf.List{ID: i, Title: "List " + strconv.Itoa(i)}}))
+		}
+	}).Return(nil)
+	h := Handler{&service.Service{Export: export}}
+
+	withUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "userID", 1)))
+		})
+	}
+	srv := httptest.NewUnstartedServer(withUser(timeout(limit)(h.exportAccount(slogdiscard.NewDiscardLogger()))))
+	srv.Config.WriteTimeout = limit
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?format=ndjson")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 5, strings.Count(string(body), "\n"))
+}
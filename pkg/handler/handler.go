@@ -1,6 +1,8 @@
 package handler
 
 import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
 	"bookshelf-api/pkg/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -16,39 +18,103 @@ func New(services *service.Service) *Handler {
 	return &Handler{services: services}
 }
 
-func (h *Handler) InitRoutes(log *slog.Logger) http.Handler {
+func (h *Handler) InitRoutes(log *slog.Logger, cfg config.HTTPServer) http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	router.Use(timeout(cfg.Timeout))
 
-	router.Route("/auth", func(r chi.Router) {
+	// URLFormat strips extensions from the path before routing, the JWKS
+	// path keeps its extension and is routed before the other routes.
+	router.Get("/.well-known/jwks.json", h.jwks(log))
+
+	routes := chi.NewRouter()
+	routes.Use(middleware.URLFormat)
+
+	routes.Route("/auth", func(r chi.Router) {
 		r.Post("/sign-up", h.SignUp(log))
 		r.Post("/sign-in", h.SignIn(log))
+		r.Post("/refresh", h.refresh(log))
+		r.With(h.userIdentity(log), h.sessionOnly(log)).Post("/logout", h.logout(log))
+	})
+
+	routes.Get("/shared/{token}", h.getSharedList(log))
+
+	routes.Route("/opds", func(r chi.Router) {
+		r.Use(h.basicIdentity(log))
+		r.Use(h.requireScope(log, bookshelf.ScopeListsRead), h.requireScope(log, bookshelf.ScopeBooksRead))
+		r.Get("/", h.opdsRoot(log))
+		r.Get("/lists/{id}", h.opdsList(log))
+		r.Get("/books/{id}", h.getBookByID(log))
 	})
 
-	router.Route("/api", func(r chi.Router) {
+	routes.Route("/api", func(r chi.Router) {
 		r.Use(h.userIdentity(log))
+		listsRead := h.requireScope(log, bookshelf.ScopeListsRead)
+		listsWrite := h.requireScope(log, bookshelf.ScopeListsWrite)
+		booksRead := h.requireScope(log, bookshelf.ScopeBooksRead)
+		booksWrite := h.requireScope(log, bookshelf.ScopeBooksWrite)
+
 		r.Route("/lists", func(r chi.Router) {
-			r.Post("/", h.createList(log))
-			r.Get("/", h.getAllLists(log))
-			r.Get("/{id}", h.getListByID(log))
-			r.Put("/{id}", h.updateList(log))
-			r.Delete("/{id}", h.deleteList(log))
+			r.With(listsWrite).Post("/", h.createList(log))
+			r.With(listsRead).Get("/", h.getAllLists(log))
+			r.With(listsRead).Get("/{id}", h.getListByID(log))
+			r.With(listsWrite).Put("/{id}", h.updateList(log))
+			r.With(listsWrite).Delete("/{id}", h.deleteList(log))
+			r.With(listsRead, booksRead).Get("/{id}/export", h.exportList(log))
+			r.With(listsRead, booksRead).Get("/{id}/cite", h.citeList(log))
 
 			r.Route("/{id}/books", func(r chi.Router) {
-				r.Post("/", h.createBook(log))
-				r.Get("/", h.getAllBooks(log))
+				r.With(booksWrite).Post("/", h.createBook(log))
+				r.With(booksRead).Get("/", h.getAllBooks(log))
+				r.With(booksWrite).Post("/{bookID}", h.attachBook(log))
+				r.With(booksWrite).Delete("/{bookID}", h.detachBook(log))
+				r.With(booksWrite).Post("/{bookID}/move", h.moveBook(log))
+			})
+			r.Route("/{id}/members", func(r chi.Router) {
+				r.With(listsRead).Get("/", h.getListMembers(log))
+				r.With(listsWrite).Post("/", h.addListMember(log))
+				r.With(listsWrite).Put("/{userID}", h.updateListMember(log))
+				r.With(listsWrite).Delete("/{userID}", h.removeListMember(log))
+			})
+			r.Route("/{id}/shares", func(r chi.Router) {
+				r.With(listsWrite).Post("/", h.createListShare(log))
+				r.With(listsRead).Get("/", h.getListShares(log))
+				r.With(listsWrite).Delete("/{shareID}", h.revokeListShare(log))
 			})
 		})
 		r.Route("/books", func(r chi.Router) {
-			r.Get("/{id}", h.getBookByID(log))
-			r.Put("/{id}", h.updateBook(log))
-			r.Delete("/{id}", h.deleteBook(log))
+			r.With(booksRead).Get("/suggest", h.suggestBooks(log))
+			r.With(booksRead).Get("/duplicates", h.getDuplicateBooks(log))
+			r.With(booksWrite).Post("/merge", h.mergeBooks(log))
+			r.With(booksRead).Get("/by-isbn/{isbn}", h.getBookByISBN(log))
+			r.With(booksRead).Get("/{id}", h.getBookByID(log))
+			r.With(booksRead).Get("/{id}/cite", h.citeBook(log))
+			r.With(booksWrite).Put("/{id}", h.updateBook(log))
+			r.With(booksWrite).Post("/{id}/refresh", h.refreshBook(log))
+			r.With(booksWrite).Delete("/{id}", h.deleteBook(log))
+		})
+		r.With(listsRead, booksRead).Get("/search", h.search(log))
+		r.With(listsRead, booksRead).Get("/export", h.exportAccount(log))
+		r.Route("/backup", func(r chi.Router) {
+			r.With(listsRead, booksRead).Get("/", h.createBackup(log))
+			r.With(listsWrite, booksWrite).Post("/restore", h.restoreBackup(log))
+		})
+		r.Route("/import", func(r chi.Router) {
+			r.With(listsWrite, booksWrite).Post("/goodreads", h.importGoodreads(log))
+			r.With(listsWrite, booksWrite).Post("/json", h.importJSON(log))
+			r.With(booksRead).Get("/jobs/{id}", h.getImportJob(log))
+		})
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(h.sessionOnly(log))
+			r.Post("/", h.createAPIToken(log))
+			r.Get("/", h.getAllAPITokens(log))
+			r.Delete("/{id}", h.deleteAPIToken(log))
 		})
 
 	})
+	router.Mount("/", routes)
 	return router
 }
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// maxImportSize limits uploaded files, an export of a few thousand books is
+// well below it.
+const maxImportSize = 16 << 20
+
+func (h *Handler) importGoodreads(log *slog.Logger) http.HandlerFunc {
+	return h.startImport(log, func(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+		return h.services.Import.ImportGoodreads(ctx, userID, r)
+	})
+}
+
+func (h *Handler) importJSON(log *slog.Logger) http.HandlerFunc {
+	return h.startImport(log, func(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+		return h.services.Import.ImportJSON(ctx, userID, r)
+	})
+}
+
+// startImport passes the uploaded file to start and responds with the
+// created job.
+func (h *Handler) startImport(log *slog.Logger, start func(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		file, err := importFile(w, r)
+		if err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		defer file.Close()
+
+		job, err := start(r.Context(), userID, file)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot import file")
+			return
+		}
+
+		log.Info("import has been started", slog.String("job", job.ID))
+		w.Header().Set("Location", "/api/import/jobs/"+job.ID)
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, job)
+	}
+}
+
+func (h *Handler) getImportJob(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		job, err := h.services.Import.GetJob(r.Context(), userID, chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get import job")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, job)
+	}
+}
+
+// importFile returns the uploaded file, sent either as the "file" field of a
+// multipart form or as the raw request body.
+func importFile(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	return file, err
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"bytes"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_importGoodreads(t *testing.T) {
+	type mockBehaviour func(s *mocks.Import)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	job := bookshelf.ImportJob{ID: "job", Source: "goodreads", Status: bookshelf.ImportRunning, Total: 1, Errors: []bookshelf.ImportRowError{}, CreatedAt: created}
+	csv := "Title\nDune\n"
+
+	form := &bytes.Buffer{}
+	mw := multipart.NewWriter(form)
+	part, _ := mw.CreateFormFile("file", "goodreads_library_export.csv")
+	part.Write([]byte(csv))
+	mw.Close()
+
+	tests := []struct {
+		name             string
+		body             io.Reader
+		contentType      string
+		mockBehaviour    mockBehaviour
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name:        "Raw body",
+			body:        bytes.NewBufferString(csv),
+			contentType: "text/csv",
+			mockBehaviour: func(s *mocks.Import) {
+				s.On("ImportGoodreads", mock.Anything, 1, mock.MatchedBy(func(r io.Reader) bool {
+					data, _ := io.ReadAll(r)
+					return string(data) == csv
+				})).Return(job, nil)
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedLocation: "/api/import/jobs/job",
+			expectedBody:     "{\"id\":\"job\",\"source\":\"goodreads\",\"status\":\"running\",\"total\":1,\"processed\":0,\"created\":0,\"skipped\":0,\"failed\":0,\"errors\":[],\"created_at\":\"2024-01-02T03:04:05Z\"}\n",
+		},
+		{
+			name:        "Multipart form",
+			body:        bytes.NewReader(form.Bytes()),
+			contentType: mw.FormDataContentType(),
+			mockBehaviour: func(s *mocks.Import) {
+				s.On("ImportGoodreads", mock.Anything, 1, mock.MatchedBy(func(r io.Reader) bool {
+					data, _ := io.ReadAll(r)
+					return string(data) == csv
+				})).Return(job, nil)
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedLocation: "/api/import/jobs/job",
+			expectedBody:     "{\"id\":\"job\",\"source\":\"goodreads\",\"status\":\"running\",\"total\":1,\"processed\":0,\"created\":0,\"skipped\":0,\"failed\":0,\"errors\":[],\"created_at\":\"2024-01-02T03:04:05Z\"}\n",
+		},
+		{
+			name:           "Multipart form without file",
+			body:           bytes.NewBufferString("--x--\r\n"),
+			contentType:    "multipart/form-data; boundary=x",
+			mockBehaviour:  func(s *mocks.Import) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"invalid request\",\"code\":\"invalid_request\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewImport(t)
+			tt.mockBehaviour(s)
+			handler := Handler{&service.Service{Import: s}}
+
+			r := chi.NewRouter()
+			r.Post("/import/goodreads", handler.importGoodreads(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodPost, "/import/goodreads", tt.body)
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getImportJob(t *testing.T) {
+	s := mocks.NewImport(t)
+	s.On("GetJob", mock.Anything, 1, "unknown").Return(bookshelf.ImportJob{}, service.ErrImportJobNotFound)
+	handler := Handler{&service.Service{Import: s}}
+
+	r := chi.NewRouter()
+	r.Get("/import/jobs/{id}", handler.getImportJob(slogdiscard.NewDiscardLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/import/jobs/unknown", nil)
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), "userID", 1)
+	r.ServeHTTP(w, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"import job not found\",\"code\":\"import_job_not_found\"}\n", w.Body.String())
+}
@@ -4,14 +4,12 @@ import (
 	bookshelf "bookshelf-api"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
 	"strconv"
 )
 
 type createListResponse struct {
-	Response
 	ListID int `json:"list_id"`
 }
 
@@ -20,30 +18,26 @@ func (h *Handler) createList(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		var input bookshelf.List
 		if err := render.DecodeJSON(r.Body, &input); err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
-		if err := validator.New().Struct(input); err != nil {
+		if err := validate.Struct(input); err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			validationError(w, r, err)
 			return
 		}
 
-		id, err := h.services.List.Create(userID, input)
+		id, err := h.services.List.Create(r.Context(), userID, input)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot create list"))
+			serviceError(w, r, err, "cannot create list")
 			return
 		}
 
@@ -56,8 +50,8 @@ func (h *Handler) createList(log *slog.Logger) http.HandlerFunc {
 }
 
 type getAllListsResponse struct {
-	Response
-	Data []bookshelf.List `json:"data"`
+	Data       []bookshelf.List `json:"data"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 }
 
 func (h *Handler) getAllLists(log *slog.Logger) http.HandlerFunc {
@@ -65,29 +59,34 @@ func (h *Handler) getAllLists(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
-		lists, err := h.services.List.GetAll(userID)
+		filter, err := parseListFilter(r)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot get all lists"))
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+
+		lists, next, err := h.services.List.GetAll(r.Context(), userID, filter)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get all lists")
 			return
 		}
 
 		log.Info("lists have been received successfully")
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, getAllListsResponse{
-			Data: lists,
+			Data:       lists,
+			NextCursor: next,
 		})
 	}
 }
 
 type getListByIDResponse struct {
-	Response
 	Data bookshelf.List `json:"data"`
 }
 
@@ -96,24 +95,21 @@ func (h *Handler) getListByID(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
 
-		list, err := h.services.List.GetByID(userID, id)
+		list, err := h.services.List.GetByID(r.Context(), userID, id)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot get list by id"))
+			serviceError(w, r, err, "cannot get list by id")
 			return
 		}
 
@@ -131,32 +127,28 @@ func (h *Handler) updateList(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
 
 		var input bookshelf.UpdateListInput
 		if err := render.DecodeJSON(r.Body, &input); err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid request"))
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
 			return
 		}
 
-		err = h.services.List.Update(userID, id, input)
+		err = h.services.List.Update(r.Context(), userID, id, input)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("cannot update list"))
+			serviceError(w, r, err, "cannot update list")
 			return
 		}
 		log.Info("list has been updated")
@@ -170,23 +162,20 @@ func (h *Handler) deleteList(log *slog.Logger) http.HandlerFunc {
 		userID, ok := r.Context().Value("userID").(int)
 		if !ok {
 			log.Error("user id not found")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error("user id not found"))
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 			return
 		}
 
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			log.Error("invalid id")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Error("invalid id"))
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
 			return
 		}
-		err = h.services.List.Delete(userID, id)
+		err = h.services.List.Delete(r.Context(), userID, id)
 		if err != nil {
 			log.Error(err.Error())
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Error(err.Error()))
+			serviceError(w, r, err, "cannot delete list")
 			return
 		}
 		log.Info("list has been deleted")
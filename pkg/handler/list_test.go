@@ -7,9 +7,12 @@ import (
 	"bookshelf-api/pkg/service/mocks"
 	"bytes"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,7 +32,7 @@ func TestHandler_CreateList(t *testing.T) {
 		{
 			name: "OK",
 			mockBehaviour: func(list *mocks.List, userID int, input bookshelf.List) {
-				list.On("Create", userID, input).Return(1, nil)
+				list.On("Create", mock.Anything, userID, input).Return(1, nil)
 			},
 			inputBody: `{"title":"title","description":"description"}`,
 			inputList: bookshelf.List{
@@ -42,7 +45,7 @@ func TestHandler_CreateList(t *testing.T) {
 		{
 			name: "Only Title",
 			mockBehaviour: func(list *mocks.List, userID int, input bookshelf.List) {
-				list.On("Create", userID, input).Return(1, nil)
+				list.On("Create", mock.Anything, userID, input).Return(1, nil)
 			},
 			inputBody: `{"title":"title"}`,
 			inputList: bookshelf.List{
@@ -58,13 +61,13 @@ func TestHandler_CreateList(t *testing.T) {
 			inputList: bookshelf.List{
 				Description: "description",
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"error\":\"invalid request\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"title\",\"rule\":\"required\",\"message\":\"title is required\"}]}\n",
 		},
 		{
 			name: "service error",
 			mockBehaviour: func(list *mocks.List, userID int, input bookshelf.List) {
-				list.On("Create", userID, input).Return(0, errors.New("service error"))
+				list.On("Create", mock.Anything, userID, input).Return(0, errors.New("service error"))
 			},
 			inputBody: `{"title":"title","description":"description"}`,
 			inputList: bookshelf.List{
@@ -72,7 +75,7 @@ func TestHandler_CreateList(t *testing.T) {
 				Description: "description",
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "{\"error\":\"cannot create list\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"cannot create list\",\"code\":\"internal_error\"}\n",
 		},
 	}
 	for _, tt := range tests {
@@ -95,3 +98,71 @@ func TestHandler_CreateList(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_getListByID(t *testing.T) {
+	type mockBehaviour func(list *mocks.List)
+
+	tests := []struct {
+		name           string
+		id             string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "OK",
+			id:   "1",
+			mockBehaviour: func(list *mocks.List) {
+				list.On("GetByID", mock.Anything, 1, 1).Return(bookshelf.List{ID: 1, Title: "title"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"data\":{\"id\":1,\"title\":\"title\",\"description\":\"\"}}\n",
+		},
+		{
+			name:           "Invalid id",
+			id:             "abc",
+			mockBehaviour:  func(list *mocks.List) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"invalid id\",\"code\":\"invalid_id\"}\n",
+		},
+		{
+			name: "Not found",
+			id:   "2",
+			mockBehaviour: func(list *mocks.List) {
+				list.On("GetByID", mock.Anything, 1, 2).Return(bookshelf.List{}, fmt.Errorf("%w: %w", bookshelf.ErrNotFound, sql.ErrNoRows))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"not found\",\"code\":\"not_found\"}\n",
+		},
+		{
+			name: "service error",
+			id:   "1",
+			mockBehaviour: func(list *mocks.List) {
+				list.On("GetByID", mock.Anything, 1, 1).Return(bookshelf.List{}, errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"cannot get list by id\",\"code\":\"internal_error\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := mocks.NewList(t)
+			tt.mockBehaviour(list)
+			handler := Handler{&service.Service{List: list}}
+
+			r := chi.NewRouter()
+			r.Get("/{id}", handler.getListByID(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.id, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
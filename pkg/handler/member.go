@@ -0,0 +1,166 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type getListMembersResponse struct {
+	Data []bookshelf.ListMember `json:"data"`
+}
+
+func (h *Handler) getListMembers(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		members, err := h.services.List.GetMembers(r.Context(), userID, listID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get list members")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getListMembersResponse{
+			Data: members,
+		})
+	}
+}
+
+func (h *Handler) addListMember(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		var input bookshelf.AddListMemberInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		if err := h.services.List.AddMember(r.Context(), userID, listID, input); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot add list member")
+			return
+		}
+
+		log.Info("list member has been added")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
+
+func (h *Handler) updateListMember(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+		if err != nil {
+			log.Error("invalid user id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid user id")
+			return
+		}
+
+		var input bookshelf.UpdateListMemberInput
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			log.Error(err.Error())
+			problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			log.Error(err.Error())
+			validationError(w, r, err)
+			return
+		}
+
+		if err := h.services.List.UpdateMember(r.Context(), userID, listID, memberID, input); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot update list member")
+			return
+		}
+
+		log.Info("list member has been updated")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
+
+func (h *Handler) removeListMember(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+		if err != nil {
+			log.Error("invalid user id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid user id")
+			return
+		}
+
+		if err := h.services.List.RemoveMember(r.Context(), userID, listID, memberID); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot remove list member")
+			return
+		}
+
+		log.Info("list member has been removed")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
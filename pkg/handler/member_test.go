@@ -0,0 +1,95 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"bytes"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_addListMember(t *testing.T) {
+	type mockBehaviour func(list *mocks.List, input bookshelf.AddListMemberInput)
+
+	tests := []struct {
+		name           string
+		inputBody      string
+		input          bookshelf.AddListMemberInput
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"username":"friend","role":"viewer"}`,
+			input:     bookshelf.AddListMemberInput{Username: "friend", Role: bookshelf.RoleViewer},
+			mockBehaviour: func(list *mocks.List, input bookshelf.AddListMemberInput) {
+				list.On("AddMember", mock.Anything, 1, 1, input).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"status\":\"OK\"}\n",
+		},
+		{
+			name:           "Invalid role",
+			inputBody:      `{"username":"friend","role":"admin"}`,
+			mockBehaviour:  func(list *mocks.List, input bookshelf.AddListMemberInput) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"request validation failed\",\"code\":\"validation_failed\",\"errors\":[{\"field\":\"role\",\"rule\":\"oneof\",\"message\":\"role must be one of: owner editor viewer\"}]}\n",
+		},
+		{
+			name:      "Not an owner",
+			inputBody: `{"username":"friend","role":"editor"}`,
+			input:     bookshelf.AddListMemberInput{Username: "friend", Role: bookshelf.RoleEditor},
+			mockBehaviour: func(list *mocks.List, input bookshelf.AddListMemberInput) {
+				list.On("AddMember", mock.Anything, 1, 1, input).Return(bookshelf.ErrForbidden)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Forbidden\",\"status\":403,\"detail\":\"access denied\",\"code\":\"forbidden\"}\n",
+		},
+		{
+			name:      "Unknown user",
+			inputBody: `{"username":"nobody","role":"editor"}`,
+			input:     bookshelf.AddListMemberInput{Username: "nobody", Role: bookshelf.RoleEditor},
+			mockBehaviour: func(list *mocks.List, input bookshelf.AddListMemberInput) {
+				list.On("AddMember", mock.Anything, 1, 1, input).Return(bookshelf.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"not found\",\"code\":\"not_found\"}\n",
+		},
+		{
+			name:      "Already a member",
+			inputBody: `{"username":"friend","role":"editor"}`,
+			input:     bookshelf.AddListMemberInput{Username: "friend", Role: bookshelf.RoleEditor},
+			mockBehaviour: func(list *mocks.List, input bookshelf.AddListMemberInput) {
+				list.On("AddMember", mock.Anything, 1, 1, input).Return(service.ErrAlreadyMember)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Conflict\",\"status\":409,\"detail\":\"user is already a member of the list\",\"code\":\"already_member\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := mocks.NewList(t)
+			tt.mockBehaviour(list, tt.input)
+			h := Handler{&service.Service{List: list}}
+
+			r := chi.NewRouter()
+			r.Post("/lists/{id}/members", h.addListMember(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodPost, "/lists/1/members", bytes.NewReader([]byte(tt.inputBody)))
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/service"
 	"context"
-	"github.com/go-chi/render"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,33 +18,151 @@ const (
 func (h *Handler) userIdentity(log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			header := r.Header.Get(authHeader)
-			if header == "" {
-				log.Error("empty auth header")
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, Error("empty auth header"))
+			ctx, err := h.identify(r)
+			if err != nil {
+				log.Error(err.Error())
+				problem(w, r, http.StatusUnauthorized, codeUnauthorized, err.Error())
 				return
 			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			headerParts := strings.Split(header, " ")
-			if len(headerParts) != 2 || headerParts[0] != "Bearer" || headerParts[1] == "" {
-				log.Error("invalid auth header")
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, Error("invalid auth header"))
-				return
+// basicIdentity is userIdentity for clients that cannot sign in, such as
+// e-readers. It also accepts HTTP Basic credentials, the password may be an
+// API token, and asks for them when they are missing.
+func (h *Handler) basicIdentity(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ctx context.Context
+			var err error
+			if username, password, ok := r.BasicAuth(); !ok {
+				ctx, err = h.identify(r)
+			} else if strings.HasPrefix(password, service.APITokenPrefix) {
+				ctx, err = h.identifyAPIToken(r, password)
+			} else {
+				var id int
+				if id, err = h.services.Authorization.Authenticate(r.Context(), username, password); err == nil {
+					ctx = context.WithValue(r.Context(), "userID", id)
+				}
 			}
-
-			id, err := h.services.Authorization.ParseToken(headerParts[1])
 			if err != nil {
 				log.Error(err.Error())
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, Error(err.Error()))
+				w.Header().Set("WWW-Authenticate", `Basic realm="bookshelf", charset="UTF-8"`)
+				problem(w, r, http.StatusUnauthorized, codeUnauthorized, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// identify authenticates the bearer token of the request, either an API
+// token or a JWT, and returns the context carrying the user.
+func (h *Handler) identify(r *http.Request) (context.Context, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(token, service.APITokenPrefix) {
+		return h.identifyAPIToken(r, token)
+	}
+
+	id, err := h.services.Authorization.ParseToken(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(r.Context(), "userID", id), nil
+}
+
+func (h *Handler) identifyAPIToken(r *http.Request, token string) (context.Context, error) {
+	apiToken, err := h.services.APIToken.Authenticate(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(r.Context(), "userID", apiToken.UserID)
+	return context.WithValue(ctx, "apiToken", apiToken), nil
+}
+
+// requireScope rejects requests authenticated with an API token that does
+// not grant scope. Requests authenticated with a JWT have every scope.
+func (h *Handler) requireScope(log *slog.Logger, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiToken, ok := r.Context().Value("apiToken").(bookshelf.APIToken)
+			if ok && !apiToken.HasScope(scope) {
+				log.Error("insufficient scope", slog.String("scope", scope))
+				problem(w, r, http.StatusForbidden, codeInsufficientScope, "token has no "+scope+" scope")
 				return
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			ctx := context.WithValue(r.Context(), "userID", id)
+// sessionOnly rejects requests authenticated with an API token.
+func (h *Handler) sessionOnly(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value("apiToken").(bookshelf.APIToken); ok {
+				log.Error("api token used for session only route")
+				problem(w, r, http.StatusForbidden, codeSessionRequired, "api tokens are not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
+// requestTimeout is the limit set by timeout, kept in the request context so
+// streaming handlers can push it back.
+type requestTimeout struct {
+	timer *time.Timer
+	d     time.Duration
+}
+
+// timeout cancels the request context once d has passed, so storage calls
+// stop when the server would drop the response anyway. A zero d disables
+// the limit, as it does for http.Server.
+func timeout(d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+			timer := time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) })
+			defer timer.Stop()
+			ctx = context.WithValue(ctx, "timeout", &requestTimeout{timer: timer, d: d})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// extendTimeout gives a streamed response another full timeout, both for the
+// request context and for the server's write deadline.
+func extendTimeout(w http.ResponseWriter, r *http.Request) {
+	t, ok := r.Context().Value("timeout").(*requestTimeout)
+	if !ok {
+		return
+	}
+	t.timer.Reset(t.d)
+	// Writers without deadlines, such as test recorders, are not bound by
+	// the server's write timeout either.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(t.d))
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get(authHeader)
+	if header == "" {
+		return "", errors.New("empty auth header")
+	}
+
+	headerParts := strings.Split(header, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" || headerParts[1] == "" {
+		return "", errors.New("invalid auth header")
+	}
+	return headerParts[1], nil
+}
@@ -1,16 +1,22 @@
 package handler
 
 import (
+	bookshelf "bookshelf-api"
 	"bookshelf-api/pkg/lib/slogdiscard"
 	"bookshelf-api/pkg/service"
 	"bookshelf-api/pkg/service/mocks"
+	"context"
 	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/render"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestHandler_userIdentity(t *testing.T) {
@@ -32,7 +38,7 @@ func TestHandler_userIdentity(t *testing.T) {
 			token:       "token",
 			mockBehaviour: func(auth *mocks.Authorization, token string) {
 				auth.
-					On("ParseToken", token).
+					On("ParseToken", mock.Anything, token).
 					Return(1, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -45,7 +51,7 @@ func TestHandler_userIdentity(t *testing.T) {
 			token:          "token",
 			mockBehaviour:  func(auth *mocks.Authorization, token string) {},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "{\"error\":\"empty auth header\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"empty auth header\",\"code\":\"unauthorized\"}\n",
 		},
 		{
 			name:           "invalid header value",
@@ -54,7 +60,7 @@ func TestHandler_userIdentity(t *testing.T) {
 			token:          "token",
 			mockBehaviour:  func(auth *mocks.Authorization, token string) {},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "{\"error\":\"invalid auth header\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"invalid auth header\",\"code\":\"unauthorized\"}\n",
 		},
 		{
 			name:           "empty token",
@@ -63,7 +69,7 @@ func TestHandler_userIdentity(t *testing.T) {
 			token:          "token",
 			mockBehaviour:  func(auth *mocks.Authorization, token string) {},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "{\"error\":\"invalid auth header\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"invalid auth header\",\"code\":\"unauthorized\"}\n",
 		},
 		{
 			name:        "parse error",
@@ -71,11 +77,11 @@ func TestHandler_userIdentity(t *testing.T) {
 			headerValue: "Bearer token",
 			token:       "token",
 			mockBehaviour: func(auth *mocks.Authorization, token string) {
-				auth.On("ParseToken", token).
+				auth.On("ParseToken", mock.Anything, token).
 					Return(0, errors.New("invalid token"))
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "{\"error\":\"invalid token\"}\n",
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"invalid token\",\"code\":\"unauthorized\"}\n",
 		},
 	}
 	for _, tt := range tests {
@@ -89,8 +95,7 @@ func TestHandler_userIdentity(t *testing.T) {
 				userID, ok := r.Context().Value("userID").(int)
 				if !ok {
 					t.Error("user id not found")
-					render.Status(r, http.StatusInternalServerError)
-					render.JSON(w, r, Error("user id not found"))
+					problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
 					return
 				}
 				render.Status(r, http.StatusOK)
@@ -107,3 +112,203 @@ func TestHandler_userIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_userIdentity_APIToken(t *testing.T) {
+	type mockBehaviour func(apiToken *mocks.APIToken, token string)
+
+	tests := []struct {
+		name           string
+		token          string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "OK",
+			token: "bsk_token",
+			mockBehaviour: func(apiToken *mocks.APIToken, token string) {
+				apiToken.On("Authenticate", mock.Anything, token).
+					Return(bookshelf.APIToken{ID: 1, UserID: 2, Scopes: []string{bookshelf.ScopeListsRead}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "2",
+		},
+		{
+			name:  "Expired token",
+			token: "bsk_token",
+			mockBehaviour: func(apiToken *mocks.APIToken, token string) {
+				apiToken.On("Authenticate", mock.Anything, token).
+					Return(bookshelf.APIToken{}, service.ErrInvalidToken)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"invalid token\",\"code\":\"unauthorized\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiToken := mocks.NewAPIToken(t)
+			tt.mockBehaviour(apiToken, tt.token)
+			h := Handler{&service.Service{APIToken: apiToken}}
+
+			nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID := r.Context().Value("userID").(int)
+				render.Data(w, r, []byte(strconv.Itoa(userID)))
+			})
+			handlerToTest := h.userIdentity(slogdiscard.NewDiscardLogger())(nextHandler)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/identity", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			handlerToTest.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_basicIdentity(t *testing.T) {
+	type mockBehaviour func(auth *mocks.Authorization, apiToken *mocks.APIToken)
+
+	tests := []struct {
+		name              string
+		setAuth           func(r *http.Request)
+		mockBehaviour     mockBehaviour
+		expectedStatus    int
+		expectedBody      string
+		expectedChallenge bool
+	}{
+		{
+			name:    "Password",
+			setAuth: func(r *http.Request) { r.SetBasicAuth("reader", "secret") },
+			mockBehaviour: func(auth *mocks.Authorization, apiToken *mocks.APIToken) {
+				auth.On("Authenticate", mock.Anything, "reader", "secret").Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "1",
+		},
+		{
+			name:    "API token as password",
+			setAuth: func(r *http.Request) { r.SetBasicAuth("reader", "bsk_token") },
+			mockBehaviour: func(auth *mocks.Authorization, apiToken *mocks.APIToken) {
+				apiToken.On("Authenticate", mock.Anything, "bsk_token").Return(bookshelf.APIToken{ID: 1, UserID: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "2",
+		},
+		{
+			name:    "API token as bearer",
+			setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer bsk_token") },
+			mockBehaviour: func(auth *mocks.Authorization, apiToken *mocks.APIToken) {
+				apiToken.On("Authenticate", mock.Anything, "bsk_token").Return(bookshelf.APIToken{ID: 1, UserID: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "3",
+		},
+		{
+			name:    "Wrong password",
+			setAuth: func(r *http.Request) { r.SetBasicAuth("reader", "wrong") },
+			mockBehaviour: func(auth *mocks.Authorization, apiToken *mocks.APIToken) {
+				auth.On("Authenticate", mock.Anything, "reader", "wrong").Return(0, service.ErrInvalidCredentials)
+			},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBody:      "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"invalid username or password\",\"code\":\"unauthorized\"}\n",
+			expectedChallenge: true,
+		},
+		{
+			name:              "No credentials",
+			setAuth:           func(r *http.Request) {},
+			mockBehaviour:     func(auth *mocks.Authorization, apiToken *mocks.APIToken) {},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedBody:      "{\"type\":\"about:blank\",\"title\":\"Unauthorized\",\"status\":401,\"detail\":\"empty auth header\",\"code\":\"unauthorized\"}\n",
+			expectedChallenge: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := mocks.NewAuthorization(t)
+			apiToken := mocks.NewAPIToken(t)
+			tt.mockBehaviour(auth, apiToken)
+			h := Handler{&service.Service{Authorization: auth, APIToken: apiToken}}
+
+			nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID := r.Context().Value("userID").(int)
+				render.Data(w, r, []byte(strconv.Itoa(userID)))
+			})
+			handlerToTest := h.basicIdentity(slogdiscard.NewDiscardLogger())(nextHandler)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/opds", nil)
+			tt.setAuth(req)
+			handlerToTest.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, tt.expectedChallenge, w.Header().Get("WWW-Authenticate") != "")
+		})
+	}
+}
+
+func TestHandler_requireScope(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiToken       *bookshelf.APIToken
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "JWT",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "OK",
+		},
+		{
+			name:           "Scope granted",
+			apiToken:       &bookshelf.APIToken{Scopes: []string{bookshelf.ScopeBooksRead, bookshelf.ScopeBooksWrite}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "OK",
+		},
+		{
+			name:           "Scope missing",
+			apiToken:       &bookshelf.APIToken{Scopes: []string{bookshelf.ScopeBooksRead}},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Forbidden\",\"status\":403,\"detail\":\"token has no books:write scope\",\"code\":\"insufficient_scope\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Handler{&service.Service{}}
+
+			nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				render.Data(w, r, []byte("OK"))
+			})
+			handlerToTest := h.requireScope(slogdiscard.NewDiscardLogger(), bookshelf.ScopeBooksWrite)(nextHandler)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.apiToken != nil {
+				req = req.WithContext(context.WithValue(req.Context(), "apiToken", *tt.apiToken))
+			}
+			handlerToTest.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	dbMock.ExpectQuery("SELECT id FROM lists").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var queryErr error
+	handler := timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, queryErr = db.QueryContext(r.Context(), "SELECT id FROM lists")
+	}))
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.ErrorIs(t, queryErr, sqlmock.ErrCancelled)
+	assert.Less(t, time.Since(start), time.Second)
+}
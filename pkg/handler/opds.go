@@ -0,0 +1,223 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bytes"
+	"encoding/xml"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	opdsNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	opdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	opdsRootPath        = "/opds"
+
+	// opdsAcquisitionRel links a book entry to the resource it describes,
+	// OPDS 1.2 requires one on every entry of an acquisition feed.
+	opdsAcquisitionRel = "http://opds-spec.org/acquisition"
+)
+
+// opdsFeed is an Atom feed of an OPDS 1.2 catalog. Books are described with
+// Dublin Core terms, as the specification recommends.
+type opdsFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	XmlnsDC string      `xml:"xmlns:dc,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  opdsAuthor  `xml:"author"`
+	Links   []opdsLink  `xml:"link"`
+	Entries []opdsEntry `xml:"entry"`
+}
+
+type opdsEntry struct {
+	ID         string       `xml:"id"`
+	Title      string       `xml:"title"`
+	Updated    string       `xml:"updated"`
+	Authors    []opdsAuthor `xml:"author"`
+	Identifier string       `xml:"dc:identifier,omitempty"`
+	Publisher  string       `xml:"dc:publisher,omitempty"`
+	Issued     string       `xml:"dc:issued,omitempty"`
+	Extent     string       `xml:"dc:extent,omitempty"`
+	Content    *opdsContent `xml:"content"`
+	Links      []opdsLink   `xml:"link"`
+}
+
+type opdsAuthor struct {
+	Name string `xml:"name"`
+}
+
+type opdsContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type opdsLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// newOPDSFeed starts a feed served at self. A non-empty next cursor adds a
+// link to the next page, keeping the other query parameters.
+func newOPDSFeed(r *http.Request, id, title, self, kind, next string) opdsFeed {
+	feed := opdsFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		XmlnsDC: "http://purl.org/dc/terms/",
+		ID:      id,
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  opdsAuthor{Name: "Bookshelf"},
+		Links: []opdsLink{
+			{Rel: "self", Href: self, Type: kind},
+			{Rel: "start", Href: opdsRootPath, Type: opdsNavigationType},
+		},
+		Entries: []opdsEntry{},
+	}
+	if self != opdsRootPath {
+		feed.Links = append(feed.Links, opdsLink{Rel: "up", Href: opdsRootPath, Type: opdsNavigationType})
+	}
+	if next != "" {
+		query := r.URL.Query()
+		query.Set("cursor", next)
+		feed.Links = append(feed.Links, opdsLink{Rel: "next", Href: self + "?" + query.Encode(), Type: kind})
+	}
+	return feed
+}
+
+// opdsRoot serves the navigation feed with one entry per list of the user.
+func (h *Handler) opdsRoot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		filter, err := parseListFilter(r)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+
+		lists, next, err := h.services.List.GetAll(r.Context(), userID, filter)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get all lists")
+			return
+		}
+
+		feed := newOPDSFeed(r, "urn:bookshelf:user:"+strconv.Itoa(userID), "Bookshelf", opdsRootPath, opdsNavigationType, next)
+		for _, list := range lists {
+			feed.Entries = append(feed.Entries, opdsEntry{
+				ID:      "urn:bookshelf:list:" + strconv.Itoa(list.ID),
+				Title:   list.Title,
+				Updated: feed.Updated,
+				Content: &opdsContent{Type: "text", Text: list.Description},
+				Links: []opdsLink{{
+					Rel:  "subsection",
+					Href: opdsRootPath + "/lists/" + strconv.Itoa(list.ID),
+					Type: opdsAcquisitionType,
+				}},
+			})
+		}
+		writeOPDSFeed(w, r, log, opdsNavigationType, feed)
+	}
+}
+
+// opdsList serves the acquisition feed with the books of a list.
+func (h *Handler) opdsList(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		var filter bookshelf.BookFilter
+		if filter.Limit, filter.After, err = parsePage(r); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+
+		list, err := h.services.List.GetByID(r.Context(), userID, listID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get list")
+			return
+		}
+
+		books, next, err := h.services.Book.GetAll(r.Context(), userID, listID, filter)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get books")
+			return
+		}
+
+		self := opdsRootPath + "/lists/" + strconv.Itoa(listID)
+		feed := newOPDSFeed(r, "urn:bookshelf:list:"+strconv.Itoa(listID), list.Title, self, opdsAcquisitionType, next)
+		for _, book := range books {
+			feed.Entries = append(feed.Entries, opdsBookEntry(book, feed.Updated))
+		}
+		writeOPDSFeed(w, r, log, opdsAcquisitionType, feed)
+	}
+}
+
+func opdsBookEntry(book bookshelf.Book, updated string) opdsEntry {
+	entry := opdsEntry{
+		ID:        "urn:bookshelf:book:" + strconv.Itoa(book.ID),
+		Title:     book.Title,
+		Updated:   updated,
+		Publisher: book.Publisher,
+	}
+	if book.Author != "" {
+		entry.Authors = append(entry.Authors, opdsAuthor{Name: book.Author})
+	}
+	if book.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + book.ISBN
+	}
+	if book.PublicationYear > 0 {
+		entry.Issued = strconv.Itoa(book.PublicationYear)
+	}
+	if book.PageCount > 0 {
+		entry.Extent = strconv.Itoa(book.PageCount) + " pages"
+	}
+	// The catalog has no book files, the book itself is served as JSON.
+	entry.Links = []opdsLink{{
+		Rel:  opdsAcquisitionRel,
+		Href: opdsRootPath + "/books/" + strconv.Itoa(book.ID),
+		Type: "application/json",
+	}}
+	return entry
+}
+
+func writeOPDSFeed(w http.ResponseWriter, r *http.Request, log *slog.Logger, contentType string, feed opdsFeed) {
+	buf := bytes.NewBufferString(xml.Header)
+	if err := xml.NewEncoder(buf).Encode(feed); err != nil {
+		log.Error(err.Error())
+		problem(w, r, http.StatusInternalServerError, codeInternal, "cannot write feed")
+		return
+	}
+	buf.WriteByte('\n')
+
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+	log.Info("opds feed has been written", slog.Int("entries", len(feed.Entries)))
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"context"
+	"encoding/xml"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_opdsRoot(t *testing.T) {
+	lists := mocks.NewList(t)
+	lists.On("GetAll", mock.Anything, 1, bookshelf.ListFilter{Limit: 2}).Return([]bookshelf.List{
+		{ID: 1, Title: "Sci-fi", Description: "Space & time"},
+		{ID: 2, Title: "Classics"},
+	}, "next", nil)
+	handler := Handler{&service.Service{List: lists}}
+
+	r := chi.NewRouter()
+	r.Get("/opds", handler.opdsRoot(slogdiscard.NewDiscardLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/opds?limit=2", nil)
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), "userID", 1)
+	r.ServeHTTP(w, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, opdsNavigationType+";charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/terms/">`)
+
+	var feed opdsFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, "urn:bookshelf:user:1", feed.ID)
+	assert.Equal(t, []opdsLink{
+		{Rel: "self", Href: "/opds", Type: opdsNavigationType},
+		{Rel: "start", Href: "/opds", Type: opdsNavigationType},
+		{Rel: "next", Href: "/opds?cursor=next&limit=2", Type: opdsNavigationType},
+	}, feed.Links)
+	require.Len(t, feed.Entries, 2)
+	assert.Equal(t, "Sci-fi", feed.Entries[0].Title)
+	assert.Equal(t, "Space & time", feed.Entries[0].Content.Text)
+	assert.Equal(t, []opdsLink{{Rel: "subsection", Href: "/opds/lists/1", Type: opdsAcquisitionType}}, feed.Entries[0].Links)
+}
+
+func TestHandler_opdsList(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockBehaviour  func(lists *mocks.List, books *mocks.Book)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "OK",
+			mockBehaviour: func(lists *mocks.List, books *mocks.Book) {
+				lists.On("GetByID", mock.Anything, 1, 2).Return(bookshelf.List{ID: 2, Title: "Sci-fi"}, nil)
+				books.On("GetAll", mock.Anything, 1, 2, mock.Anything).Return([]bookshelf.Book{
+					{ID: 3, Title: "Dune", Author: "Frank Herbert", Publisher: "Chilton", PublicationYear: 1965, PageCount: 412, ISBN: "9780441172719"},
+					{ID: 4, Title: "Anonymous"},
+				}, "", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Forbidden",
+			mockBehaviour: func(lists *mocks.List, books *mocks.Book) {
+				lists.On("GetByID", mock.Anything, 1, 2).Return(bookshelf.List{}, bookshelf.ErrForbidden)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Forbidden\",\"status\":403,\"detail\":\"access denied\",\"code\":\"forbidden\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := mocks.NewList(t)
+			books := mocks.NewBook(t)
+			tt.mockBehaviour(lists, books)
+			handler := Handler{&service.Service{List: lists, Book: books}}
+
+			r := chi.NewRouter()
+			r.Get("/opds/lists/{id}", handler.opdsList(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/opds/lists/2", nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				return
+			}
+
+			assert.Equal(t, opdsAcquisitionType+";charset=utf-8", w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Body.String(), "<dc:identifier>urn:isbn:9780441172719</dc:identifier>")
+			assert.Contains(t, w.Body.String(), "<dc:issued>1965</dc:issued>")
+
+			var feed opdsFeed
+			require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+			assert.Equal(t, "Sci-fi", feed.Title)
+			assert.Len(t, feed.Links, 3)
+			require.Len(t, feed.Entries, 2)
+			assert.Equal(t, "urn:bookshelf:book:3", feed.Entries[0].ID)
+			assert.Equal(t, []opdsAuthor{{Name: "Frank Herbert"}}, feed.Entries[0].Authors)
+			assert.Equal(t, []opdsLink{{Rel: opdsAcquisitionRel, Href: "/opds/books/3", Type: "application/json"}}, feed.Entries[0].Links)
+			assert.Empty(t, feed.Entries[1].Authors)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"net/http"
+	"strconv"
+)
+
+func parseListFilter(r *http.Request) (bookshelf.ListFilter, error) {
+	query := r.URL.Query()
+	filter := bookshelf.ListFilter{Sort: query.Get("sort")}
+
+	var err error
+	if filter.Limit, filter.After, err = parsePage(r); err != nil {
+		return bookshelf.ListFilter{}, err
+	}
+	return filter, nil
+}
+
+func parseBookFilter(r *http.Request) (bookshelf.BookFilter, error) {
+	query := r.URL.Query()
+	filter := bookshelf.BookFilter{
+		Sort:      query.Get("sort"),
+		Author:    query.Get("author"),
+		Publisher: query.Get("publisher"),
+	}
+
+	var err error
+	if filter.Limit, filter.After, err = parsePage(r); err != nil {
+		return bookshelf.BookFilter{}, err
+	}
+	if filter.YearFrom, err = queryInt(r, "year_from"); err != nil {
+		return bookshelf.BookFilter{}, err
+	}
+	if filter.YearTo, err = queryInt(r, "year_to"); err != nil {
+		return bookshelf.BookFilter{}, err
+	}
+	return filter, nil
+}
+
+// parsePage reads the limit and cursor query parameters. A missing limit is
+// returned as zero and left for the service to default.
+func parsePage(r *http.Request) (int, *bookshelf.Cursor, error) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var after *bookshelf.Cursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		if after, err = bookshelf.ParseCursor(cursor); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if limit == nil {
+		return 0, after, nil
+	}
+	if *limit < 1 {
+		return 0, nil, bookshelf.NewError(bookshelf.ErrValidation, "invalid_limit", "limit must be positive")
+	}
+	return *limit, after, nil
+}
+
+func queryInt(r *http.Request, name string) (*int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, bookshelf.NewError(bookshelf.ErrValidation, "invalid_query", name+" must be a number")
+	}
+	return &n, nil
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, bookshelf.NewError(bookshelf.ErrValidation, "invalid_query", name+" must be true or false")
+	}
+	return b, nil
+}
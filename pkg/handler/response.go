@@ -1,8 +1,31 @@
 package handler
 
-type Response struct {
-	Error string `json:"error,omitempty"`
-}
+import (
+	bookshelf "bookshelf-api"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// Codes of problems detected by the handlers themselves. Errors coming from
+// the services carry their own code, see bookshelf.Code.
+const (
+	codeInternal           = "internal_error"
+	codeInvalidRequest     = "invalid_request"
+	codeInvalidID          = "invalid_id"
+	codeValidation         = "validation_failed"
+	codeUnauthorized       = "unauthorized"
+	codeInvalidCredentials = "invalid_credentials"
+	codeInvalidToken       = "invalid_token"
+	codeTokenRevoked       = "token_revoked"
+	codeInsufficientScope  = "insufficient_scope"
+	codeSessionRequired    = "session_required"
+	codeShareNotFound      = "share_not_found"
+)
 
 type Status struct {
 	Status string `json:"status"`
@@ -12,8 +35,92 @@ func OK() Status {
 	return Status{Status: "OK"}
 }
 
-func Error(msg string) Response {
-	return Response{
-		Error: msg,
+// Problem is an RFC 7807 problem details object. Code is a stable identifier
+// clients can rely on instead of parsing Detail.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: middleware.GetReqID(r.Context()),
+		Code:     code,
+	}
+}
+
+func renderProblem(w http.ResponseWriter, p Problem) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	w.Write(buf.Bytes())
+}
+
+// problem responds with a problem of the given status and code.
+func problem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	renderProblem(w, newProblem(r, status, code, detail))
+}
+
+// serviceError responds with the problem matching the kind of err. Errors
+// of unknown kinds are internal, msg is shown instead so driver details do
+// not leak.
+func serviceError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		problem(w, r, status, codeInternal, msg)
+		return
+	}
+	message, _ := bookshelf.Message(err)
+	problem(w, r, status, bookshelf.Code(err), message)
+}
+
+// validationError responds with 422 and one entry per invalid field. Errors
+// other than validator.ValidationErrors are treated as malformed input.
+func validationError(w http.ResponseWriter, r *http.Request, err error) {
+	fields := fieldErrors(err)
+	if fields == nil {
+		problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+		return
+	}
+	p := newProblem(r, http.StatusUnprocessableEntity, codeValidation, "request validation failed")
+	p.Errors = fields
+	renderProblem(w, p)
+}
+
+// errorStatus translates the error kinds of the bookshelf package into an
+// HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, bookshelf.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, bookshelf.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, bookshelf.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, bookshelf.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, bookshelf.ErrUpstream):
+		return http.StatusBadGateway
 	}
+	return http.StatusInternalServerError
 }
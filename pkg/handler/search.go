@@ -0,0 +1,45 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type searchResponse struct {
+	Data       []bookshelf.SearchHit `json:"data"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+func (h *Handler) search(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		query := bookshelf.SearchQuery{Query: r.URL.Query().Get("q")}
+		var err error
+		if query.Limit, query.After, err = parsePage(r); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "invalid query")
+			return
+		}
+
+		hits, next, err := h.services.Search.Search(r.Context(), userID, query)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot search")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, searchResponse{
+			Data:       hits,
+			NextCursor: next,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_search(t *testing.T) {
+	type mockBehaviour func(search *mocks.Search)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "OK",
+			query: "?q=dune&limit=1",
+			mockBehaviour: func(search *mocks.Search) {
+				search.On("Search", mock.Anything, 1, bookshelf.SearchQuery{Query: "dune", Limit: 1}).
+					Return([]bookshelf.SearchHit{{Kind: "book", ID: 2, Title: "Dune", Headline: "<mark>Dune</mark>", Rank: 0.5}}, "next", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"data\":[{\"kind\":\"book\",\"id\":2,\"title\":\"Dune\",\"headline\":\"\\u003cmark\\u003eDune\\u003c/mark\\u003e\",\"rank\":0.5}],\"next_cursor\":\"next\"}\n",
+		},
+		{
+			name:  "Empty query",
+			query: "",
+			mockBehaviour: func(search *mocks.Search) {
+				search.On("Search", mock.Anything, 1, bookshelf.SearchQuery{}).
+					Return(nil, "", bookshelf.NewError(bookshelf.ErrValidation, "empty_query", "search query is empty"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"search query is empty\",\"code\":\"empty_query\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := mocks.NewSearch(t)
+			tt.mockBehaviour(search)
+			handler := Handler{&service.Service{Search: search}}
+
+			r := chi.NewRouter()
+			r.Get("/search", handler.search(slogdiscard.NewDiscardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), "userID", 1)
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/service"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type createListShareResponse struct {
+	Token string              `json:"token"`
+	Data  bookshelf.ListShare `json:"data"`
+}
+
+func (h *Handler) createListShare(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		var input bookshelf.CreateListShareInput
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &input); err != nil {
+				log.Error(err.Error())
+				problem(w, r, http.StatusBadRequest, codeInvalidRequest, "invalid request")
+				return
+			}
+		}
+
+		share, token, err := h.services.Share.Create(r.Context(), userID, listID, input)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot create share link")
+			return
+		}
+
+		log.Info("share link has been created")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, createListShareResponse{
+			Token: token,
+			Data:  share,
+		})
+	}
+}
+
+type getListSharesResponse struct {
+	Data []bookshelf.ListShare `json:"data"`
+}
+
+func (h *Handler) getListShares(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		shares, err := h.services.Share.GetAll(r.Context(), userID, listID)
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get share links")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getListSharesResponse{
+			Data: shares,
+		})
+	}
+}
+
+func (h *Handler) revokeListShare(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		if !ok {
+			log.Error("user id not found")
+			problem(w, r, http.StatusInternalServerError, codeInternal, "user id not found")
+			return
+		}
+
+		listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid id")
+			return
+		}
+
+		shareID, err := strconv.Atoi(chi.URLParam(r, "shareID"))
+		if err != nil {
+			log.Error("invalid share id")
+			problem(w, r, http.StatusBadRequest, codeInvalidID, "invalid share id")
+			return
+		}
+
+		if err := h.services.Share.Revoke(r.Context(), userID, listID, shareID); err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot revoke share link")
+			return
+		}
+
+		log.Info("share link has been revoked")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, OK())
+	}
+}
+
+type getSharedListResponse struct {
+	Data  bookshelf.List   `json:"data"`
+	Books []bookshelf.Book `json:"books"`
+}
+
+func (h *Handler) getSharedList(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		list, books, err := h.services.Share.Resolve(r.Context(), chi.URLParam(r, "token"))
+		if errors.Is(err, service.ErrInvalidToken) {
+			log.Error(err.Error())
+			problem(w, r, http.StatusNotFound, codeShareNotFound, "share link not found")
+			return
+		}
+		if err != nil {
+			log.Error(err.Error())
+			serviceError(w, r, err, "cannot get shared list")
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, getSharedListResponse{
+			Data:  list,
+			Books: books,
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
+	"bookshelf-api/pkg/lib/slogdiscard"
+	"bookshelf-api/pkg/service"
+	"bookshelf-api/pkg/service/mocks"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_getSharedList(t *testing.T) {
+	type mockBehaviour func(share *mocks.Share, token string)
+
+	tests := []struct {
+		name           string
+		token          string
+		mockBehaviour  mockBehaviour
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "OK",
+			token: "token",
+			mockBehaviour: func(share *mocks.Share, token string) {
+				share.On("Resolve", mock.Anything, token).Return(
+					bookshelf.List{ID: 1, Title: "title"},
+					[]bookshelf.Book{{ID: 2, Title: "book", Author: "author"}},
+					nil,
+				)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"data\":{\"id\":1,\"title\":\"title\",\"description\":\"\"},\"books\":[{\"id\":2,\"title\":\"book\",\"author\":\"author\",\"publisher\":\"\",\"publication_year\":0,\"page_count\":0}]}\n",
+		},
+		{
+			name:  "Revoked",
+			token: "token",
+			mockBehaviour: func(share *mocks.Share, token string) {
+				share.On("Resolve", mock.Anything, token).Return(bookshelf.List{}, nil, service.ErrInvalidToken)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"share link not found\",\"instance\":\"req-1\",\"code\":\"share_not_found\"}\n",
+		},
+		{
+			name:  "Service error",
+			token: "token",
+			mockBehaviour: func(share *mocks.Share, token string) {
+				share.On("Resolve", mock.Anything, token).Return(bookshelf.List{}, nil, errors.New("some error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"cannot get shared list\",\"instance\":\"req-1\",\"code\":\"internal_error\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := mocks.NewShare(t)
+			tt.mockBehaviour(share, tt.token)
+			h := Handler{&service.Service{Share: share}}
+
+			req := httptest.NewRequest(http.MethodGet, "/shared/"+tt.token, nil)
+			req.Header.Set(middleware.RequestIDHeader, "req-1")
+			w := httptest.NewRecorder()
+			h.InitRoutes(slogdiscard.NewDiscardLogger(), config.HTTPServer{}).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getSharedList_Extension(t *testing.T) {
+	share := mocks.NewShare(t)
+	share.On("Resolve", mock.Anything, "token").Return(bookshelf.List{ID: 1, Title: "title"}, []bookshelf.Book{}, nil)
+	h := Handler{&service.Service{Share: share}}
+
+	req := httptest.NewRequest(http.MethodGet, "/shared/token.json", nil)
+	w := httptest.NewRecorder()
+	h.InitRoutes(slogdiscard.NewDiscardLogger(), config.HTTPServer{}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// validate reports fields by their JSON names so errors match the request
+// body the client sent.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+func fieldErrors(err error) []FieldError {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return fields
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "oneof":
+		return fe.Field() + " must be one of: " + fe.Param()
+	case "min":
+		return fe.Field() + " must be at least " + fe.Param()
+	case "max":
+		return fe.Field() + " must be at most " + fe.Param()
+	}
+	return fe.Field() + " is invalid"
+}
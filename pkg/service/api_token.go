@@ -0,0 +1,100 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix marks personal access tokens so they can be told apart from
+// JWTs in the Authorization header.
+const APITokenPrefix = "bsk_"
+
+var ErrInvalidScope = bookshelf.NewError(bookshelf.ErrValidation, "invalid_scope", "invalid scope")
+
+type APITokenService struct {
+	storage storage.APIToken
+}
+
+func NewAPITokenService(storage storage.APIToken) *APITokenService {
+	return &APITokenService{storage: storage}
+}
+
+// Create issues a new API token and returns it together with the raw token,
+// which is not stored. A token without scopes gets read-only access.
+func (s *APITokenService) Create(ctx context.Context, userID int, input bookshelf.CreateAPITokenInput) (bookshelf.APIToken, string, error) {
+	if len(input.Scopes) == 0 {
+		input.Scopes = append([]string{}, bookshelf.DefaultScopes...)
+	}
+	for _, scope := range input.Scopes {
+		if !validScope(scope) {
+			return bookshelf.APIToken{}, "", bookshelf.NewError(ErrInvalidScope, "invalid_scope", "invalid scope "+strconv.Quote(scope))
+		}
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		return bookshelf.APIToken{}, "", bookshelf.NewError(bookshelf.ErrValidation, "invalid_expiration", "expiration time is in the past")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return bookshelf.APIToken{}, "", err
+	}
+	raw := APITokenPrefix + secret
+
+	token := bookshelf.APIToken{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    raw[:len(APITokenPrefix)+6],
+		TokenHash: hashToken(raw),
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	token.ID, err = s.storage.Create(ctx, token)
+	if err != nil {
+		return bookshelf.APIToken{}, "", err
+	}
+	return token, raw, nil
+}
+
+func (s *APITokenService) GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error) {
+	return s.storage.GetAll(ctx, userID)
+}
+
+func (s *APITokenService) Delete(ctx context.Context, userID, tokenID int) error {
+	return s.storage.Delete(ctx, userID, tokenID)
+}
+
+// Authenticate resolves a raw API token to its stored record.
+func (s *APITokenService) Authenticate(ctx context.Context, raw string) (bookshelf.APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return bookshelf.APIToken{}, ErrInvalidToken
+	}
+	token, err := s.storage.GetByHash(ctx, hashToken(raw))
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return bookshelf.APIToken{}, ErrInvalidToken
+	}
+	if err != nil {
+		return bookshelf.APIToken{}, err
+	}
+	if token.ExpiresAt != nil && token.ExpiresAt.Before(time.Now()) {
+		return bookshelf.APIToken{}, ErrInvalidToken
+	}
+	if err := s.storage.Touch(ctx, token.ID); err != nil {
+		return bookshelf.APIToken{}, err
+	}
+	return token, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range bookshelf.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
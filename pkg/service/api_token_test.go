@@ -0,0 +1,168 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiTokenStorageStub struct {
+	tokens  map[int]bookshelf.APIToken
+	touched map[int]bool
+}
+
+func newAPITokenStorageStub() *apiTokenStorageStub {
+	return &apiTokenStorageStub{tokens: map[int]bookshelf.APIToken{}, touched: map[int]bool{}}
+}
+
+func (s *apiTokenStorageStub) Create(ctx context.Context, token bookshelf.APIToken) (int, error) {
+	token.ID = len(s.tokens) + 1
+	s.tokens[token.ID] = token
+	return token.ID, nil
+}
+
+func (s *apiTokenStorageStub) GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error) {
+	var tokens []bookshelf.APIToken
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *apiTokenStorageStub) GetByHash(ctx context.Context, tokenHash string) (bookshelf.APIToken, error) {
+	for _, token := range s.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return bookshelf.APIToken{}, bookshelf.ErrNotFound
+}
+
+func (s *apiTokenStorageStub) Touch(ctx context.Context, tokenID int) error {
+	s.touched[tokenID] = true
+	return nil
+}
+
+func (s *apiTokenStorageStub) Delete(ctx context.Context, userID, tokenID int) error {
+	token, ok := s.tokens[tokenID]
+	if !ok || token.UserID != userID {
+		return bookshelf.ErrNotFound
+	}
+	delete(s.tokens, tokenID)
+	return nil
+}
+
+func TestAPITokenService_Create(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		input      bookshelf.CreateAPITokenInput
+		wantScopes []string
+		wantErr    error
+	}{
+		{
+			name:       "OK",
+			input:      bookshelf.CreateAPITokenInput{Name: "backup", Scopes: []string{bookshelf.ScopeBooksWrite}},
+			wantScopes: []string{bookshelf.ScopeBooksWrite},
+		},
+		{
+			name:       "Default scopes",
+			input:      bookshelf.CreateAPITokenInput{Name: "backup"},
+			wantScopes: []string{bookshelf.ScopeListsRead, bookshelf.ScopeBooksRead},
+		},
+		{
+			name:    "Invalid scope",
+			input:   bookshelf.CreateAPITokenInput{Name: "backup", Scopes: []string{"admin"}},
+			wantErr: ErrInvalidScope,
+		},
+		{
+			name:    "Expired",
+			input:   bookshelf.CreateAPITokenInput{Name: "backup", ExpiresAt: &past},
+			wantErr: bookshelf.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAPITokenStorageStub()
+			s := NewAPITokenService(stub)
+
+			token, raw, err := s.Create(ctx, 1, tt.input)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, stub.tokens)
+				return
+			}
+			assert.True(t, strings.HasPrefix(raw, APITokenPrefix))
+			assert.True(t, strings.HasPrefix(raw, token.Prefix))
+			assert.Equal(t, tt.wantScopes, token.Scopes)
+
+			// Only the hash of the token is stored.
+			stored := stub.tokens[token.ID]
+			assert.Equal(t, hashToken(raw), stored.TokenHash)
+			assert.NotContains(t, stored.TokenHash, raw[len(token.Prefix):])
+		})
+	}
+}
+
+func TestAPITokenService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	stub := newAPITokenStorageStub()
+	s := NewAPITokenService(stub)
+
+	token, raw, err := s.Create(ctx, 1, bookshelf.CreateAPITokenInput{Name: "script"})
+	require.NoError(t, err)
+	soon := time.Now().Add(time.Hour)
+	expiring, expiringRaw, err := s.Create(ctx, 1, bookshelf.CreateAPITokenInput{Name: "expiring", ExpiresAt: &soon})
+	require.NoError(t, err)
+	expired := stub.tokens[expiring.ID]
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	stub.tokens[expiring.ID] = expired
+	revoked, revokedRaw, err := s.Create(ctx, 1, bookshelf.CreateAPITokenInput{Name: "revoked"})
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, 1, revoked.ID))
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantID  int
+		wantErr error
+	}{
+		{name: "OK", raw: raw, wantID: token.ID},
+		{name: "Not an API token", raw: strings.TrimPrefix(raw, APITokenPrefix), wantErr: ErrInvalidToken},
+		{name: "Unknown", raw: APITokenPrefix + "unknown", wantErr: ErrInvalidToken},
+		{name: "Expired", raw: expiringRaw, wantErr: ErrInvalidToken},
+		{name: "Revoked", raw: revokedRaw, wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Authenticate(ctx, tt.raw)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantID, got.ID)
+		})
+	}
+	// Only the successful authentication is recorded as a use.
+	assert.Equal(t, map[int]bool{token.ID: true}, stub.touched)
+}
+
+func TestAPITokenService_Delete(t *testing.T) {
+	ctx := context.Background()
+	stub := newAPITokenStorageStub()
+	s := NewAPITokenService(stub)
+	token, _, err := s.Create(ctx, 1, bookshelf.CreateAPITokenInput{Name: "script"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.Delete(ctx, 2, token.ID), bookshelf.ErrNotFound)
+	assert.NoError(t, s.Delete(ctx, 1, token.ID))
+	tokens, err := s.GetAll(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+}
@@ -2,76 +2,259 @@ package service
 
 import (
 	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
 	"bookshelf-api/pkg/storage"
-	"crypto/sha1"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
-const (
-	salt          = "fjdnj36rfebhf51u3"
-	signingString = "dsfbj222vdaj411gerd"
-	tokenTTL      = 12 * time.Hour
-)
-
 type tokenClaims struct {
 	jwt.StandardClaims
 	UserID int `json:"user_id"`
 }
 
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrRevokedToken = errors.New("token has been revoked")
+)
+
 type AuthService struct {
-	storage storage.Authorization
+	storage         storage.Authorization
+	passwordCost    int
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+	keys            *keyring
+	// dummyHash is compared against for unknown users, so they take as
+	// long to reject as a wrong password.
+	dummyHash string
 }
 
-func NewAuthService(storage storage.Authorization) *AuthService {
-	return &AuthService{storage: storage}
+func NewAuthService(storage storage.Authorization, cfg config.Auth) (*AuthService, error) {
+	keys, err := newKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	cost := cfg.PasswordCost
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	dummyHash, err := hashPassword("bookshelf", cost)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	return &AuthService{
+		storage:         storage,
+		passwordCost:    cost,
+		tokenTTL:        cfg.TokenTTL,
+		refreshTokenTTL: cfg.RefreshTokenTTL,
+		keys:            keys,
+		dummyHash:       dummyHash,
+	}, nil
 }
 
-func (s *AuthService) CreateUser(user bookshelf.User) (int, error) {
-	user.Password = s.generatePasswordHash(user.Password)
-	return s.storage.CreateUser(user)
+func (s *AuthService) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
+	hash, err := hashPassword(user.Password, s.passwordCost)
+	if err != nil {
+		return 0, err
+	}
+	user.Password = hash
+	return s.storage.CreateUser(ctx, user)
 }
 
-func (s *AuthService) GenerateToken(username, password string) (string, error) {
-	user, err := s.storage.GetUser(username, s.generatePasswordHash(password))
+func (s *AuthService) GenerateToken(ctx context.Context, username, password string) (bookshelf.TokenPair, error) {
+	userID, err := s.Authenticate(ctx, username, password)
 	if err != nil {
-		return "", err
+		return bookshelf.TokenPair{}, err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
-			IssuedAt:  time.Now().Unix(),
-		},
-		user.ID,
-	})
-	return token.SignedString([]byte(signingString))
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+	return s.issueTokens(ctx, userID, familyID)
 }
 
-func (s *AuthService) generatePasswordHash(password string) string {
-	hash := sha1.New()
-	hash.Write([]byte(password))
+// Authenticate checks the password of the user and returns the user ID. It
+// backs the sign-in and clients that send credentials with every request.
+func (s *AuthService) Authenticate(ctx context.Context, username, password string) (int, error) {
+	user, err := s.storage.GetUser(ctx, username)
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		_, _, _ = verifyPassword(s.dummyHash, password, s.passwordCost)
+		return 0, ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
 
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+	ok, rehash, err := verifyPassword(user.Password, password, s.passwordCost)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrInvalidCredentials
+	}
+	if rehash {
+		// A failed upgrade must not block the sign-in, the hash is
+		// upgraded again on the next successful attempt.
+		if hash, err := hashPassword(password, s.passwordCost); err == nil {
+			_ = s.storage.UpdatePasswordHash(ctx, user.ID, hash)
+		}
+	}
+	return user.ID, nil
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
+// RefreshTokens exchanges a refresh token for a new token pair. Every refresh
+// token can be used once, presenting a used one again revokes all tokens
+// rotated from the same sign-in.
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (bookshelf.TokenPair, error) {
+	token, err := s.storage.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return bookshelf.TokenPair{}, ErrInvalidToken
+	}
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+	if token.Revoked || time.Now().After(token.ExpiresAt) {
+		return bookshelf.TokenPair{}, ErrInvalidToken
+	}
+
+	fresh, err := s.storage.UseRefreshToken(ctx, token.ID)
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+	if !fresh {
+		if err := s.storage.RevokeTokenFamily(ctx, token.FamilyID); err != nil {
+			return bookshelf.TokenPair{}, err
 		}
+		return bookshelf.TokenPair{}, ErrRevokedToken
+	}
 
-		return []byte(signingString), nil
-	})
+	return s.issueTokens(ctx, token.UserID, token.FamilyID)
+}
+
+// Logout revokes the access token and, when given, the refresh token family
+// it was issued with.
+func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := s.parseClaims(accessToken)
+	if err != nil {
+		return err
+	}
+	if claims.Id != "" {
+		if err := s.storage.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.storage.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if token.UserID != claims.UserID {
+		return ErrInvalidToken
+	}
+	return s.storage.RevokeTokenFamily(ctx, token.FamilyID)
+}
+
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (int, error) {
+	claims, err := s.parseClaims(accessToken)
 	if err != nil {
 		return 0, err
 	}
 
+	// Tokens issued before jti was introduced cannot be revoked and are
+	// accepted until they expire.
+	if claims.Id != "" {
+		revoked, err := s.storage.IsTokenRevoked(ctx, claims.Id)
+		if err != nil {
+			return 0, err
+		}
+		if revoked {
+			return 0, ErrRevokedToken
+		}
+	}
+
+	return claims.UserID, nil
+}
+
+func (s *AuthService) parseClaims(accessToken string) (*tokenClaims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, s.keys.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, errors.New("token claims are not of type *tokenClaims")
+		return nil, errors.New("token claims are not of type *tokenClaims")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, userID int, familyID string) (bookshelf.TokenPair, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+	now := time.Now()
+	accessToken, err := s.keys.sign(&tokenClaims{
+		jwt.StandardClaims{
+			Id:        jti,
+			ExpiresAt: now.Add(s.tokenTTL).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+		userID,
+	})
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+	err = s.storage.CreateRefreshToken(ctx, bookshelf.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return bookshelf.TokenPair{}, err
+	}
+
+	return bookshelf.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *AuthService) JWKS() bookshelf.JWKS {
+	return s.keys.jwks()
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,276 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type authStorageStub struct {
+	users         map[string]bookshelf.User
+	refreshTokens []bookshelf.RefreshToken
+	revoked       map[string]time.Time
+}
+
+func (s *authStorageStub) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
+	user.ID = len(s.users) + 1
+	s.users[user.Username] = user
+	return user.ID, nil
+}
+
+func (s *authStorageStub) GetUser(ctx context.Context, username string) (bookshelf.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return bookshelf.User{}, bookshelf.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *authStorageStub) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	for name, user := range s.users {
+		if user.ID == userID {
+			user.Password = hash
+			s.users[name] = user
+		}
+	}
+	return nil
+}
+
+func (s *authStorageStub) CreateRefreshToken(ctx context.Context, token bookshelf.RefreshToken) error {
+	token.ID = len(s.refreshTokens) + 1
+	s.refreshTokens = append(s.refreshTokens, token)
+	return nil
+}
+
+func (s *authStorageStub) GetRefreshToken(ctx context.Context, tokenHash string) (bookshelf.RefreshToken, error) {
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return bookshelf.RefreshToken{}, bookshelf.ErrNotFound
+}
+
+func (s *authStorageStub) UseRefreshToken(ctx context.Context, id int) (bool, error) {
+	token := &s.refreshTokens[id-1]
+	if token.Used || token.Revoked {
+		return false, nil
+	}
+	token.Used = true
+	return true, nil
+}
+
+func (s *authStorageStub) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	for i := range s.refreshTokens {
+		if s.refreshTokens[i].FamilyID == familyID {
+			s.refreshTokens[i].Revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *authStorageStub) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *authStorageStub) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+func TestAuthService_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	stub := &authStorageStub{users: map[string]bookshelf.User{}}
+	s := newTestAuthService(t, stub)
+
+	_, err := s.CreateUser(ctx, bookshelf.User{Username: "test", Password: "qwerty"})
+	require.NoError(t, err)
+
+	hash := stub.users["test"].Password
+	assert.True(t, strings.HasPrefix(hash, bcryptPrefix))
+	assert.NotContains(t, hash, "qwerty")
+
+	_, err = s.CreateUser(ctx, bookshelf.User{Username: "long", Password: strings.Repeat("ä", 37)})
+	assert.ErrorIs(t, err, ErrPasswordTooLong)
+	assert.ErrorIs(t, err, bookshelf.ErrValidation)
+}
+
+func TestAuthService_GenerateToken(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		hash       string
+		password   string
+		wantErr    error
+		wantRehash bool
+	}{
+		{
+			name:     "OK",
+			hash:     mustHash(t, "qwerty", 4),
+			password: "qwerty",
+		},
+		{
+			name:     "Wrong password",
+			hash:     mustHash(t, "qwerty", 4),
+			password: "asdfgh",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:       "Legacy hash",
+			hash:       legacyPasswordHash("qwerty"),
+			password:   "qwerty",
+			wantRehash: true,
+		},
+		{
+			name:     "Legacy hash wrong password",
+			hash:     legacyPasswordHash("qwerty"),
+			password: "asdfgh",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "Too long for bcrypt",
+			hash:     mustHash(t, "qwerty", 4),
+			password: strings.Repeat("q", 73),
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:       "Outdated cost",
+			hash:       mustHash(t, "qwerty", 5),
+			password:   "qwerty",
+			wantRehash: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &authStorageStub{users: map[string]bookshelf.User{
+				"test": {ID: 1, Username: "test", Password: tt.hash},
+			}}
+			s := newTestAuthService(t, stub)
+
+			tokens, err := s.GenerateToken(ctx, "test", tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.hash, stub.users["test"].Password)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, tokens.AccessToken)
+			assert.NotEmpty(t, tokens.RefreshToken)
+
+			hash := stub.users["test"].Password
+			assert.Equal(t, tt.wantRehash, hash != tt.hash)
+			ok, rehash, err := verifyPassword(hash, tt.password, 4)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.False(t, rehash)
+		})
+	}
+}
+
+func TestAuthService_GenerateToken_LongLegacyPassword(t *testing.T) {
+	ctx := context.Background()
+	password := strings.Repeat("q", 73)
+	hash := legacyPasswordHash(password)
+	stub := &authStorageStub{users: map[string]bookshelf.User{
+		"test": {ID: 1, Username: "test", Password: hash},
+	}}
+	s := newTestAuthService(t, stub)
+
+	_, err := s.GenerateToken(ctx, "test", password)
+	require.NoError(t, err)
+	assert.Equal(t, hash, stub.users["test"].Password)
+}
+
+func TestAuthService_GenerateToken_UnknownUser(t *testing.T) {
+	ctx := context.Background()
+	stub := &authStorageStub{users: map[string]bookshelf.User{}}
+	s := newTestAuthService(t, stub)
+
+	_, err := s.GenerateToken(ctx, "test", "qwerty")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Unknown users are checked against a hash of the same cost.
+	cost, err := bcrypt.Cost([]byte(s.dummyHash))
+	require.NoError(t, err)
+	assert.Equal(t, s.passwordCost, cost)
+}
+
+func TestAuthService_RefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	stub := newAuthStorageStub(t)
+	s := newTestAuthService(t, stub)
+
+	first, err := s.GenerateToken(ctx, "test", "qwerty")
+	require.NoError(t, err)
+
+	second, err := s.RefreshTokens(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	userID, err := s.ParseToken(ctx, second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	// Reusing a rotated token revokes the whole family.
+	_, err = s.RefreshTokens(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRevokedToken)
+	_, err = s.RefreshTokens(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = s.RefreshTokens(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	ctx := context.Background()
+	stub := newAuthStorageStub(t)
+	s := newTestAuthService(t, stub)
+
+	tokens, err := s.GenerateToken(ctx, "test", "qwerty")
+	require.NoError(t, err)
+
+	_, err = s.ParseToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Logout(ctx, tokens.AccessToken, tokens.RefreshToken))
+
+	_, err = s.ParseToken(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrRevokedToken)
+	_, err = s.RefreshTokens(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func newAuthStorageStub(t *testing.T) *authStorageStub {
+	return &authStorageStub{
+		users: map[string]bookshelf.User{
+			"test": {ID: 1, Username: "test", Password: mustHash(t, "qwerty", 4)},
+		},
+		revoked: map[string]time.Time{},
+	}
+}
+
+func newTestAuthService(t *testing.T, stub *authStorageStub) *AuthService {
+	t.Helper()
+	s, err := NewAuthService(stub, config.Auth{
+		PasswordCost:    4,
+		TokenTTL:        time.Hour,
+		RefreshTokenTTL: time.Hour,
+		SigningKeys:     []config.SigningKey{{ID: "test", Algorithm: "HS256", Secret: "secret"}},
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func mustHash(t *testing.T, password string, cost int) string {
+	t.Helper()
+	hash, err := hashPassword(password, cost)
+	require.NoError(t, err)
+	return hash
+}
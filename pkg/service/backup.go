@@ -0,0 +1,151 @@
+package service
+
+import (
+	"archive/zip"
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	backupFileName = "backup.json"
+
+	// maxBackupFileSize limits the unpacked backup, so a small archive
+	// cannot expand into an arbitrary amount of memory.
+	maxBackupFileSize = 64 << 20
+)
+
+var ErrInvalidBackup = bookshelf.NewError(bookshelf.ErrValidation, "invalid_backup", "file is not a valid backup")
+
+type BackupService struct {
+	storage storage.Backup
+}
+
+func NewBackupService(storage storage.Backup) *BackupService {
+	return &BackupService{storage: storage}
+}
+
+// Create writes a zip archive with a backup of everything the user has
+// access to.
+func (s *BackupService) Create(ctx context.Context, userID int, w io.Writer) error {
+	backup, err := s.storage.Dump(ctx, userID)
+	if err != nil {
+		return err
+	}
+	backup.Version = bookshelf.BackupVersion
+	backup.CreatedAt = time.Now().UTC()
+
+	archive := zip.NewWriter(w)
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     backupFileName,
+		Method:   zip.Deflate,
+		Modified: backup.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(backup); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// Restore merges a backup archive into the account of the user. Restoring
+// into a new account recreates every list and book. The members of the
+// lists are only restored when the user asks for them and only as viewers,
+// an archive must not hand out edit or owner rights. Members left out are
+// reported as skipped.
+func (s *BackupService) Restore(ctx context.Context, userID int, archive []byte, members bool) (bookshelf.RestoreResult, error) {
+	backup, err := readBackup(archive)
+	if err != nil {
+		return bookshelf.RestoreResult{}, err
+	}
+	if err := validateBackup(&backup); err != nil {
+		return bookshelf.RestoreResult{}, err
+	}
+
+	var skipped []string
+	if members {
+		for i := range backup.Members {
+			backup.Members[i].Role = bookshelf.RoleViewer
+		}
+	} else {
+		for _, member := range backup.Members {
+			skipped = append(skipped, member.Username)
+		}
+		backup.Members = nil
+	}
+
+	result, err := s.storage.Restore(ctx, userID, backup)
+	if err != nil {
+		return bookshelf.RestoreResult{}, err
+	}
+	result.SkippedMembers = append(result.SkippedMembers, skipped...)
+	return result, nil
+}
+
+func readBackup(archive []byte) (bookshelf.Backup, error) {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return bookshelf.Backup{}, ErrInvalidBackup
+	}
+	f, err := r.Open(backupFileName)
+	if err != nil {
+		return bookshelf.Backup{}, ErrInvalidBackup
+	}
+	defer f.Close()
+
+	var backup bookshelf.Backup
+	if err := json.NewDecoder(io.LimitReader(f, maxBackupFileSize)).Decode(&backup); err != nil {
+		return bookshelf.Backup{}, ErrInvalidBackup
+	}
+	if backup.Version != bookshelf.BackupVersion {
+		return bookshelf.Backup{}, bookshelf.NewError(bookshelf.ErrValidation, "unsupported_version", "unsupported backup version "+strconv.Itoa(backup.Version))
+	}
+	return backup, nil
+}
+
+// validateBackup checks that every reference of the backup resolves and
+// normalizes the ISBNs of its books.
+func validateBackup(backup *bookshelf.Backup) error {
+	lists := make(map[int]bool, len(backup.Lists))
+	for _, list := range backup.Lists {
+		if list.Title == "" || lists[list.ID] {
+			return ErrInvalidBackup
+		}
+		lists[list.ID] = true
+	}
+	books := make(map[int]bool, len(backup.Books))
+	for i, book := range backup.Books {
+		if books[book.ID] {
+			return ErrInvalidBackup
+		}
+		books[book.ID] = true
+		if book.ISBN == "" {
+			continue
+		}
+		isbn, err := bookshelf.NormalizeISBN(book.ISBN)
+		if err != nil {
+			return ErrInvalidBackup
+		}
+		backup.Books[i].ISBN = isbn
+	}
+	for _, entry := range backup.Entries {
+		if !lists[entry.ListID] || !books[entry.BookID] {
+			return ErrInvalidBackup
+		}
+	}
+	for _, member := range backup.Members {
+		if !lists[member.ListID] || !bookshelf.HasRole(member.Role, bookshelf.RoleViewer) {
+			return ErrInvalidBackup
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"archive/zip"
+	bookshelf "bookshelf-api"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type backupStorageStub struct {
+	backup   bookshelf.Backup
+	restored *bookshelf.Backup
+}
+
+func (s *backupStorageStub) Dump(ctx context.Context, userID int) (bookshelf.Backup, error) {
+	return s.backup, nil
+}
+
+func (s *backupStorageStub) Restore(ctx context.Context, userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error) {
+	s.restored = &backup
+	return bookshelf.RestoreResult{ListsCreated: len(backup.Lists)}, nil
+}
+
+func TestBackupService_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	stub := &backupStorageStub{backup: bookshelf.Backup{
+		Lists:   []bookshelf.BackupList{{List: bookshelf.List{ID: 1, Title: "Sci-fi"}, Role: bookshelf.RoleOwner}},
+		Books:   []bookshelf.Book{{ID: 2, Title: "Dune", ISBN: "9780441172719"}},
+		Entries: []bookshelf.BackupEntry{{ListID: 1, BookID: 2}},
+		Members: []bookshelf.BackupMember{{ListID: 1, Username: "friend", Role: bookshelf.RoleViewer}},
+	}}
+	s := NewBackupService(stub)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.Create(ctx, 1, buf))
+
+	result, err := s.Restore(ctx, 2, buf.Bytes(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ListsCreated)
+
+	require.NotNil(t, stub.restored)
+	assert.Equal(t, bookshelf.BackupVersion, stub.restored.Version)
+	assert.False(t, stub.restored.CreatedAt.IsZero())
+	stub.restored.Version, stub.restored.CreatedAt = 0, stub.backup.CreatedAt
+	assert.Equal(t, stub.backup, *stub.restored)
+}
+
+func TestBackupService_Restore_Members(t *testing.T) {
+	ctx := context.Background()
+	stub := &backupStorageStub{backup: bookshelf.Backup{
+		Lists: []bookshelf.BackupList{{List: bookshelf.List{ID: 1, Title: "Sci-fi"}, Role: bookshelf.RoleOwner}},
+		Members: []bookshelf.BackupMember{
+			{ListID: 1, Username: "boss", Role: bookshelf.RoleOwner},
+			{ListID: 1, Username: "friend", Role: bookshelf.RoleEditor},
+		},
+	}}
+	s := NewBackupService(stub)
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.Create(ctx, 1, buf))
+
+	tests := []struct {
+		name        string
+		members     bool
+		wantMembers []bookshelf.BackupMember
+		wantSkipped []string
+	}{
+		{
+			name:        "Skipped",
+			wantSkipped: []string{"boss", "friend"},
+		},
+		{
+			name:    "Restored as viewers",
+			members: true,
+			wantMembers: []bookshelf.BackupMember{
+				{ListID: 1, Username: "boss", Role: bookshelf.RoleViewer},
+				{ListID: 1, Username: "friend", Role: bookshelf.RoleViewer},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Restore(ctx, 2, buf.Bytes(), tt.members)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMembers, stub.restored.Members)
+			assert.Equal(t, tt.wantSkipped, result.SkippedMembers)
+		})
+	}
+}
+
+func TestBackupService_Restore_Invalid(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		json string
+	}{
+		{
+			name: "Not JSON",
+			json: "backup",
+		},
+		{
+			name: "Unsupported version",
+			json: `{"version":2}`,
+		},
+		{
+			name: "Unknown list",
+			json: `{"version":1,"books":[{"id":1,"title":"Dune"}],"entries":[{"list_id":1,"book_id":1}]}`,
+		},
+		{
+			name: "Invalid ISBN",
+			json: `{"version":1,"books":[{"id":1,"title":"Dune","isbn":"123"}]}`,
+		},
+		{
+			name: "Invalid role",
+			json: `{"version":1,"lists":[{"id":1,"title":"Sci-fi"}],"members":[{"list_id":1,"username":"friend","role":"admin"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			archive := zip.NewWriter(buf)
+			f, err := archive.Create(backupFileName)
+			require.NoError(t, err)
+			f.Write([]byte(tt.json))
+			require.NoError(t, archive.Close())
+
+			stub := &backupStorageStub{}
+			_, err = NewBackupService(stub).Restore(ctx, 1, buf.Bytes(), false)
+			assert.ErrorIs(t, err, bookshelf.ErrValidation)
+			assert.Nil(t, stub.restored)
+		})
+	}
+
+	_, err := NewBackupService(&backupStorageStub{}).Restore(ctx, 1, []byte("not a zip"), false)
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+}
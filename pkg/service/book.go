@@ -3,41 +3,302 @@ package service
 import (
 	bookshelf "bookshelf-api"
 	"bookshelf-api/pkg/storage"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrLastList      = bookshelf.NewError(bookshelf.ErrConflict, "last_list", "book is not part of any other list")
+	ErrDuplicateISBN = bookshelf.NewError(bookshelf.ErrConflict, "duplicate_isbn", "a book with this ISBN already exists")
 )
 
 type BookService struct {
 	storage     storage.Book
 	listStorage storage.List
+	metadata    MetadataProvider
 }
 
-func NewBookService(storage storage.Book, listStorage storage.List) *BookService {
+// NewBookService creates the book service, metadata may be nil when lookups
+// are disabled.
+func NewBookService(storage storage.Book, listStorage storage.List, metadata MetadataProvider) *BookService {
 	return &BookService{
 		storage:     storage,
 		listStorage: listStorage,
+		metadata:    metadata,
 	}
 }
 
-func (s *BookService) Create(userID, listID int, book bookshelf.Book) (int, error) {
-	_, err := s.listStorage.GetByID(userID, listID)
-	if err != nil {
+// Create adds a new book to the list. A book with an ISBN but without a title
+// is completed from the metadata provider, fields given by the client win.
+func (s *BookService) Create(ctx context.Context, userID, listID int, book bookshelf.Book) (int, error) {
+	if err := s.requireListRole(ctx, userID, listID, bookshelf.RoleEditor); err != nil {
 		return 0, err
 	}
+	if book.ISBN != "" {
+		isbn, err := bookshelf.NormalizeISBN(book.ISBN)
+		if err != nil {
+			return 0, err
+		}
+		book.ISBN = isbn
+	}
+	if book.ISBN != "" && book.Title == "" {
+		meta, err := s.lookup(ctx, book.ISBN)
+		if err != nil {
+			return 0, err
+		}
+		book = enrich(book, meta)
+	}
+	book.UserID = userID
+
+	id, err := s.storage.Create(ctx, listID, book)
+	if errors.Is(err, bookshelf.ErrConflict) {
+		return 0, ErrDuplicateISBN
+	}
+	return id, err
+}
+
+// GetAll returns a page of books of the list and the cursor of the next page,
+// which is empty on the last page.
+func (s *BookService) GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = bookshelf.DefaultPageLimit
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	books, err := s.storage.GetAll(ctx, userID, listID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(books) <= limit {
+		return books, "", nil
+	}
+	books = books[:limit]
+	return books, bookCursor(filter.Sort, books[limit-1]).Encode(), nil
+}
+
+func (s *BookService) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	return s.storage.GetByID(ctx, userID, bookID)
+}
+
+// GetByISBN looks up a book of the user by an ISBN in any accepted format.
+func (s *BookService) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
+	isbn, err := bookshelf.NormalizeISBN(isbn)
+	if err != nil {
+		return bookshelf.Book{}, err
+	}
+	return s.storage.GetByISBN(ctx, userID, isbn)
+}
+
+// Update changes the given fields of the book. An empty ISBN removes it.
+func (s *BookService) Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error {
+	if err := s.requireRole(ctx, userID, bookID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	if input.ISBN != nil && *input.ISBN != "" {
+		isbn, err := bookshelf.NormalizeISBN(*input.ISBN)
+		if err != nil {
+			return err
+		}
+		input.ISBN = &isbn
+	}
+
+	err := s.storage.Update(ctx, userID, bookID, input)
+	if errors.Is(err, bookshelf.ErrConflict) {
+		return ErrDuplicateISBN
+	}
+	return err
+}
+
+// Delete removes the book from the lists the user can edit. Lists the user
+// cannot edit keep the book, so deleting only needs read access to the book
+// and edit access to one of its lists.
+func (s *BookService) Delete(ctx context.Context, userID, bookID int) error {
+	if err := s.requireRole(ctx, userID, bookID, bookshelf.RoleViewer); err != nil {
+		return err
+	}
+	err := s.storage.Delete(ctx, userID, bookID)
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return bookshelf.ErrForbidden
+	}
+	return err
+}
+
+// Refresh replaces the details of the book with the ones of the metadata
+// provider. Fields unknown to the provider are kept.
+func (s *BookService) Refresh(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	if err := s.requireRole(ctx, userID, bookID, bookshelf.RoleEditor); err != nil {
+		return bookshelf.Book{}, err
+	}
+	book, err := s.storage.GetByID(ctx, userID, bookID)
+	if err != nil {
+		return bookshelf.Book{}, err
+	}
+	if book.ISBN == "" {
+		return bookshelf.Book{}, ErrMissingISBN
+	}
+	meta, err := s.lookup(ctx, book.ISBN)
+	if err != nil {
+		return bookshelf.Book{}, err
+	}
+
+	book = enrich(meta, book)
+	book.ID = bookID
+	err = s.storage.Update(ctx, userID, bookID, bookshelf.UpdateBookInput{
+		Title:           &book.Title,
+		Author:          &book.Author,
+		Publisher:       &book.Publisher,
+		PublicationYear: &book.PublicationYear,
+		PageCount:       &book.PageCount,
+	})
+	if err != nil {
+		return bookshelf.Book{}, err
+	}
+	return book, nil
+}
+
+// Attach adds a book the user can edit to a list the user can edit. Reading
+// the book is not enough, every list containing a book shares its details,
+// so a viewer must not spread a book to a list where it could be changed.
+func (s *BookService) Attach(ctx context.Context, userID, listID, bookID int) error {
+	if err := s.requireRole(ctx, userID, bookID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	if err := s.requireListRole(ctx, userID, listID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	return s.storage.Attach(ctx, listID, bookID)
+}
+
+// Detach removes a book from a list. A book cannot be detached from its last
+// list, Delete has to be used instead.
+func (s *BookService) Detach(ctx context.Context, userID, listID, bookID int) error {
+	if err := s.requireListRole(ctx, userID, listID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	err := s.storage.Detach(ctx, listID, bookID)
+	if errors.Is(err, bookshelf.ErrConflict) {
+		return ErrLastList
+	}
+	return err
+}
+
+func (s *BookService) Move(ctx context.Context, userID, fromListID, toListID, bookID int) error {
+	if err := s.requireListRole(ctx, userID, fromListID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	if err := s.requireListRole(ctx, userID, toListID, bookshelf.RoleEditor); err != nil {
+		return err
+	}
+	return s.storage.Move(ctx, fromListID, toListID, bookID)
+}
+
+// Suggest returns books of the user resembling query, best matches first.
+func (s *BookService) Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, bookshelf.NewError(bookshelf.ErrValidation, "empty_query", "query is empty")
+	}
+	if limit < 0 || limit > bookshelf.MaxPageLimit {
+		return nil, bookshelf.NewError(bookshelf.ErrValidation, "invalid_limit", "limit is out of range")
+	}
+	if limit == 0 {
+		limit = bookshelf.DefaultSuggestLimit
+	}
+	return s.storage.Suggest(ctx, userID, query, limit)
+}
+
+// GetDuplicates returns groups of books of the user that are likely copies
+// of one another.
+func (s *BookService) GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error) {
+	return s.storage.GetDuplicates(ctx, userID)
+}
+
+// Merge keeps the canonical book and replaces the other books with it in the
+// lists the user can edit. The user has to be able to edit all of the books,
+// lists of other users keep their entries.
+func (s *BookService) Merge(ctx context.Context, userID int, input bookshelf.MergeBooksInput) (bookshelf.MergeResult, error) {
+	ids := make([]int, 0, len(input.BookIDs))
+	seen := make(map[int]bool, len(input.BookIDs))
+	for _, id := range input.BookIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return bookshelf.MergeResult{}, bookshelf.NewError(bookshelf.ErrValidation, "nothing_to_merge", "at least two different books are required")
+	}
+	canonicalID := input.CanonicalID
+	if canonicalID == 0 {
+		canonicalID = slices.Min(ids)
+	}
+	if !seen[canonicalID] {
+		return bookshelf.MergeResult{}, bookshelf.NewError(bookshelf.ErrValidation, "invalid_canonical", "canonical book is not one of the merged books")
+	}
 
-	return s.storage.Create(listID, book)
+	for _, id := range ids {
+		if err := s.requireRole(ctx, userID, id, bookshelf.RoleEditor); err != nil {
+			return bookshelf.MergeResult{}, err
+		}
+	}
+
+	merged := slices.DeleteFunc(ids, func(id int) bool { return id == canonicalID })
+	listIDs, err := s.storage.Merge(ctx, userID, canonicalID, merged)
+	if err != nil {
+		return bookshelf.MergeResult{}, err
+	}
+	if listIDs == nil {
+		listIDs = []int{}
+	}
+	return bookshelf.MergeResult{CanonicalID: canonicalID, MergedIDs: merged, ListIDs: listIDs}, nil
 }
 
-func (s *BookService) GetAll(userID, listID int) ([]bookshelf.Book, error) {
-	return s.storage.GetAll(userID, listID)
+func (s *BookService) lookup(ctx context.Context, isbn string) (bookshelf.Book, error) {
+	if s.metadata == nil {
+		return bookshelf.Book{}, ErrNoMetadata
+	}
+	return s.metadata.Lookup(ctx, isbn)
 }
 
-func (s *BookService) GetByID(userID, bookID int) (bookshelf.Book, error) {
-	return s.storage.GetByID(userID, bookID)
+func (s *BookService) requireListRole(ctx context.Context, userID, listID int, required string) error {
+	role, err := s.listStorage.GetRole(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	if !bookshelf.HasRole(role, required) {
+		return bookshelf.ErrForbidden
+	}
+	return nil
 }
 
-func (s *BookService) Update(userID, bookID int, input bookshelf.UpdateBookInput) error {
-	return s.storage.Update(userID, bookID, input)
+func (s *BookService) requireRole(ctx context.Context, userID, bookID int, required string) error {
+	role, err := s.storage.GetRole(ctx, userID, bookID)
+	if err != nil {
+		return err
+	}
+	if !bookshelf.HasRole(role, required) {
+		return bookshelf.ErrForbidden
+	}
+	return nil
 }
 
-func (s *BookService) Delete(userID, bookID int) error {
-	return s.storage.Delete(userID, bookID)
+func bookCursor(sort string, book bookshelf.Book) bookshelf.Cursor {
+	cursor := bookshelf.Cursor{Sort: sort, ID: book.ID}
+	switch field, _ := bookshelf.SortField(sort); field {
+	case "title":
+		cursor.Value = book.Title
+	case "author":
+		cursor.Value = book.Author
+	case "publication_year":
+		cursor.Value = book.PublicationYear
+	case "page_count":
+		cursor.Value = book.PageCount
+	}
+	return cursor
 }
@@ -0,0 +1,303 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type bookStorageStub struct {
+	storage.Book
+	books    []bookshelf.Book
+	filter   bookshelf.BookFilter
+	roles    map[int]string
+	merged   []int
+	attached []int
+}
+
+type listStorageStub struct {
+	storage.List
+	role string
+}
+
+func (s *listStorageStub) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	return s.role, nil
+}
+
+func (s *bookStorageStub) Create(ctx context.Context, listID int, book bookshelf.Book) (int, error) {
+	for _, b := range s.books {
+		if b.UserID == book.UserID && b.ISBN != "" && b.ISBN == book.ISBN {
+			return 0, bookshelf.ErrConflict
+		}
+	}
+	book.ID = len(s.books) + 1
+	s.books = append(s.books, book)
+	return book.ID, nil
+}
+
+func (s *bookStorageStub) GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, error) {
+	s.filter = filter
+	if filter.Limit > 0 && filter.Limit < len(s.books) {
+		return s.books[:filter.Limit], nil
+	}
+	return s.books, nil
+}
+
+func (s *bookStorageStub) GetRole(ctx context.Context, userID, bookID int) (string, error) {
+	role, ok := s.roles[bookID]
+	if !ok {
+		return "", bookshelf.ErrNotFound
+	}
+	return role, nil
+}
+
+func (s *bookStorageStub) Merge(ctx context.Context, userID, canonicalID int, bookIDs []int) ([]int, error) {
+	s.merged = append([]int{canonicalID}, bookIDs...)
+	return []int{7}, nil
+}
+
+func TestBookService_Create(t *testing.T) {
+	ctx := context.Background()
+	stub := &bookStorageStub{}
+	s := NewBookService(stub, &listStorageStub{role: bookshelf.RoleEditor}, nil)
+
+	id, err := s.Create(ctx, 1, 1, bookshelf.Book{Title: "Dune", ISBN: "0-441-17271-7"})
+	require.NoError(t, err)
+	assert.Equal(t, bookshelf.Book{ID: id, UserID: 1, Title: "Dune", ISBN: "9780441172719"}, stub.books[0])
+
+	_, err = s.Create(ctx, 1, 1, bookshelf.Book{Title: "Dune", ISBN: "978-0-441-17271-9"})
+	assert.ErrorIs(t, err, ErrDuplicateISBN)
+
+	_, err = s.Create(ctx, 1, 1, bookshelf.Book{Title: "Dune", ISBN: "0-441-17271-8"})
+	assert.ErrorIs(t, err, bookshelf.ErrInvalidISBN)
+
+	_, err = s.Create(ctx, 2, 1, bookshelf.Book{Title: "Dune", ISBN: "9780441172719"})
+	assert.NoError(t, err)
+}
+
+func (s *bookStorageStub) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	return s.books[bookID-1], nil
+}
+
+func (s *bookStorageStub) Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error {
+	book := &s.books[bookID-1]
+	book.Title, book.Author, book.Publisher = *input.Title, *input.Author, *input.Publisher
+	book.PublicationYear, book.PageCount = *input.PublicationYear, *input.PageCount
+	return nil
+}
+
+func TestBookService_Create_Metadata(t *testing.T) {
+	ctx := context.Background()
+	stub := &bookStorageStub{}
+	s := NewBookService(stub, &listStorageStub{role: bookshelf.RoleEditor}, NewStubMetadataProvider(stubBooks))
+
+	_, err := s.Create(ctx, 1, 1, bookshelf.Book{ISBN: "0-441-17271-7", PageCount: 600})
+	require.NoError(t, err)
+	assert.Equal(t, bookshelf.Book{
+		ID:              1,
+		UserID:          1,
+		Title:           "Dune",
+		Author:          "Frank Herbert",
+		Publisher:       "Ace Books",
+		PublicationYear: 1990,
+		PageCount:       600,
+		ISBN:            "9780441172719",
+	}, stub.books[0])
+
+	_, err = s.Create(ctx, 1, 1, bookshelf.Book{ISBN: "9780306406157"})
+	assert.ErrorIs(t, err, ErrMetadataNotFound)
+
+	s = NewBookService(stub, &listStorageStub{role: bookshelf.RoleEditor}, nil)
+	_, err = s.Create(ctx, 1, 1, bookshelf.Book{ISBN: "9780306406157"})
+	assert.ErrorIs(t, err, ErrNoMetadata)
+}
+
+func TestBookService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	stub := &bookStorageStub{
+		books: []bookshelf.Book{
+			{ID: 1, Title: "dune", Author: "Herbert", Publisher: "Old", PageCount: 400, ISBN: "9780441172719"},
+			{ID: 2, Title: "Untitled"},
+		},
+		roles: map[int]string{1: bookshelf.RoleEditor, 2: bookshelf.RoleEditor},
+	}
+	s := NewBookService(stub, nil, NewStubMetadataProvider(map[string]bookshelf.Book{
+		"9780441172719": {Title: "Dune", Author: "Frank Herbert", PublicationYear: 1990},
+	}))
+
+	book, err := s.Refresh(ctx, 1, 1)
+	require.NoError(t, err)
+	want := bookshelf.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Publisher: "Old", PublicationYear: 1990, PageCount: 400, ISBN: "9780441172719"}
+	assert.Equal(t, want, book)
+	assert.Equal(t, want, stub.books[0])
+
+	_, err = s.Refresh(ctx, 1, 2)
+	assert.ErrorIs(t, err, ErrMissingISBN)
+}
+
+func TestBookService_GetAll(t *testing.T) {
+	ctx := context.Background()
+	stub := &bookStorageStub{books: []bookshelf.Book{
+		{ID: 1, Title: "a", PageCount: 300},
+		{ID: 2, Title: "b", PageCount: 200},
+		{ID: 3, Title: "c", PageCount: 100},
+	}}
+	s := NewBookService(stub, nil, nil)
+
+	books, next, err := s.GetAll(ctx, 1, 1, bookshelf.BookFilter{Sort: "-page_count", Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+	assert.Equal(t, 3, stub.filter.Limit)
+
+	cursor, err := bookshelf.ParseCursor(next)
+	require.NoError(t, err)
+	assert.Equal(t, bookshelf.Cursor{Sort: "-page_count", Value: 200, ID: 2}, *cursor)
+
+	books, next, err = s.GetAll(ctx, 1, 1, bookshelf.BookFilter{})
+	require.NoError(t, err)
+	assert.Len(t, books, 3)
+	assert.Empty(t, next)
+	assert.Equal(t, bookshelf.DefaultPageLimit+1, stub.filter.Limit)
+}
+
+func TestBookService_GetAll_Invalid(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		filter bookshelf.BookFilter
+	}{
+		{
+			name:   "Unknown sort field",
+			filter: bookshelf.BookFilter{Sort: "id; DROP TABLE books"},
+		},
+		{
+			name:   "Limit too large",
+			filter: bookshelf.BookFilter{Limit: bookshelf.MaxPageLimit + 1},
+		},
+		{
+			name:   "Cursor of another sort",
+			filter: bookshelf.BookFilter{Sort: "title", After: &bookshelf.Cursor{Sort: "author", Value: "a", ID: 1}},
+		},
+		{
+			name:   "Empty year range",
+			filter: bookshelf.BookFilter{YearFrom: intPointer(2010), YearTo: intPointer(2000)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBookService(&bookStorageStub{}, nil, nil)
+			_, _, err := s.GetAll(ctx, 1, 1, tt.filter)
+			assert.ErrorIs(t, err, bookshelf.ErrValidation)
+		})
+	}
+}
+
+func TestBookService_Merge(t *testing.T) {
+	ctx := context.Background()
+	roles := map[int]string{
+		1: bookshelf.RoleOwner,
+		2: bookshelf.RoleEditor,
+		3: bookshelf.RoleEditor,
+		4: bookshelf.RoleViewer,
+	}
+	tests := []struct {
+		name    string
+		input   bookshelf.MergeBooksInput
+		want    bookshelf.MergeResult
+		wantErr error
+	}{
+		{
+			name:  "Oldest book is canonical",
+			input: bookshelf.MergeBooksInput{BookIDs: []int{3, 1, 2}},
+			want:  bookshelf.MergeResult{CanonicalID: 1, MergedIDs: []int{3, 2}, ListIDs: []int{7}},
+		},
+		{
+			name:  "Explicit canonical",
+			input: bookshelf.MergeBooksInput{CanonicalID: 2, BookIDs: []int{1, 2, 2}},
+			want:  bookshelf.MergeResult{CanonicalID: 2, MergedIDs: []int{1}, ListIDs: []int{7}},
+		},
+		{
+			name:    "Single book",
+			input:   bookshelf.MergeBooksInput{BookIDs: []int{1, 1}},
+			wantErr: bookshelf.ErrValidation,
+		},
+		{
+			name:    "Canonical outside of the merged books",
+			input:   bookshelf.MergeBooksInput{CanonicalID: 3, BookIDs: []int{1, 2}},
+			wantErr: bookshelf.ErrValidation,
+		},
+		{
+			name:    "Read only book",
+			input:   bookshelf.MergeBooksInput{BookIDs: []int{1, 4}},
+			wantErr: bookshelf.ErrForbidden,
+		},
+		{
+			name:    "Unknown book",
+			input:   bookshelf.MergeBooksInput{BookIDs: []int{1, 5}},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &bookStorageStub{roles: roles}
+			s := NewBookService(stub, nil, nil)
+
+			got, err := s.Merge(ctx, 1, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, stub.merged)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, append([]int{got.CanonicalID}, got.MergedIDs...), stub.merged)
+		})
+	}
+}
+
+func (s *bookStorageStub) Attach(ctx context.Context, listID, bookID int) error {
+	s.attached = append(s.attached, bookID)
+	return nil
+}
+
+func TestBookService_Attach(t *testing.T) {
+	roles := map[int]string{
+		1: bookshelf.RoleOwner,
+		2: bookshelf.RoleEditor,
+		3: bookshelf.RoleViewer,
+	}
+	tests := []struct {
+		name     string
+		bookID   int
+		listRole string
+		wantErr  error
+	}{
+		{name: "Own book", bookID: 1, listRole: bookshelf.RoleOwner},
+		{name: "Editable book", bookID: 2, listRole: bookshelf.RoleEditor},
+		{name: "Read only book", bookID: 3, listRole: bookshelf.RoleOwner, wantErr: bookshelf.ErrForbidden},
+		{name: "Read only list", bookID: 1, listRole: bookshelf.RoleViewer, wantErr: bookshelf.ErrForbidden},
+		{name: "Unknown book", bookID: 4, listRole: bookshelf.RoleOwner, wantErr: bookshelf.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &bookStorageStub{roles: roles}
+			s := NewBookService(stub, &listStorageStub{role: tt.listRole}, nil)
+
+			err := s.Attach(context.Background(), 1, 1, tt.bookID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, stub.attached)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []int{tt.bookID}, stub.attached)
+		})
+	}
+}
+
+func intPointer(n int) *int {
+	return &n
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const DefaultCitationFormat = "bibtex"
+
+var ErrInvalidCitationFormat = bookshelf.NewError(bookshelf.ErrValidation, "invalid_format", "format must be one of: bibtex csl-json ris")
+
+// CitationFormat is a format citations can be written in.
+type CitationFormat struct {
+	Name        string
+	ContentType string
+	Extension   string
+	write       func(w io.Writer, citations []citation) error
+}
+
+var citationFormats = map[string]CitationFormat{
+	"bibtex":   {"bibtex", "application/x-bibtex; charset=utf-8", "bib", writeBibTeX},
+	"ris":      {"ris", "application/x-research-info-systems; charset=utf-8", "ris", writeRIS},
+	"csl-json": {"csl-json", "application/vnd.citationstyles.csl+json", "json", writeCSLJSON},
+}
+
+// LookupCitationFormat returns the format called name, an empty name selects
+// DefaultCitationFormat.
+func LookupCitationFormat(name string) (CitationFormat, error) {
+	if name == "" {
+		name = DefaultCitationFormat
+	}
+	format, ok := citationFormats[name]
+	if !ok {
+		return CitationFormat{}, ErrInvalidCitationFormat
+	}
+	return format, nil
+}
+
+// CiteService writes citations of books in the formats of reference
+// managers.
+type CiteService struct {
+	books  Book
+	export Export
+}
+
+func NewCiteService(books Book, export Export) *CiteService {
+	return &CiteService{books: books, export: export}
+}
+
+// CiteBook writes the citation of a book of the user in the named format.
+func (s *CiteService) CiteBook(ctx context.Context, userID, bookID int, format string, w io.Writer) error {
+	f, err := LookupCitationFormat(format)
+	if err != nil {
+		return err
+	}
+	book, err := s.books.GetByID(ctx, userID, bookID)
+	if err != nil {
+		return err
+	}
+	return f.write(w, newCitations([]bookshelf.Book{book}))
+}
+
+// CiteList writes the citations of the books of a list in the named format.
+func (s *CiteService) CiteList(ctx context.Context, userID, listID int, format string, w io.Writer) error {
+	f, err := LookupCitationFormat(format)
+	if err != nil {
+		return err
+	}
+	books := []bookshelf.Book{}
+	err = s.export.ExportList(ctx, userID, listID, func(row bookshelf.ExportRow) error {
+		if row.Book != nil {
+			books = append(books, *row.Book)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.write(w, newCitations(books))
+}
+
+// citation is a book together with its citation key and parsed authors.
+type citation struct {
+	Key     string
+	Book    bookshelf.Book
+	Authors []citationName
+}
+
+// citationName is an author split into family and given names. Names that
+// cannot be split, like organisations, only have a family name.
+type citationName struct {
+	Family string
+	Given  string
+}
+
+func (n citationName) String() string {
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Family + ", " + n.Given
+}
+
+// newCitations builds the citations of books. Keys are made of the family
+// name of the first author, the year and the first word of the title, like
+// herbert1965dune. Books sharing a key get a letter suffix derived from their
+// ID, so a key does not depend on the order of the books.
+func newCitations(books []bookshelf.Book) []citation {
+	citations := make([]citation, 0, len(books))
+	count := make(map[string]int, len(books))
+	for _, book := range books {
+		c := citation{Book: book, Authors: parseAuthors(book.Author)}
+
+		author := "anon"
+		if len(c.Authors) > 0 {
+			if family := citationKeyPart(c.Authors[0].Family); family != "" {
+				author = family
+			}
+		}
+		year := "nd"
+		if book.PublicationYear > 0 {
+			year = strconv.Itoa(book.PublicationYear)
+		}
+		c.Key = author + year + citationTitleWord(book.Title)
+		count[c.Key]++
+		citations = append(citations, c)
+	}
+	for i, c := range citations {
+		if count[c.Key] > 1 {
+			citations[i].Key += citationSuffix(c.Book.ID)
+		}
+	}
+	return citations
+}
+
+// citationSuffix returns a, b, ..., z, aa, ab, ... for n = 1, 2, ...
+func citationSuffix(n int) string {
+	suffix := ""
+	for ; n > 0; n = (n - 1) / 26 {
+		suffix = string(rune('a'+(n-1)%26)) + suffix
+	}
+	return suffix
+}
+
+var citationStopWords = map[string]bool{"a": true, "an": true, "the": true}
+
+func citationTitleWord(title string) string {
+	for _, word := range strings.Fields(title) {
+		word = citationKeyPart(word)
+		if word != "" && !citationStopWords[word] {
+			return word
+		}
+	}
+	return ""
+}
+
+// citationKeyPart folds s to lowercase ASCII letters and digits. Accents are
+// dropped, other characters removed.
+func citationKeyPart(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseAuthors splits the author field into names. Authors are separated by
+// semicolons, "and" or "&", each given either as "Family, Given" or as
+// "Given Family".
+func parseAuthors(author string) []citationName {
+	author = strings.NewReplacer(" and ", ";", " & ", ";").Replace(author)
+	var names []citationName
+	for _, name := range strings.Split(author, ";") {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" {
+			continue
+		}
+		if family, given, ok := strings.Cut(name, ","); ok {
+			names = append(names, citationName{Family: strings.TrimSpace(family), Given: strings.TrimSpace(given)})
+			continue
+		}
+		if i := strings.LastIndexByte(name, ' '); i > 0 {
+			names = append(names, citationName{Family: name[i+1:], Given: name[:i]})
+			continue
+		}
+		names = append(names, citationName{Family: name})
+	}
+	return names
+}
+
+var bibTeXEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func bibTeXEscape(s string) string {
+	return bibTeXEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+func writeBibTeX(w io.Writer, citations []citation) error {
+	buf := &bytes.Buffer{}
+	for i, c := range citations {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "@book{%s,\n", c.Key)
+		if len(c.Authors) > 0 {
+			names := make([]string, 0, len(c.Authors))
+			for _, name := range c.Authors {
+				names = append(names, bibTeXEscape(name.String()))
+			}
+			fmt.Fprintf(buf, "  author = {%s},\n", strings.Join(names, " and "))
+		}
+		// The extra braces keep BibTeX styles from changing the case.
+		fmt.Fprintf(buf, "  title = {{%s}},\n", bibTeXEscape(c.Book.Title))
+		if c.Book.Publisher != "" {
+			fmt.Fprintf(buf, "  publisher = {%s},\n", bibTeXEscape(c.Book.Publisher))
+		}
+		if c.Book.PublicationYear > 0 {
+			fmt.Fprintf(buf, "  year = {%d},\n", c.Book.PublicationYear)
+		}
+		if c.Book.ISBN != "" {
+			fmt.Fprintf(buf, "  isbn = {%s},\n", c.Book.ISBN)
+		}
+		buf.WriteString("}\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// risValue keeps a value on its tag line, RIS has no way to escape line
+// breaks.
+func risValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func writeRIS(w io.Writer, citations []citation) error {
+	buf := &bytes.Buffer{}
+	line := func(tag, value string) {
+		fmt.Fprintf(buf, "%s  - %s\r\n", tag, value)
+	}
+	for _, c := range citations {
+		line("TY", "BOOK")
+		line("ID", c.Key)
+		for _, name := range c.Authors {
+			line("AU", risValue(name.String()))
+		}
+		line("TI", risValue(c.Book.Title))
+		if c.Book.Publisher != "" {
+			line("PB", risValue(c.Book.Publisher))
+		}
+		if c.Book.PublicationYear > 0 {
+			line("PY", strconv.Itoa(c.Book.PublicationYear))
+		}
+		if c.Book.ISBN != "" {
+			line("SN", c.Book.ISBN)
+		}
+		line("ER", "")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type cslItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Author    []cslName `json:"author,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	Issued    *cslDate  `json:"issued,omitempty"`
+	ISBN      string    `json:"ISBN,omitempty"`
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func writeCSLJSON(w io.Writer, citations []citation) error {
+	items := make([]cslItem, 0, len(citations))
+	for _, c := range citations {
+		item := cslItem{
+			ID:        c.Key,
+			Type:      "book",
+			Title:     c.Book.Title,
+			Publisher: c.Book.Publisher,
+			ISBN:      c.Book.ISBN,
+		}
+		for _, name := range c.Authors {
+			if name.Given == "" {
+				item.Author = append(item.Author, cslName{Literal: name.Family})
+			} else {
+				item.Author = append(item.Author, cslName{Family: name.Family, Given: name.Given})
+			}
+		}
+		if c.Book.PublicationYear > 0 {
+			item.Issued = &cslDate{DateParts: [][]int{{c.Book.PublicationYear}}}
+		}
+		items = append(items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
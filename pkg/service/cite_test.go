@@ -0,0 +1,175 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// citeBookServiceStub serves the books by ID.
+type citeBookServiceStub struct {
+	Book
+	books map[int]bookshelf.Book
+}
+
+func (s *citeBookServiceStub) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	book, ok := s.books[bookID]
+	if !ok {
+		return bookshelf.Book{}, bookshelf.ErrNotFound
+	}
+	return book, nil
+}
+
+// citeExportServiceStub exports rows as the books of list 2.
+type citeExportServiceStub struct {
+	rows []bookshelf.ExportRow
+}
+
+func (s *citeExportServiceStub) Export(ctx context.Context, userID int, fn func(bookshelf.ExportRow) error) error {
+	return s.ExportList(ctx, userID, 2, fn)
+}
+
+func (s *citeExportServiceStub) ExportList(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error {
+	if listID != 2 {
+		return bookshelf.ErrNotFound
+	}
+	for _, row := range s.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestNewCitations(t *testing.T) {
+	citations := newCitations([]bookshelf.Book{
+		{ID: 1, Title: "Dune", Author: "Frank Herbert", PublicationYear: 1965},
+		{ID: 2, Title: "Dune Messiah", Author: "Herbert, Frank", PublicationYear: 1965},
+		{ID: 30, Title: "Dune", Author: "Frank Herbert", PublicationYear: 1965},
+		{ID: 4, Title: "The Ægis of Čapek", Author: "Karel Čapek"},
+		{ID: 5, Title: "!!!", Author: ""},
+		{ID: 6, Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman; Plato"},
+		{ID: 7, Title: "Emma", Author: "Jane Austen", PublicationYear: 1815},
+	})
+
+	keys := make([]string, 0, len(citations))
+	for _, c := range citations {
+		keys = append(keys, c.Key)
+	}
+	assert.Equal(t, []string{"herbert1965dunea", "herbert1965duneb", "herbert1965dunead", "capekndgis", "anonnd", "pratchettndgood", "austen1815emma"}, keys)
+	assert.Equal(t, []citationName{{Family: "Pratchett", Given: "Terry"}, {Family: "Gaiman", Given: "Neil"}, {Family: "Plato"}}, citations[5].Authors)
+
+	// Keys only depend on the books, not on their order.
+	reversed := newCitations([]bookshelf.Book{citations[2].Book, citations[1].Book, citations[0].Book})
+	assert.Equal(t, "herbert1965dunead", reversed[0].Key)
+	assert.Equal(t, "herbert1965dunea", reversed[2].Key)
+}
+
+func TestCiteService_CiteBook(t *testing.T) {
+	books := &citeBookServiceStub{books: map[int]bookshelf.Book{
+		3: {ID: 3, Title: "Cats & {Dogs}", Author: "Jane Doe and John Roe", Publisher: "100% Press", PublicationYear: 2001, ISBN: "9780441172719"},
+	}}
+
+	tests := []struct {
+		name    string
+		bookID  int
+		format  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "BibTeX",
+			bookID: 3,
+			want: "@book{doe2001cats,\n" +
+				"  author = {Doe, Jane and Roe, John},\n" +
+				"  title = {{Cats \\& \\{Dogs\\}}},\n" +
+				"  publisher = {100\\% Press},\n" +
+				"  year = {2001},\n" +
+				"  isbn = {9780441172719},\n" +
+				"}\n",
+		},
+		{
+			name:   "RIS",
+			bookID: 3,
+			format: "ris",
+			want: "TY  - BOOK\r\n" +
+				"ID  - doe2001cats\r\n" +
+				"AU  - Doe, Jane\r\n" +
+				"AU  - Roe, John\r\n" +
+				"TI  - Cats & {Dogs}\r\n" +
+				"PB  - 100% Press\r\n" +
+				"PY  - 2001\r\n" +
+				"SN  - 9780441172719\r\n" +
+				"ER  - \r\n",
+		},
+		{
+			name:   "CSL-JSON",
+			bookID: 3,
+			format: "csl-json",
+			want: `[
+  {
+    "id": "doe2001cats",
+    "type": "book",
+    "title": "Cats & {Dogs}",
+    "author": [
+      {
+        "family": "Doe",
+        "given": "Jane"
+      },
+      {
+        "family": "Roe",
+        "given": "John"
+      }
+    ],
+    "publisher": "100% Press",
+    "issued": {
+      "date-parts": [
+        [
+          2001
+        ]
+      ]
+    },
+    "ISBN": "9780441172719"
+  }
+]
+`,
+		},
+		{name: "Unknown format", bookID: 3, format: "mla", wantErr: ErrInvalidCitationFormat},
+		{name: "Not found", bookID: 4, wantErr: bookshelf.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := NewCiteService(books, nil).CiteBook(context.Background(), 1, tt.bookID, tt.format, buf)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestCiteService_CiteList(t *testing.T) {
+	list := bookshelf.List{ID: 2, Title: "Papers"}
+	export := &citeExportServiceStub{rows: []bookshelf.ExportRow{
+		{List: list, Book: &bookshelf.Book{ID: 1, Title: "Solaris", Author: "Stanisław Lem", PublicationYear: 1961}},
+		{List: list, Book: &bookshelf.Book{ID: 2, Title: "Solaris", Author: "Stanislaw Lem", PublicationYear: 1961}},
+	}}
+	s := NewCiteService(nil, export)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, s.CiteList(context.Background(), 1, 2, "bibtex", buf))
+	assert.Equal(t, "@book{lem1961solarisa,\n"+
+		"  author = {Lem, Stanisław},\n"+
+		"  title = {{Solaris}},\n"+
+		"  year = {1961},\n"+
+		"}\n"+
+		"\n"+
+		"@book{lem1961solarisb,\n"+
+		"  author = {Lem, Stanislaw},\n"+
+		"  title = {{Solaris}},\n"+
+		"  year = {1961},\n"+
+		"}\n", buf.String())
+
+	assert.ErrorIs(t, s.CiteList(context.Background(), 1, 3, "bibtex", &bytes.Buffer{}), bookshelf.ErrNotFound)
+}
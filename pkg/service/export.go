@@ -0,0 +1,28 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+)
+
+type ExportService struct {
+	listStorage storage.List
+}
+
+func NewExportService(listStorage storage.List) *ExportService {
+	return &ExportService{listStorage: listStorage}
+}
+
+// Export calls fn for every book in every list of the user.
+func (s *ExportService) Export(ctx context.Context, userID int, fn func(bookshelf.ExportRow) error) error {
+	return s.listStorage.Export(ctx, userID, 0, fn)
+}
+
+// ExportList calls fn for every book of a list the user is a member of.
+func (s *ExportService) ExportList(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error {
+	if _, err := s.listStorage.GetRole(ctx, userID, listID); err != nil {
+		return err
+	}
+	return s.listStorage.Export(ctx, userID, listID, fn)
+}
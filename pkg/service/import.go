@@ -0,0 +1,384 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	importSourceGoodreads = "goodreads"
+	importSourceJSON      = "json"
+
+	// importJobTTL is how long finished jobs can still be looked up.
+	importJobTTL = 24 * time.Hour
+)
+
+var ErrImportJobNotFound = bookshelf.NewError(bookshelf.ErrNotFound, "import_job_not_found", "import job not found")
+
+// importRecord is a book to import together with the titles of the lists it
+// belongs to.
+type importRecord struct {
+	book  bookshelf.Book
+	lists []string
+}
+
+// ImportService imports books from files of other applications. Imports run
+// in the background, jobs are kept in memory and are lost on restart.
+type ImportService struct {
+	listStorage storage.List
+	books       Book
+
+	mu   sync.Mutex
+	jobs map[string]*bookshelf.ImportJob
+}
+
+func NewImportService(listStorage storage.List, books Book) *ImportService {
+	return &ImportService{
+		listStorage: listStorage,
+		books:       books,
+		jobs:        make(map[string]*bookshelf.ImportJob),
+	}
+}
+
+// ImportGoodreads reads a Goodreads library export and starts importing it.
+// Shelves become lists of the same name, books already in the library are
+// recognized by ISBN and only added to the lists.
+func (s *ImportService) ImportGoodreads(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+	records, err := parseGoodreads(r)
+	if err != nil {
+		return bookshelf.ImportJob{}, err
+	}
+	return s.start(ctx, userID, importSourceGoodreads, nil, records)
+}
+
+// ImportJSON reads a document written by the JSON export and starts
+// importing it. Lists are matched by title, books by ISBN.
+func (s *ImportService) ImportJSON(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+	var doc bookshelf.ExportDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return bookshelf.ImportJob{}, bookshelf.NewError(bookshelf.ErrValidation, "invalid_json", "file is not a valid export")
+	}
+	if doc.Version != bookshelf.ExportVersion {
+		return bookshelf.ImportJob{}, bookshelf.NewError(bookshelf.ErrValidation, "unsupported_version", "unsupported export version "+strconv.Itoa(doc.Version))
+	}
+
+	lists := make([]bookshelf.List, 0, len(doc.Lists))
+	var records []importRecord
+	// A book is exported once per list holding it, its exported ID joins
+	// the copies into one record, even without an ISBN to match them.
+	exported := make(map[int]int)
+	for _, list := range doc.Lists {
+		lists = append(lists, bookshelf.List{Title: list.Title, Description: list.Description})
+		for _, book := range list.Books {
+			if i, ok := exported[book.ID]; ok {
+				records[i].lists = append(records[i].lists, list.Title)
+				continue
+			}
+			if book.ID != 0 {
+				exported[book.ID] = len(records)
+			}
+			book.ID = 0
+			records = append(records, importRecord{book: book, lists: []string{list.Title}})
+		}
+	}
+	return s.start(ctx, userID, importSourceJSON, lists, records)
+}
+
+// GetJob returns the current state of an import job of the user.
+func (s *ImportService) GetJob(ctx context.Context, userID int, jobID string) (bookshelf.ImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return bookshelf.ImportJob{}, ErrImportJobNotFound
+	}
+	return snapshot(job), nil
+}
+
+// start runs the import in the background. lists are created up front, so
+// lists without books are imported too. The job outlives the request, so it
+// keeps the values of ctx but not its cancellation.
+func (s *ImportService) start(ctx context.Context, userID int, source string, lists []bookshelf.List, records []importRecord) (bookshelf.ImportJob, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return bookshelf.ImportJob{}, err
+	}
+	job := &bookshelf.ImportJob{
+		ID:        id,
+		UserID:    userID,
+		Source:    source,
+		Status:    bookshelf.ImportRunning,
+		Total:     len(records),
+		Errors:    []bookshelf.ImportRowError{},
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.prune()
+	s.jobs[id] = job
+	view := snapshot(job)
+	s.mu.Unlock()
+
+	go s.run(context.WithoutCancel(ctx), job, lists, records)
+	return view, nil
+}
+
+func (s *ImportService) run(ctx context.Context, job *bookshelf.ImportJob, lists []bookshelf.List, records []importRecord) {
+	status := bookshelf.ImportDone
+	ids, err := s.listIDs(ctx, job.UserID)
+	for _, list := range lists {
+		if err != nil {
+			break
+		}
+		_, err = s.listID(ctx, job.UserID, ids, list)
+	}
+	if err != nil {
+		status = bookshelf.ImportFailed
+		records = nil
+		s.mu.Lock()
+		job.Errors = append(job.Errors, bookshelf.ImportRowError{Message: rowMessage(err)})
+		s.mu.Unlock()
+	}
+
+	for i, record := range records {
+		created, err := s.importRecord(ctx, job.UserID, ids, record)
+
+		s.mu.Lock()
+		job.Processed++
+		switch {
+		case created:
+			// The book exists even if adding it to a later list failed.
+			job.Created++
+		case err != nil:
+			job.Failed++
+		default:
+			job.Skipped++
+		}
+		if err != nil {
+			job.Errors = append(job.Errors, bookshelf.ImportRowError{Row: i + 1, Message: rowMessage(err)})
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	s.mu.Unlock()
+}
+
+// importRecord adds the book of record to its lists and reports whether the
+// book had to be created.
+func (s *ImportService) importRecord(ctx context.Context, userID int, lists map[string]int, record importRecord) (bool, error) {
+	if record.book.Title == "" && record.book.ISBN == "" {
+		return false, bookshelf.NewError(bookshelf.ErrValidation, "missing_title", "book has neither a title nor an ISBN")
+	}
+	if len(record.lists) == 0 {
+		return false, bookshelf.NewError(bookshelf.ErrValidation, "missing_list", "book is not part of any list")
+	}
+	listIDs := make([]int, 0, len(record.lists))
+	for _, title := range record.lists {
+		id, err := s.listID(ctx, userID, lists, bookshelf.List{Title: title})
+		if err != nil {
+			return false, err
+		}
+		listIDs = append(listIDs, id)
+	}
+
+	book := record.book
+	bookID := 0
+	if book.ISBN != "" {
+		existing, err := s.books.GetByISBN(ctx, userID, book.ISBN)
+		switch {
+		case err == nil:
+			bookID = existing.ID
+		case errors.Is(err, bookshelf.ErrValidation):
+			// The book is still worth importing without its broken ISBN.
+			book.ISBN = ""
+		case !errors.Is(err, bookshelf.ErrNotFound):
+			return false, err
+		}
+	}
+
+	if bookID != 0 {
+		err := s.books.Attach(ctx, userID, listIDs[0], bookID)
+		switch {
+		case errors.Is(err, bookshelf.ErrForbidden):
+			// The book is only shared with the user, import a copy of
+			// their own instead.
+			bookID = 0
+		case err != nil:
+			return false, err
+		default:
+			listIDs = listIDs[1:]
+		}
+	}
+
+	created := bookID == 0
+	if created {
+		id, err := s.books.Create(ctx, userID, listIDs[0], book)
+		if err != nil {
+			return false, err
+		}
+		bookID = id
+		listIDs = listIDs[1:]
+	}
+	for _, listID := range listIDs {
+		if err := s.books.Attach(ctx, userID, listID, bookID); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// listIDs maps the lowercased titles of the lists the user can edit to their
+// IDs. Lists the user can only view are left out, the import creates a list
+// of its own instead.
+func (s *ImportService) listIDs(ctx context.Context, userID int) (map[string]int, error) {
+	lists, err := s.listStorage.GetAll(ctx, userID, bookshelf.ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int, len(lists))
+	for _, list := range lists {
+		key := strings.ToLower(list.Title)
+		if _, ok := ids[key]; ok {
+			continue
+		}
+		role, err := s.listStorage.GetRole(ctx, userID, list.ID)
+		if err != nil {
+			return nil, err
+		}
+		if bookshelf.HasRole(role, bookshelf.RoleEditor) {
+			ids[key] = list.ID
+		}
+	}
+	return ids, nil
+}
+
+// listID returns the list with the title of list, creating it on first use.
+func (s *ImportService) listID(ctx context.Context, userID int, lists map[string]int, list bookshelf.List) (int, error) {
+	key := strings.ToLower(list.Title)
+	if id, ok := lists[key]; ok {
+		return id, nil
+	}
+	id, err := s.listStorage.Create(ctx, userID, list)
+	if err != nil {
+		return 0, err
+	}
+	lists[key] = id
+	return id, nil
+}
+
+// prune drops jobs that finished more than importJobTTL ago. The caller must
+// hold s.mu.
+func (s *ImportService) prune() {
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > importJobTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// snapshot copies job so it can be read while the import goes on.
+func snapshot(job *bookshelf.ImportJob) bookshelf.ImportJob {
+	view := *job
+	view.Errors = append([]bookshelf.ImportRowError{}, job.Errors...)
+	return view
+}
+
+func rowMessage(err error) string {
+	if message, ok := bookshelf.Message(err); ok {
+		return message
+	}
+	return "internal error"
+}
+
+// parseGoodreads reads the CSV export of a Goodreads library. Only the Title
+// column is required.
+func parseGoodreads(r io.Reader) ([]importRecord, error) {
+	invalid := bookshelf.NewError(bookshelf.ErrValidation, "invalid_csv", "file is not a valid Goodreads export")
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalid
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns["Title"]; !ok {
+		return nil, bookshelf.NewError(bookshelf.ErrValidation, "invalid_csv", "Title column is missing")
+	}
+
+	var records []importRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalid
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		book := bookshelf.Book{
+			Title:     field("Title"),
+			Author:    field("Author"),
+			Publisher: field("Publisher"),
+			ISBN:      goodreadsISBN(field("ISBN13")),
+		}
+		if book.ISBN == "" {
+			book.ISBN = goodreadsISBN(field("ISBN"))
+		}
+		book.PageCount, _ = strconv.Atoi(field("Number of Pages"))
+		book.PublicationYear, _ = strconv.Atoi(field("Year Published"))
+		if book.PublicationYear == 0 {
+			book.PublicationYear, _ = strconv.Atoi(field("Original Publication Year"))
+		}
+
+		shelves := []string{field("Exclusive Shelf")}
+		shelves = append(shelves, strings.Split(field("Bookshelves"), ",")...)
+		records = append(records, importRecord{book: book, lists: uniqueTitles(shelves)})
+	}
+	return records, nil
+}
+
+// goodreadsISBN strips the spreadsheet formula Goodreads wraps ISBNs in,
+// e.g. ="0441172717".
+func goodreadsISBN(s string) string {
+	return strings.Trim(s, `="`)
+}
+
+func uniqueTitles(titles []string) []string {
+	seen := make(map[string]bool, len(titles))
+	unique := make([]string, 0, len(titles))
+	for _, title := range titles {
+		title = strings.TrimSpace(title)
+		key := strings.ToLower(title)
+		if title == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, title)
+	}
+	return unique
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// importListStorageStub makes the user owner of every list not in roles.
+// Creating lists fails with createErr.
+type importListStorageStub struct {
+	storage.List
+	lists     []bookshelf.List
+	roles     map[int]string
+	createErr error
+}
+
+func (s *importListStorageStub) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, error) {
+	return s.lists, nil
+}
+
+func (s *importListStorageStub) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	if role, ok := s.roles[listID]; ok {
+		return role, nil
+	}
+	return bookshelf.RoleOwner, nil
+}
+
+func (s *importListStorageStub) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	if s.createErr != nil {
+		return 0, s.createErr
+	}
+	list.ID = len(s.lists) + 1
+	s.lists = append(s.lists, list)
+	return list.ID, nil
+}
+
+// importBookServiceStub records which lists every book ended up in. Books in
+// readOnly cannot be attached, neither can any book to lists in broken.
+type importBookServiceStub struct {
+	Book
+	mu       sync.Mutex
+	books    []bookshelf.Book
+	lists    map[int][]int
+	readOnly map[int]bool
+	broken   map[int]bool
+}
+
+func (s *importBookServiceStub) Create(ctx context.Context, userID, listID int, book bookshelf.Book) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	book.ID = len(s.books) + 1
+	s.books = append(s.books, book)
+	s.lists[book.ID] = append(s.lists[book.ID], listID)
+	return book.ID, nil
+}
+
+func (s *importBookServiceStub) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	normalized, err := bookshelf.NormalizeISBN(isbn)
+	if err != nil {
+		return bookshelf.Book{}, err
+	}
+	for _, book := range s.books {
+		if book.ISBN == normalized {
+			return book, nil
+		}
+	}
+	return bookshelf.Book{}, bookshelf.ErrNotFound
+}
+
+func (s *importBookServiceStub) Attach(ctx context.Context, userID, listID, bookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly[bookID] {
+		return bookshelf.ErrForbidden
+	}
+	if s.broken[listID] {
+		return bookshelf.ErrNotFound
+	}
+	s.lists[bookID] = append(s.lists[bookID], listID)
+	return nil
+}
+
+const goodreadsExport = `Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Publisher,Binding,Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves,Bookshelves with positions,Exclusive Shelf
+234225,"Dune (Dune, #1)",Frank Herbert,"Herbert, Frank",,"=""0441172717""","=""9780441172719""",5,4.27,Ace Books,Paperback,535,1990,1965,,2020/01/01,"favorites, sci-fi","favorites (#1), sci-fi (#2)",read
+5907,The Hobbit,J.R.R. Tolkien,"Tolkien, J.R.R.",,"=""""","=""""",0,4.29,Houghton Mifflin,Paperback,366,,1937,,2020/01/02,,,to-read
+1,Dune,Frank Herbert,"Herbert, Frank",,"=""""","=""9780441172719""",0,4.27,Ace Books,Paperback,535,1990,1965,,2020/01/03,,,currently-reading
+2,,,,,"=""""","=""""",0,0,,,,,,,2020/01/04,,,to-read
+`
+
+func TestImportService_ImportGoodreads(t *testing.T) {
+	ctx := context.Background()
+	lists := &importListStorageStub{lists: []bookshelf.List{{ID: 1, Title: "To-Read"}}}
+	books := &importBookServiceStub{
+		books: []bookshelf.Book{{ID: 1, Title: "Existing", ISBN: "9780306406157"}},
+		lists: map[int][]int{1: {1}},
+	}
+	s := NewImportService(lists, books)
+
+	job, err := s.ImportGoodreads(ctx, 1, strings.NewReader(goodreadsExport))
+	require.NoError(t, err)
+	assert.Equal(t, 4, job.Total)
+
+	require.Eventually(t, func() bool {
+		job, err = s.GetJob(ctx, 1, job.ID)
+		return err == nil && job.Status != bookshelf.ImportRunning
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, bookshelf.ImportDone, job.Status)
+	assert.Equal(t, 4, job.Processed)
+	assert.Equal(t, 2, job.Created)
+	assert.Equal(t, 1, job.Skipped)
+	assert.Equal(t, 1, job.Failed)
+	assert.Equal(t, []bookshelf.ImportRowError{{Row: 4, Message: "book has neither a title nor an ISBN"}}, job.Errors)
+
+	titles := make([]string, len(lists.lists))
+	for i, list := range lists.lists {
+		titles[i] = list.Title
+	}
+	assert.Equal(t, []string{"To-Read", "read", "favorites", "sci-fi", "currently-reading"}, titles)
+
+	assert.Equal(t, bookshelf.Book{
+		ID:              2,
+		Title:           "Dune (Dune, #1)",
+		Author:          "Frank Herbert",
+		Publisher:       "Ace Books",
+		PublicationYear: 1990,
+		PageCount:       535,
+		ISBN:            "9780441172719",
+	}, books.books[1])
+	assert.Equal(t, []int{2, 3, 4, 5}, books.lists[2])
+	assert.Equal(t, 1937, books.books[2].PublicationYear)
+	assert.Equal(t, []int{1}, books.lists[3])
+
+	_, err = s.GetJob(ctx, 2, job.ID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+}
+
+func TestImportService_ImportGoodreads_Shared(t *testing.T) {
+	ctx := context.Background()
+	// The user views the shared To-Read list of someone else, Dune is on it.
+	lists := &importListStorageStub{
+		lists: []bookshelf.List{{ID: 1, Title: "To-Read"}, {ID: 2, Title: "Broken"}},
+		roles: map[int]string{1: bookshelf.RoleViewer},
+	}
+	books := &importBookServiceStub{
+		books:    []bookshelf.Book{{ID: 1, Title: "Dune", ISBN: "9780441172719"}},
+		lists:    map[int][]int{1: {1}},
+		readOnly: map[int]bool{1: true},
+		broken:   map[int]bool{2: true},
+	}
+	s := NewImportService(lists, books)
+
+	export := `Title,ISBN13,Bookshelves,Exclusive Shelf
+Dune,"=""9780441172719""",,to-read
+The Hobbit,,broken,to-read
+`
+	job, err := s.ImportGoodreads(ctx, 1, strings.NewReader(export))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, err = s.GetJob(ctx, 1, job.ID)
+		return err == nil && job.Status != bookshelf.ImportRunning
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, bookshelf.ImportDone, job.Status)
+	assert.Equal(t, 2, job.Processed)
+	assert.Equal(t, 2, job.Created)
+	assert.Equal(t, 0, job.Skipped)
+	assert.Equal(t, 0, job.Failed)
+	assert.Equal(t, []bookshelf.ImportRowError{{Row: 2, Message: "not found"}}, job.Errors)
+
+	// Both books go to a to-read list of the user, Dune as a copy.
+	assert.Equal(t, bookshelf.List{ID: 3, Title: "to-read"}, lists.lists[2])
+	assert.Equal(t, []int{1}, books.lists[1])
+	assert.Equal(t, "Dune", books.books[1].Title)
+	assert.Equal(t, []int{3}, books.lists[2])
+	assert.Equal(t, []int{3}, books.lists[3])
+}
+
+func TestImportService_ImportGoodreads_Invalid(t *testing.T) {
+	ctx := context.Background()
+	s := NewImportService(&importListStorageStub{}, &importBookServiceStub{})
+
+	_, err := s.ImportGoodreads(ctx, 1, strings.NewReader("Book Id,Author\n1,Frank Herbert\n"))
+	assert.ErrorIs(t, err, bookshelf.ErrValidation)
+
+	_, err = s.ImportGoodreads(ctx, 1, strings.NewReader(""))
+	assert.ErrorIs(t, err, bookshelf.ErrValidation)
+}
+
+func TestImportService_ImportJSON(t *testing.T) {
+	ctx := context.Background()
+	lists := &importListStorageStub{lists: []bookshelf.List{{ID: 1, Title: "Sci-fi"}}}
+	books := &importBookServiceStub{lists: map[int][]int{}}
+	s := NewImportService(lists, books)
+
+	doc := `{"version":1,"lists":[
+		{"id":7,"title":"sci-fi","description":"","books":[{"id":3,"title":"Dune","author":"Frank Herbert","isbn":"9780441172719"}]},
+		{"id":8,"title":"Favorites","description":"the best","books":[{"id":3,"title":"Dune","author":"Frank Herbert","isbn":"9780441172719"}]},
+		{"id":9,"title":"Empty","description":"","books":[]}
+	]}`
+	job, err := s.ImportJSON(ctx, 1, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, err = s.GetJob(ctx, 1, job.ID)
+		return err == nil && job.Status != bookshelf.ImportRunning
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, bookshelf.ImportDone, job.Status)
+	assert.Equal(t, 1, job.Total)
+	assert.Equal(t, 1, job.Created)
+	assert.Equal(t, []bookshelf.List{
+		{ID: 1, Title: "Sci-fi"},
+		{ID: 2, Title: "Favorites", Description: "the best"},
+		{ID: 3, Title: "Empty"},
+	}, lists.lists)
+	assert.Equal(t, bookshelf.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"}, books.books[0])
+	assert.Equal(t, []int{1, 2}, books.lists[1])
+
+	_, err = s.ImportJSON(ctx, 1, strings.NewReader(`{"version":2,"lists":[]}`))
+	assert.ErrorIs(t, err, bookshelf.ErrValidation)
+}
+
+func TestImportService_ImportJSON_ListFailed(t *testing.T) {
+	ctx := context.Background()
+	lists := &importListStorageStub{createErr: bookshelf.NewError(bookshelf.ErrValidation, "invalid_title", "title is too long")}
+	books := &importBookServiceStub{lists: map[int][]int{}}
+	s := NewImportService(lists, books)
+
+	doc := `{"version":1,"lists":[{"id":7,"title":"Classics","description":"","books":[{"id":3,"title":"Emma","author":"Jane Austen"}]}]}`
+	job, err := s.ImportJSON(ctx, 1, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, err = s.GetJob(ctx, 1, job.ID)
+		return err == nil && job.Status != bookshelf.ImportRunning
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, bookshelf.ImportFailed, job.Status)
+	assert.Equal(t, []bookshelf.ImportRowError{{Message: "title is too long"}}, job.Errors)
+	assert.Empty(t, books.books)
+}
+
+func TestImportService_ImportJSON_SharedWithoutISBN(t *testing.T) {
+	ctx := context.Background()
+	lists := &importListStorageStub{}
+	books := &importBookServiceStub{lists: map[int][]int{}}
+	s := NewImportService(lists, books)
+
+	doc := `{"version":1,"lists":[
+		{"id":7,"title":"Classics","description":"","books":[{"id":3,"title":"Emma","author":"Jane Austen"},{"id":4,"title":"Emma","author":"Jane Austen"}]},
+		{"id":8,"title":"Favorites","description":"","books":[{"id":3,"title":"Emma","author":"Jane Austen"}]}
+	]}`
+	job, err := s.ImportJSON(ctx, 1, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, err = s.GetJob(ctx, 1, job.ID)
+		return err == nil && job.Status != bookshelf.ImportRunning
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, bookshelf.ImportDone, job.Status)
+	assert.Equal(t, 2, job.Created)
+	require.Len(t, books.books, 2)
+	assert.Equal(t, []int{1, 2}, books.lists[books.books[0].ID])
+	assert.Equal(t, []int{1}, books.lists[books.books[1].ID])
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"os"
+)
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// keyring holds every key accepted by ParseToken and the one used to sign
+// new tokens.
+type keyring struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	order  []string
+}
+
+func newKeyring(cfg config.Auth) (*keyring, error) {
+	if len(cfg.SigningKeys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	kr := &keyring{keys: make(map[string]*signingKey, len(cfg.SigningKeys))}
+	for _, kc := range cfg.SigningKeys {
+		if kc.ID == "" {
+			return nil, errors.New("signing key without id")
+		}
+		if _, ok := kr.keys[kc.ID]; ok {
+			return nil, fmt.Errorf("duplicate signing key %q", kc.ID)
+		}
+		key, err := loadSigningKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", kc.ID, err)
+		}
+		kr.keys[kc.ID] = key
+		kr.order = append(kr.order, kc.ID)
+	}
+
+	activeID := cfg.SigningKeyID
+	if activeID == "" {
+		activeID = cfg.SigningKeys[0].ID
+	}
+	active, ok := kr.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", activeID)
+	}
+	kr.active = active
+
+	return kr, nil
+}
+
+func loadSigningKey(cfg config.SigningKey) (*signingKey, error) {
+	key := &signingKey{id: cfg.ID}
+
+	switch cfg.Algorithm {
+	case "HS256", "HS384", "HS512":
+		if cfg.Secret == "" {
+			return nil, errors.New("empty secret")
+		}
+		key.method = jwt.GetSigningMethod(cfg.Algorithm)
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+	case "RS256", "RS384", "RS512":
+		key.method = jwt.GetSigningMethod(cfg.Algorithm)
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = &private.PublicKey
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = private.(ed25519.PrivateKey).Public()
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			public, err := jwt.ParseEdPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	if key.verifyKey == nil {
+		return nil, errors.New("no key file configured")
+	}
+	return key, nil
+}
+
+// sign issues a token signed with the active key.
+func (kr *keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.method, claims)
+	token.Header["kid"] = kr.active.id
+	return token.SignedString(kr.active.signKey)
+}
+
+// keyFunc selects the verification key by the kid header. Tokens issued
+// before kid was introduced are checked against the active key.
+func (kr *keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := kr.active
+	if kid, ok := token.Header["kid"]; ok {
+		id, ok := kid.(string)
+		if !ok {
+			return nil, errors.New("invalid kid header")
+		}
+		key, ok = kr.keys[id]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// jwks returns the public part of every asymmetric key. HMAC secrets are
+// never published.
+func (kr *keyring) jwks() bookshelf.JWKS {
+	set := bookshelf.JWKS{Keys: []bookshelf.JWK{}}
+	for _, id := range kr.order {
+		key := kr.keys[id]
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, bookshelf.JWK{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, bookshelf.JWK{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return set
+}
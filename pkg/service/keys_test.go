@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bookshelf-api/pkg/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey := config.SigningKey{ID: "old", Algorithm: "HS256", Secret: "old-secret"}
+	newKey := config.SigningKey{ID: "new", Algorithm: "HS256", Secret: "new-secret"}
+
+	before, err := newKeyring(config.Auth{SigningKeyID: "old", SigningKeys: []config.SigningKey{oldKey}})
+	require.NoError(t, err)
+	oldToken, err := before.sign(testClaims(1))
+	require.NoError(t, err)
+
+	after, err := newKeyring(config.Auth{SigningKeyID: "new", SigningKeys: []config.SigningKey{newKey, oldKey}})
+	require.NoError(t, err)
+	newToken, err := after.sign(testClaims(2))
+	require.NoError(t, err)
+
+	for token, userID := range map[string]int{oldToken: 1, newToken: 2} {
+		parsed, err := jwt.ParseWithClaims(token, &tokenClaims{}, after.keyFunc)
+		require.NoError(t, err)
+		assert.Equal(t, userID, parsed.Claims.(*tokenClaims).UserID)
+	}
+
+	_, err = jwt.ParseWithClaims(newToken, &tokenClaims{}, before.keyFunc)
+	assert.Error(t, err)
+}
+
+func TestKeyring_Asymmetric(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaFile := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edFile := writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", edDER)
+
+	keys := []config.SigningKey{
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaFile},
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edFile},
+		{ID: "hmac", Algorithm: "HS256", Secret: "secret"},
+	}
+	for _, active := range []string{"rsa", "ed"} {
+		t.Run(active, func(t *testing.T) {
+			kr, err := newKeyring(config.Auth{SigningKeyID: active, SigningKeys: keys})
+			require.NoError(t, err)
+
+			token, err := kr.sign(testClaims(1))
+			require.NoError(t, err)
+			parsed, err := jwt.ParseWithClaims(token, &tokenClaims{}, kr.keyFunc)
+			require.NoError(t, err)
+			assert.Equal(t, active, parsed.Header["kid"])
+
+			set := kr.jwks()
+			require.Len(t, set.Keys, 2)
+			assert.Equal(t, "RSA", set.Keys[0].Kty)
+			assert.Equal(t, "AQAB", set.Keys[0].E)
+			assert.Equal(t, "OKP", set.Keys[1].Kty)
+			assert.Equal(t, "Ed25519", set.Keys[1].Crv)
+		})
+	}
+}
+
+func TestKeyring_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Auth
+	}{
+		{
+			name: "No keys",
+			cfg:  config.Auth{},
+		},
+		{
+			name: "Unknown active key",
+			cfg: config.Auth{
+				SigningKeyID: "missing",
+				SigningKeys:  []config.SigningKey{{ID: "a", Algorithm: "HS256", Secret: "secret"}},
+			},
+		},
+		{
+			name: "Unsupported algorithm",
+			cfg: config.Auth{
+				SigningKeys: []config.SigningKey{{ID: "a", Algorithm: "none"}},
+			},
+		},
+		{
+			name: "Empty secret",
+			cfg: config.Auth{
+				SigningKeys: []config.SigningKey{{ID: "a", Algorithm: "HS256"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newKeyring(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func testClaims(userID int) *tokenClaims {
+	return &tokenClaims{
+		jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+		userID,
+	}
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
@@ -3,39 +3,147 @@ package service
 import (
 	bookshelf "bookshelf-api"
 	"bookshelf-api/pkg/storage"
+	"context"
+	"errors"
+)
+
+var (
+	ErrAlreadyMember = bookshelf.NewError(bookshelf.ErrConflict, "already_member", "user is already a member of the list")
+	ErrLastOwner     = bookshelf.NewError(bookshelf.ErrConflict, "last_owner", "list must have at least one owner")
 )
 
 type ListService struct {
-	storage storage.List
+	storage     storage.List
+	userStorage storage.Authorization
 }
 
-func NewListService(storage storage.List) *ListService {
-	return &ListService{storage: storage}
+func NewListService(storage storage.List, userStorage storage.Authorization) *ListService {
+	return &ListService{
+		storage:     storage,
+		userStorage: userStorage,
+	}
 }
 
-func (s *ListService) Create(userID int, list bookshelf.List) (int, error) {
-	return s.storage.Create(userID, list)
+func (s *ListService) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	return s.storage.Create(ctx, userID, list)
 }
 
-func (s *ListService) GetAll(userID int) ([]bookshelf.List, error) {
-	return s.storage.GetAll(userID)
+// GetAll returns a page of lists of the user and the cursor of the next page,
+// which is empty on the last page.
+func (s *ListService) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = bookshelf.DefaultPageLimit
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	lists, err := s.storage.GetAll(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(lists) <= limit {
+		return lists, "", nil
+	}
+	lists = lists[:limit]
+	last := lists[limit-1]
+	cursor := bookshelf.Cursor{Sort: filter.Sort, ID: last.ID}
+	if filter.Sort != "" {
+		cursor.Value = last.Title
+	}
+	return lists, cursor.Encode(), nil
 }
 
-func (s *ListService) GetByID(userID, listID int) (bookshelf.List, error) {
-	return s.storage.GetByID(userID, listID)
+func (s *ListService) GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error) {
+	return s.storage.GetByID(ctx, userID, listID)
 }
 
-func (s *ListService) Update(userID, listID int, input bookshelf.UpdateListInput) error {
+func (s *ListService) Update(ctx context.Context, userID, listID int, input bookshelf.UpdateListInput) error {
 	if err := input.Validate(); err != nil {
 		return err
 	}
-	list, err := s.GetByID(userID, listID)
+	list, err := s.GetByID(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireRole(ctx, userID, listID, bookshelf.RoleOwner); err != nil {
+		return err
+	}
+	return s.storage.Update(ctx, userID, listID, list, input)
+}
+
+func (s *ListService) Delete(ctx context.Context, userID, listID int) error {
+	if err := s.requireRole(ctx, userID, listID, bookshelf.RoleOwner); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, userID, listID)
+}
+
+func (s *ListService) GetMembers(ctx context.Context, userID, listID int) ([]bookshelf.ListMember, error) {
+	if err := s.requireRole(ctx, userID, listID, bookshelf.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.storage.GetMembers(ctx, listID)
+}
+
+func (s *ListService) AddMember(ctx context.Context, userID, listID int, input bookshelf.AddListMemberInput) error {
+	if err := s.requireRole(ctx, userID, listID, bookshelf.RoleOwner); err != nil {
+		return err
+	}
+	user, err := s.userStorage.GetUser(ctx, input.Username)
 	if err != nil {
 		return err
 	}
-	return s.storage.Update(userID, listID, list, input)
+	_, err = s.storage.GetRole(ctx, user.ID, listID)
+	if err == nil {
+		return ErrAlreadyMember
+	}
+	if !errors.Is(err, bookshelf.ErrNotFound) {
+		return err
+	}
+	return s.storage.AddMember(ctx, listID, user.ID, input.Role)
 }
 
-func (s *ListService) Delete(userID, listID int) error {
-	return s.storage.Delete(userID, listID)
+// UpdateMember changes the role of a member. The storage refuses to demote
+// the last owner in the same transaction, so two owners demoting each other
+// at the same time cannot leave the list without one.
+func (s *ListService) UpdateMember(ctx context.Context, userID, listID, memberID int, input bookshelf.UpdateListMemberInput) error {
+	if err := s.requireRole(ctx, userID, listID, bookshelf.RoleOwner); err != nil {
+		return err
+	}
+	err := s.storage.UpdateMember(ctx, listID, memberID, input.Role)
+	if errors.Is(err, bookshelf.ErrConflict) {
+		return ErrLastOwner
+	}
+	return err
+}
+
+// RemoveMember removes a member from the list. Owners can remove anyone,
+// other members can only leave the list themselves. The last owner cannot
+// leave.
+func (s *ListService) RemoveMember(ctx context.Context, userID, listID, memberID int) error {
+	if userID != memberID {
+		if err := s.requireRole(ctx, userID, listID, bookshelf.RoleOwner); err != nil {
+			return err
+		}
+	}
+	err := s.storage.RemoveMember(ctx, listID, memberID)
+	if errors.Is(err, bookshelf.ErrConflict) {
+		return ErrLastOwner
+	}
+	return err
+}
+
+func (s *ListService) requireRole(ctx context.Context, userID, listID int, required string) error {
+	role, err := s.storage.GetRole(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	if !bookshelf.HasRole(role, required) {
+		return bookshelf.ErrForbidden
+	}
+	return nil
 }
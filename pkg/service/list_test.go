@@ -0,0 +1,188 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// memberStorageStub holds the members of a single list by user ID.
+type memberStorageStub struct {
+	storage.List
+	roles map[int]string
+}
+
+func (s *memberStorageStub) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	role, ok := s.roles[userID]
+	if !ok {
+		return "", bookshelf.ErrNotFound
+	}
+	return role, nil
+}
+
+func (s *memberStorageStub) AddMember(ctx context.Context, listID, userID int, role string) error {
+	s.roles[userID] = role
+	return nil
+}
+
+func (s *memberStorageStub) UpdateMember(ctx context.Context, listID, userID int, role string) error {
+	if _, ok := s.roles[userID]; !ok {
+		return bookshelf.ErrNotFound
+	}
+	previous := s.roles[userID]
+	s.roles[userID] = role
+	if !s.hasOwner() {
+		s.roles[userID] = previous
+		return bookshelf.ErrConflict
+	}
+	return nil
+}
+
+func (s *memberStorageStub) RemoveMember(ctx context.Context, listID, userID int) error {
+	role, ok := s.roles[userID]
+	if !ok {
+		return bookshelf.ErrNotFound
+	}
+	delete(s.roles, userID)
+	if !s.hasOwner() {
+		s.roles[userID] = role
+		return bookshelf.ErrConflict
+	}
+	return nil
+}
+
+func (s *memberStorageStub) hasOwner() bool {
+	for _, role := range s.roles {
+		if role == bookshelf.RoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+// The users of the list tests: alice owns the list, bob edits it, carol
+// views it and dave is not a member.
+const (
+	alice = iota + 1
+	bob
+	carol
+	dave
+)
+
+func newMemberStorageStub() *memberStorageStub {
+	return &memberStorageStub{roles: map[int]string{
+		alice: bookshelf.RoleOwner,
+		bob:   bookshelf.RoleEditor,
+		carol: bookshelf.RoleViewer,
+	}}
+}
+
+func TestListService_AddMember(t *testing.T) {
+	users := &authStorageStub{users: map[string]bookshelf.User{
+		"bob":  {ID: bob, Username: "bob"},
+		"dave": {ID: dave, Username: "dave"},
+	}}
+
+	tests := []struct {
+		name     string
+		userID   int
+		username string
+		wantErr  error
+	}{
+		{name: "OK", userID: alice, username: "dave"},
+		{name: "Editor", userID: bob, username: "dave", wantErr: bookshelf.ErrForbidden},
+		{name: "Not a member", userID: dave, username: "dave", wantErr: bookshelf.ErrNotFound},
+		{name: "Already member", userID: alice, username: "bob", wantErr: ErrAlreadyMember},
+		{name: "Unknown user", userID: alice, username: "erin", wantErr: bookshelf.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newMemberStorageStub()
+			s := NewListService(stub, users)
+
+			err := s.AddMember(context.Background(), tt.userID, 1, bookshelf.AddListMemberInput{Username: tt.username, Role: bookshelf.RoleViewer})
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, bookshelf.RoleViewer, stub.roles[dave])
+			}
+		})
+	}
+}
+
+func TestListService_UpdateMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   int
+		memberID int
+		role     string
+		wantErr  error
+	}{
+		{name: "OK", userID: alice, memberID: bob, role: bookshelf.RoleOwner},
+		{name: "Editor", userID: bob, memberID: carol, role: bookshelf.RoleEditor, wantErr: bookshelf.ErrForbidden},
+		{name: "Viewer", userID: carol, memberID: carol, role: bookshelf.RoleOwner, wantErr: bookshelf.ErrForbidden},
+		{name: "Not a member", userID: dave, memberID: bob, role: bookshelf.RoleViewer, wantErr: bookshelf.ErrNotFound},
+		{name: "Unknown member", userID: alice, memberID: dave, role: bookshelf.RoleViewer, wantErr: bookshelf.ErrNotFound},
+		{name: "Last owner", userID: alice, memberID: alice, role: bookshelf.RoleEditor, wantErr: ErrLastOwner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newMemberStorageStub()
+			s := NewListService(stub, nil)
+
+			err := s.UpdateMember(context.Background(), tt.userID, 1, tt.memberID, bookshelf.UpdateListMemberInput{Role: tt.role})
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.role, stub.roles[tt.memberID])
+			}
+		})
+	}
+}
+
+func TestListService_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   int
+		memberID int
+		wantErr  error
+	}{
+		{name: "Owner removes member", userID: alice, memberID: bob},
+		{name: "Member leaves", userID: carol, memberID: carol},
+		{name: "Editor removes member", userID: bob, memberID: carol, wantErr: bookshelf.ErrForbidden},
+		{name: "Not a member", userID: dave, memberID: carol, wantErr: bookshelf.ErrNotFound},
+		{name: "Last owner", userID: alice, memberID: alice, wantErr: ErrLastOwner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newMemberStorageStub()
+			s := NewListService(stub, nil)
+
+			err := s.RemoveMember(context.Background(), tt.userID, 1, tt.memberID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, member := stub.roles[tt.memberID]
+			assert.Equal(t, tt.wantErr != nil, member)
+		})
+	}
+}
+
+func TestListService_requireRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   int
+		required string
+		wantErr  error
+	}{
+		{name: "Owner", userID: alice, required: bookshelf.RoleOwner},
+		{name: "Editor reads", userID: bob, required: bookshelf.RoleViewer},
+		{name: "Editor owns", userID: bob, required: bookshelf.RoleOwner, wantErr: bookshelf.ErrForbidden},
+		{name: "Viewer edits", userID: carol, required: bookshelf.RoleEditor, wantErr: bookshelf.ErrForbidden},
+		{name: "Not a member", userID: dave, required: bookshelf.RoleViewer, wantErr: bookshelf.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewListService(newMemberStorageStub(), nil)
+			assert.ErrorIs(t, s.requireRole(context.Background(), tt.userID, 1, tt.required), tt.wantErr)
+		})
+	}
+}
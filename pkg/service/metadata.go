@@ -0,0 +1,80 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
+	"context"
+	"fmt"
+)
+
+var (
+	ErrMetadataNotFound = bookshelf.NewError(bookshelf.ErrNotFound, "metadata_not_found", "no metadata found for ISBN")
+	ErrMissingISBN      = bookshelf.NewError(bookshelf.ErrValidation, "missing_isbn", "book has no ISBN")
+	ErrNoMetadata       = bookshelf.NewError(bookshelf.ErrValidation, "metadata_disabled", "metadata lookup is disabled")
+	ErrMetadataFailed   = bookshelf.NewError(bookshelf.ErrUpstream, "metadata_unavailable", "metadata provider is unavailable")
+)
+
+// MetadataProvider looks up the details of a book by its normalized ISBN-13.
+// Fields the provider does not know are left empty.
+type MetadataProvider interface {
+	Lookup(ctx context.Context, isbn string) (bookshelf.Book, error)
+}
+
+// NewMetadataProvider returns the provider selected by cfg, or nil when
+// lookups are disabled.
+func NewMetadataProvider(cfg config.Metadata) (MetadataProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openlibrary":
+		return NewOpenLibraryProvider(cfg.BaseURL, cfg.Timeout), nil
+	case "stub":
+		return NewStubMetadataProvider(stubBooks), nil
+	}
+	return nil, fmt.Errorf("unknown metadata provider %q", cfg.Provider)
+}
+
+// StubMetadataProvider serves a fixed set of books, it allows to work on the
+// API without network access.
+type StubMetadataProvider struct {
+	books map[string]bookshelf.Book
+}
+
+func NewStubMetadataProvider(books map[string]bookshelf.Book) *StubMetadataProvider {
+	return &StubMetadataProvider{books: books}
+}
+
+func (p *StubMetadataProvider) Lookup(ctx context.Context, isbn string) (bookshelf.Book, error) {
+	book, ok := p.books[isbn]
+	if !ok {
+		return bookshelf.Book{}, ErrMetadataNotFound
+	}
+	book.ISBN = isbn
+	return book, nil
+}
+
+var stubBooks = map[string]bookshelf.Book{
+	"9780441172719": {Title: "Dune", Author: "Frank Herbert", Publisher: "Ace Books", PublicationYear: 1990, PageCount: 535},
+	"9780547928227": {Title: "The Hobbit", Author: "J. R. R. Tolkien", Publisher: "Houghton Mifflin Harcourt", PublicationYear: 2012, PageCount: 300},
+	"9780140449136": {Title: "Crime and Punishment", Author: "Fyodor Dostoevsky", Publisher: "Penguin Classics", PublicationYear: 2003, PageCount: 720},
+}
+
+// enrich fills the empty fields of book from meta.
+func enrich(book, meta bookshelf.Book) bookshelf.Book {
+	if book.Title == "" {
+		book.Title = meta.Title
+	}
+	if book.Author == "" {
+		book.Author = meta.Author
+	}
+	if book.Publisher == "" {
+		book.Publisher = meta.Publisher
+	}
+	if book.PublicationYear == 0 {
+		book.PublicationYear = meta.PublicationYear
+	}
+	if book.PageCount == 0 {
+		book.PageCount = meta.PageCount
+	}
+	return book
+}
@@ -0,0 +1,140 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// APIToken is an autogenerated mock type for the APIToken type
+type APIToken struct {
+	mock.Mock
+}
+
+// Authenticate provides a mock function with given fields: ctx, token
+func (_m *APIToken) Authenticate(ctx context.Context, token string) (bookshelf.APIToken, error) {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authenticate")
+	}
+
+	var r0 bookshelf.APIToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bookshelf.APIToken, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bookshelf.APIToken); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Get(0).(bookshelf.APIToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, userID, input
+func (_m *APIToken) Create(ctx context.Context, userID int, input bookshelf.CreateAPITokenInput) (bookshelf.APIToken, string, error) {
+	ret := _m.Called(ctx, userID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 bookshelf.APIToken
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.CreateAPITokenInput) (bookshelf.APIToken, string, error)); ok {
+		return rf(ctx, userID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.CreateAPITokenInput) bookshelf.APIToken); ok {
+		r0 = rf(ctx, userID, input)
+	} else {
+		r0 = ret.Get(0).(bookshelf.APIToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bookshelf.CreateAPITokenInput) string); ok {
+		r1 = rf(ctx, userID, input)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, bookshelf.CreateAPITokenInput) error); ok {
+		r2 = rf(ctx, userID, input)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Delete provides a mock function with given fields: ctx, userID, tokenID
+func (_m *APIToken) Delete(ctx context.Context, userID int, tokenID int) error {
+	ret := _m.Called(ctx, userID, tokenID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, userID, tokenID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAll provides a mock function with given fields: ctx, userID
+func (_m *APIToken) GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []bookshelf.APIToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]bookshelf.APIToken, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []bookshelf.APIToken); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.APIToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAPIToken creates a new instance of APIToken. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAPIToken(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *APIToken {
+	mock := &APIToken{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	bookshelf "bookshelf-api"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,9 +14,37 @@ type Authorization struct {
 	mock.Mock
 }
 
-// CreateUser provides a mock function with given fields: user
-func (_m *Authorization) CreateUser(user bookshelf.User) (int, error) {
-	ret := _m.Called(user)
+// Authenticate provides a mock function with given fields: ctx, username, password
+func (_m *Authorization) Authenticate(ctx context.Context, username string, password string) (int, error) {
+	ret := _m.Called(ctx, username, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authenticate")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int, error)); ok {
+		return rf(ctx, username, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int); ok {
+		r0 = rf(ctx, username, password)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *Authorization) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
+	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateUser")
@@ -23,17 +52,17 @@ func (_m *Authorization) CreateUser(user bookshelf.User) (int, error) {
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(bookshelf.User) (int, error)); ok {
-		return rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, bookshelf.User) (int, error)); ok {
+		return rf(ctx, user)
 	}
-	if rf, ok := ret.Get(0).(func(bookshelf.User) int); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, bookshelf.User) int); ok {
+		r0 = rf(ctx, user)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(bookshelf.User) error); ok {
-		r1 = rf(user)
+	if rf, ok := ret.Get(1).(func(context.Context, bookshelf.User) error); ok {
+		r1 = rf(ctx, user)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,27 +70,27 @@ func (_m *Authorization) CreateUser(user bookshelf.User) (int, error) {
 	return r0, r1
 }
 
-// GenerateToken provides a mock function with given fields: username, password
-func (_m *Authorization) GenerateToken(username string, password string) (string, error) {
-	ret := _m.Called(username, password)
+// GenerateToken provides a mock function with given fields: ctx, username, password
+func (_m *Authorization) GenerateToken(ctx context.Context, username string, password string) (bookshelf.TokenPair, error) {
+	ret := _m.Called(ctx, username, password)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateToken")
 	}
 
-	var r0 string
+	var r0 bookshelf.TokenPair
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (string, error)); ok {
-		return rf(username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bookshelf.TokenPair, error)); ok {
+		return rf(ctx, username, password)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) string); ok {
-		r0 = rf(username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bookshelf.TokenPair); ok {
+		r0 = rf(ctx, username, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(bookshelf.TokenPair)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(username, password)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, password)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -69,9 +98,45 @@ func (_m *Authorization) GenerateToken(username string, password string) (string
 	return r0, r1
 }
 
-// ParseToken provides a mock function with given fields: token
-func (_m *Authorization) ParseToken(token string) (int, error) {
-	ret := _m.Called(token)
+// JWKS provides a mock function with given fields:
+func (_m *Authorization) JWKS() bookshelf.JWKS {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JWKS")
+	}
+
+	var r0 bookshelf.JWKS
+	if rf, ok := ret.Get(0).(func() bookshelf.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bookshelf.JWKS)
+	}
+
+	return r0
+}
+
+// Logout provides a mock function with given fields: ctx, accessToken, refreshToken
+func (_m *Authorization) Logout(ctx context.Context, accessToken string, refreshToken string) error {
+	ret := _m.Called(ctx, accessToken, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, accessToken, refreshToken)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ParseToken provides a mock function with given fields: ctx, token
+func (_m *Authorization) ParseToken(ctx context.Context, token string) (int, error) {
+	ret := _m.Called(ctx, token)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ParseToken")
@@ -79,17 +144,45 @@ func (_m *Authorization) ParseToken(token string) (int, error) {
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
-		return rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, token)
 	}
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, token)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(token)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshTokens provides a mock function with given fields: ctx, refreshToken
+func (_m *Authorization) RefreshTokens(ctx context.Context, refreshToken string) (bookshelf.TokenPair, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshTokens")
+	}
+
+	var r0 bookshelf.TokenPair
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bookshelf.TokenPair, error)); ok {
+		return rf(ctx, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bookshelf.TokenPair); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		r0 = ret.Get(0).(bookshelf.TokenPair)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, refreshToken)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,77 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Backup is an autogenerated mock type for the Backup type
+type Backup struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, userID, w
+func (_m *Backup) Create(ctx context.Context, userID int, w io.Writer) error {
+	ret := _m.Called(ctx, userID, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Writer) error); ok {
+		r0 = rf(ctx, userID, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Restore provides a mock function with given fields: ctx, userID, archive, members
+func (_m *Backup) Restore(ctx context.Context, userID int, archive []byte, members bool) (bookshelf.RestoreResult, error) {
+	ret := _m.Called(ctx, userID, archive, members)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 bookshelf.RestoreResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []byte, bool) (bookshelf.RestoreResult, error)); ok {
+		return rf(ctx, userID, archive, members)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []byte, bool) bookshelf.RestoreResult); ok {
+		r0 = rf(ctx, userID, archive, members)
+	} else {
+		r0 = ret.Get(0).(bookshelf.RestoreResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, []byte, bool) error); ok {
+		r1 = rf(ctx, userID, archive, members)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewBackup creates a new instance of Backup. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewBackup(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Backup {
+	mock := &Backup{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	bookshelf "bookshelf-api"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,9 +14,27 @@ type Book struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: userID, listID, book
-func (_m *Book) Create(userID int, listID int, book bookshelf.Book) (int, error) {
-	ret := _m.Called(userID, listID, book)
+// Attach provides a mock function with given fields: ctx, userID, listID, bookID
+func (_m *Book) Attach(ctx context.Context, userID int, listID int, bookID int) error {
+	ret := _m.Called(ctx, userID, listID, bookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Attach")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, listID, bookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: ctx, userID, listID, book
+func (_m *Book) Create(ctx context.Context, userID int, listID int, book bookshelf.Book) (int, error) {
+	ret := _m.Called(ctx, userID, listID, book)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
@@ -23,17 +42,17 @@ func (_m *Book) Create(userID int, listID int, book bookshelf.Book) (int, error)
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int, bookshelf.Book) (int, error)); ok {
-		return rf(userID, listID, book)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.Book) (int, error)); ok {
+		return rf(ctx, userID, listID, book)
 	}
-	if rf, ok := ret.Get(0).(func(int, int, bookshelf.Book) int); ok {
-		r0 = rf(userID, listID, book)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.Book) int); ok {
+		r0 = rf(ctx, userID, listID, book)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int, bookshelf.Book) error); ok {
-		r1 = rf(userID, listID, book)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, bookshelf.Book) error); ok {
+		r1 = rf(ctx, userID, listID, book)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,17 +60,35 @@ func (_m *Book) Create(userID int, listID int, book bookshelf.Book) (int, error)
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: userID, bookID
-func (_m *Book) Delete(userID int, bookID int) error {
-	ret := _m.Called(userID, bookID)
+// Delete provides a mock function with given fields: ctx, userID, bookID
+func (_m *Book) Delete(ctx context.Context, userID int, bookID int) error {
+	ret := _m.Called(ctx, userID, bookID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, int) error); ok {
-		r0 = rf(userID, bookID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, userID, bookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Detach provides a mock function with given fields: ctx, userID, listID, bookID
+func (_m *Book) Detach(ctx context.Context, userID int, listID int, bookID int) error {
+	ret := _m.Called(ctx, userID, listID, bookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Detach")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, listID, bookID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -59,29 +96,64 @@ func (_m *Book) Delete(userID int, bookID int) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: userID, listID
-func (_m *Book) GetAll(userID int, listID int) ([]bookshelf.Book, error) {
-	ret := _m.Called(userID, listID)
+// GetAll provides a mock function with given fields: ctx, userID, listID, filter
+func (_m *Book) GetAll(ctx context.Context, userID int, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, string, error) {
+	ret := _m.Called(ctx, userID, listID, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
 	}
 
 	var r0 []bookshelf.Book
-	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int) ([]bookshelf.Book, error)); ok {
-		return rf(userID, listID)
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.BookFilter) ([]bookshelf.Book, string, error)); ok {
+		return rf(ctx, userID, listID, filter)
 	}
-	if rf, ok := ret.Get(0).(func(int, int) []bookshelf.Book); ok {
-		r0 = rf(userID, listID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.BookFilter) []bookshelf.Book); ok {
+		r0 = rf(ctx, userID, listID, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]bookshelf.Book)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int) error); ok {
-		r1 = rf(userID, listID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, bookshelf.BookFilter) string); ok {
+		r1 = rf(ctx, userID, listID, filter)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, int, bookshelf.BookFilter) error); ok {
+		r2 = rf(ctx, userID, listID, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetByID provides a mock function with given fields: ctx, userID, bookID
+func (_m *Book) GetByID(ctx context.Context, userID int, bookID int) (bookshelf.Book, error) {
+	ret := _m.Called(ctx, userID, bookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 bookshelf.Book
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (bookshelf.Book, error)); ok {
+		return rf(ctx, userID, bookID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) bookshelf.Book); ok {
+		r0 = rf(ctx, userID, bookID)
+	} else {
+		r0 = ret.Get(0).(bookshelf.Book)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, bookID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -89,27 +161,161 @@ func (_m *Book) GetAll(userID int, listID int) ([]bookshelf.Book, error) {
 	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: userID, bookID
-func (_m *Book) GetByID(userID int, bookID int) (bookshelf.Book, error) {
-	ret := _m.Called(userID, bookID)
+// GetByISBN provides a mock function with given fields: ctx, userID, isbn
+func (_m *Book) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
+	ret := _m.Called(ctx, userID, isbn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetByID")
+		panic("no return value specified for GetByISBN")
+	}
+
+	var r0 bookshelf.Book
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) (bookshelf.Book, error)); ok {
+		return rf(ctx, userID, isbn)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) bookshelf.Book); ok {
+		r0 = rf(ctx, userID, isbn)
+	} else {
+		r0 = ret.Get(0).(bookshelf.Book)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
+		r1 = rf(ctx, userID, isbn)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDuplicates provides a mock function with given fields: ctx, userID
+func (_m *Book) GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDuplicates")
+	}
+
+	var r0 []bookshelf.DuplicateGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]bookshelf.DuplicateGroup, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []bookshelf.DuplicateGroup); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.DuplicateGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Merge provides a mock function with given fields: ctx, userID, input
+func (_m *Book) Merge(ctx context.Context, userID int, input bookshelf.MergeBooksInput) (bookshelf.MergeResult, error) {
+	ret := _m.Called(ctx, userID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Merge")
+	}
+
+	var r0 bookshelf.MergeResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.MergeBooksInput) (bookshelf.MergeResult, error)); ok {
+		return rf(ctx, userID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.MergeBooksInput) bookshelf.MergeResult); ok {
+		r0 = rf(ctx, userID, input)
+	} else {
+		r0 = ret.Get(0).(bookshelf.MergeResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bookshelf.MergeBooksInput) error); ok {
+		r1 = rf(ctx, userID, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Move provides a mock function with given fields: ctx, userID, fromListID, toListID, bookID
+func (_m *Book) Move(ctx context.Context, userID int, fromListID int, toListID int, bookID int) error {
+	ret := _m.Called(ctx, userID, fromListID, toListID, bookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Move")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, int) error); ok {
+		r0 = rf(ctx, userID, fromListID, toListID, bookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Refresh provides a mock function with given fields: ctx, userID, bookID
+func (_m *Book) Refresh(ctx context.Context, userID int, bookID int) (bookshelf.Book, error) {
+	ret := _m.Called(ctx, userID, bookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Refresh")
 	}
 
 	var r0 bookshelf.Book
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int) (bookshelf.Book, error)); ok {
-		return rf(userID, bookID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (bookshelf.Book, error)); ok {
+		return rf(ctx, userID, bookID)
 	}
-	if rf, ok := ret.Get(0).(func(int, int) bookshelf.Book); ok {
-		r0 = rf(userID, bookID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) bookshelf.Book); ok {
+		r0 = rf(ctx, userID, bookID)
 	} else {
 		r0 = ret.Get(0).(bookshelf.Book)
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int) error); ok {
-		r1 = rf(userID, bookID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, bookID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Suggest provides a mock function with given fields: ctx, userID, query, limit
+func (_m *Book) Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error) {
+	ret := _m.Called(ctx, userID, query, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Suggest")
+	}
+
+	var r0 []bookshelf.BookSuggestion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) ([]bookshelf.BookSuggestion, error)); ok {
+		return rf(ctx, userID, query, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) []bookshelf.BookSuggestion); ok {
+		r0 = rf(ctx, userID, query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.BookSuggestion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int) error); ok {
+		r1 = rf(ctx, userID, query, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -117,17 +323,17 @@ func (_m *Book) GetByID(userID int, bookID int) (bookshelf.Book, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: userID, bookID, input
-func (_m *Book) Update(userID int, bookID int, input bookshelf.UpdateBookInput) error {
-	ret := _m.Called(userID, bookID, input)
+// Update provides a mock function with given fields: ctx, userID, bookID, input
+func (_m *Book) Update(ctx context.Context, userID int, bookID int, input bookshelf.UpdateBookInput) error {
+	ret := _m.Called(ctx, userID, bookID, input)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Update")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, int, bookshelf.UpdateBookInput) error); ok {
-		r0 = rf(userID, bookID, input)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.UpdateBookInput) error); ok {
+		r0 = rf(ctx, userID, bookID, input)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,65 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Cite is an autogenerated mock type for the Cite type
+type Cite struct {
+	mock.Mock
+}
+
+// CiteBook provides a mock function with given fields: ctx, userID, bookID, format, w
+func (_m *Cite) CiteBook(ctx context.Context, userID int, bookID int, format string, w io.Writer) error {
+	ret := _m.Called(ctx, userID, bookID, format, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CiteBook")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, io.Writer) error); ok {
+		r0 = rf(ctx, userID, bookID, format, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CiteList provides a mock function with given fields: ctx, userID, listID, format, w
+func (_m *Cite) CiteList(ctx context.Context, userID int, listID int, format string, w io.Writer) error {
+	ret := _m.Called(ctx, userID, listID, format, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CiteList")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, io.Writer) error); ok {
+		r0 = rf(ctx, userID, listID, format, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewCite creates a new instance of Cite. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCite(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Cite {
+	mock := &Cite{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
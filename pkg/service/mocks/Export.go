@@ -0,0 +1,65 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Export is an autogenerated mock type for the Export type
+type Export struct {
+	mock.Mock
+}
+
+// Export provides a mock function with given fields: ctx, userID, fn
+func (_m *Export) Export(ctx context.Context, userID int, fn func(bookshelf.ExportRow) error) error {
+	ret := _m.Called(ctx, userID, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Export")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(bookshelf.ExportRow) error) error); ok {
+		r0 = rf(ctx, userID, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportList provides a mock function with given fields: ctx, userID, listID, fn
+func (_m *Export) ExportList(ctx context.Context, userID int, listID int, fn func(bookshelf.ExportRow) error) error {
+	ret := _m.Called(ctx, userID, listID, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportList")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, func(bookshelf.ExportRow) error) error); ok {
+		r0 = rf(ctx, userID, listID, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewExport creates a new instance of Export. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewExport(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Export {
+	mock := &Export{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,115 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Import is an autogenerated mock type for the Import type
+type Import struct {
+	mock.Mock
+}
+
+// GetJob provides a mock function with given fields: ctx, userID, jobID
+func (_m *Import) GetJob(ctx context.Context, userID int, jobID string) (bookshelf.ImportJob, error) {
+	ret := _m.Called(ctx, userID, jobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetJob")
+	}
+
+	var r0 bookshelf.ImportJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) (bookshelf.ImportJob, error)); ok {
+		return rf(ctx, userID, jobID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) bookshelf.ImportJob); ok {
+		r0 = rf(ctx, userID, jobID)
+	} else {
+		r0 = ret.Get(0).(bookshelf.ImportJob)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
+		r1 = rf(ctx, userID, jobID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportGoodreads provides a mock function with given fields: ctx, userID, r
+func (_m *Import) ImportGoodreads(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+	ret := _m.Called(ctx, userID, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportGoodreads")
+	}
+
+	var r0 bookshelf.ImportJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) (bookshelf.ImportJob, error)); ok {
+		return rf(ctx, userID, r)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) bookshelf.ImportJob); ok {
+		r0 = rf(ctx, userID, r)
+	} else {
+		r0 = ret.Get(0).(bookshelf.ImportJob)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, io.Reader) error); ok {
+		r1 = rf(ctx, userID, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportJSON provides a mock function with given fields: ctx, userID, r
+func (_m *Import) ImportJSON(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error) {
+	ret := _m.Called(ctx, userID, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportJSON")
+	}
+
+	var r0 bookshelf.ImportJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) (bookshelf.ImportJob, error)); ok {
+		return rf(ctx, userID, r)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) bookshelf.ImportJob); ok {
+		r0 = rf(ctx, userID, r)
+	} else {
+		r0 = ret.Get(0).(bookshelf.ImportJob)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, io.Reader) error); ok {
+		r1 = rf(ctx, userID, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewImport creates a new instance of Import. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewImport(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Import {
+	mock := &Import{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
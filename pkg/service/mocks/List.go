@@ -4,6 +4,7 @@ package mocks
 
 import (
 	bookshelf "bookshelf-api"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,9 +14,27 @@ type List struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: userID, list
-func (_m *List) Create(userID int, list bookshelf.List) (int, error) {
-	ret := _m.Called(userID, list)
+// AddMember provides a mock function with given fields: ctx, userID, listID, input
+func (_m *List) AddMember(ctx context.Context, userID int, listID int, input bookshelf.AddListMemberInput) error {
+	ret := _m.Called(ctx, userID, listID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddMember")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.AddListMemberInput) error); ok {
+		r0 = rf(ctx, userID, listID, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: ctx, userID, list
+func (_m *List) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	ret := _m.Called(ctx, userID, list)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
@@ -23,17 +42,17 @@ func (_m *List) Create(userID int, list bookshelf.List) (int, error) {
 
 	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, bookshelf.List) (int, error)); ok {
-		return rf(userID, list)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.List) (int, error)); ok {
+		return rf(ctx, userID, list)
 	}
-	if rf, ok := ret.Get(0).(func(int, bookshelf.List) int); ok {
-		r0 = rf(userID, list)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.List) int); ok {
+		r0 = rf(ctx, userID, list)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(int, bookshelf.List) error); ok {
-		r1 = rf(userID, list)
+	if rf, ok := ret.Get(1).(func(context.Context, int, bookshelf.List) error); ok {
+		r1 = rf(ctx, userID, list)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,17 +60,17 @@ func (_m *List) Create(userID int, list bookshelf.List) (int, error) {
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: userID, listID
-func (_m *List) Delete(userID int, listID int) error {
-	ret := _m.Called(userID, listID)
+// Delete provides a mock function with given fields: ctx, userID, listID
+func (_m *List) Delete(ctx context.Context, userID int, listID int) error {
+	ret := _m.Called(ctx, userID, listID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, int) error); ok {
-		r0 = rf(userID, listID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, userID, listID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -59,39 +78,46 @@ func (_m *List) Delete(userID int, listID int) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: userID
-func (_m *List) GetAll(userID int) ([]bookshelf.List, error) {
-	ret := _m.Called(userID)
+// GetAll provides a mock function with given fields: ctx, userID, filter
+func (_m *List) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, string, error) {
+	ret := _m.Called(ctx, userID, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
 	}
 
 	var r0 []bookshelf.List
-	var r1 error
-	if rf, ok := ret.Get(0).(func(int) ([]bookshelf.List, error)); ok {
-		return rf(userID)
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.ListFilter) ([]bookshelf.List, string, error)); ok {
+		return rf(ctx, userID, filter)
 	}
-	if rf, ok := ret.Get(0).(func(int) []bookshelf.List); ok {
-		r0 = rf(userID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.ListFilter) []bookshelf.List); ok {
+		r0 = rf(ctx, userID, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]bookshelf.List)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int) error); ok {
-		r1 = rf(userID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, bookshelf.ListFilter) string); ok {
+		r1 = rf(ctx, userID, filter)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, int, bookshelf.ListFilter) error); ok {
+		r2 = rf(ctx, userID, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// GetByID provides a mock function with given fields: userID, listID
-func (_m *List) GetByID(userID int, listID int) (bookshelf.List, error) {
-	ret := _m.Called(userID, listID)
+// GetByID provides a mock function with given fields: ctx, userID, listID
+func (_m *List) GetByID(ctx context.Context, userID int, listID int) (bookshelf.List, error) {
+	ret := _m.Called(ctx, userID, listID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetByID")
@@ -99,17 +125,47 @@ func (_m *List) GetByID(userID int, listID int) (bookshelf.List, error) {
 
 	var r0 bookshelf.List
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int) (bookshelf.List, error)); ok {
-		return rf(userID, listID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (bookshelf.List, error)); ok {
+		return rf(ctx, userID, listID)
 	}
-	if rf, ok := ret.Get(0).(func(int, int) bookshelf.List); ok {
-		r0 = rf(userID, listID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) bookshelf.List); ok {
+		r0 = rf(ctx, userID, listID)
 	} else {
 		r0 = ret.Get(0).(bookshelf.List)
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int) error); ok {
-		r1 = rf(userID, listID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, listID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMembers provides a mock function with given fields: ctx, userID, listID
+func (_m *List) GetMembers(ctx context.Context, userID int, listID int) ([]bookshelf.ListMember, error) {
+	ret := _m.Called(ctx, userID, listID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMembers")
+	}
+
+	var r0 []bookshelf.ListMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]bookshelf.ListMember, error)); ok {
+		return rf(ctx, userID, listID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []bookshelf.ListMember); ok {
+		r0 = rf(ctx, userID, listID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.ListMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, listID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -117,17 +173,53 @@ func (_m *List) GetByID(userID int, listID int) (bookshelf.List, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: userID, listID, input
-func (_m *List) Update(userID int, listID int, input bookshelf.UpdateListInput) error {
-	ret := _m.Called(userID, listID, input)
+// RemoveMember provides a mock function with given fields: ctx, userID, listID, memberID
+func (_m *List) RemoveMember(ctx context.Context, userID int, listID int, memberID int) error {
+	ret := _m.Called(ctx, userID, listID, memberID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveMember")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, listID, memberID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, userID, listID, input
+func (_m *List) Update(ctx context.Context, userID int, listID int, input bookshelf.UpdateListInput) error {
+	ret := _m.Called(ctx, userID, listID, input)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Update")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, int, bookshelf.UpdateListInput) error); ok {
-		r0 = rf(userID, listID, input)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.UpdateListInput) error); ok {
+		r0 = rf(ctx, userID, listID, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateMember provides a mock function with given fields: ctx, userID, listID, memberID, input
+func (_m *List) UpdateMember(ctx context.Context, userID int, listID int, memberID int, input bookshelf.UpdateListMemberInput) error {
+	ret := _m.Called(ctx, userID, listID, memberID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateMember")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, bookshelf.UpdateListMemberInput) error); ok {
+		r0 = rf(ctx, userID, listID, memberID, input)
 	} else {
 		r0 = ret.Error(0)
 	}
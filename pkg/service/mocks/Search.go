@@ -0,0 +1,66 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Search is an autogenerated mock type for the Search type
+type Search struct {
+	mock.Mock
+}
+
+// Search provides a mock function with given fields: ctx, userID, query
+func (_m *Search) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, string, error) {
+	ret := _m.Called(ctx, userID, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 []bookshelf.SearchHit
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.SearchQuery) ([]bookshelf.SearchHit, string, error)); ok {
+		return rf(ctx, userID, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bookshelf.SearchQuery) []bookshelf.SearchHit); ok {
+		r0 = rf(ctx, userID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.SearchHit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bookshelf.SearchQuery) string); ok {
+		r1 = rf(ctx, userID, query)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, bookshelf.SearchQuery) error); ok {
+		r2 = rf(ctx, userID, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewSearch creates a new instance of Search. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSearch(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Search {
+	mock := &Search{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,149 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bookshelf "bookshelf-api"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Share is an autogenerated mock type for the Share type
+type Share struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, userID, listID, input
+func (_m *Share) Create(ctx context.Context, userID int, listID int, input bookshelf.CreateListShareInput) (bookshelf.ListShare, string, error) {
+	ret := _m.Called(ctx, userID, listID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 bookshelf.ListShare
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.CreateListShareInput) (bookshelf.ListShare, string, error)); ok {
+		return rf(ctx, userID, listID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, bookshelf.CreateListShareInput) bookshelf.ListShare); ok {
+		r0 = rf(ctx, userID, listID, input)
+	} else {
+		r0 = ret.Get(0).(bookshelf.ListShare)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, bookshelf.CreateListShareInput) string); ok {
+		r1 = rf(ctx, userID, listID, input)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, int, bookshelf.CreateListShareInput) error); ok {
+		r2 = rf(ctx, userID, listID, input)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetAll provides a mock function with given fields: ctx, userID, listID
+func (_m *Share) GetAll(ctx context.Context, userID int, listID int) ([]bookshelf.ListShare, error) {
+	ret := _m.Called(ctx, userID, listID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []bookshelf.ListShare
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]bookshelf.ListShare, error)); ok {
+		return rf(ctx, userID, listID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []bookshelf.ListShare); ok {
+		r0 = rf(ctx, userID, listID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bookshelf.ListShare)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, listID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Resolve provides a mock function with given fields: ctx, token
+func (_m *Share) Resolve(ctx context.Context, token string) (bookshelf.List, []bookshelf.Book, error) {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Resolve")
+	}
+
+	var r0 bookshelf.List
+	var r1 []bookshelf.Book
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bookshelf.List, []bookshelf.Book, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bookshelf.List); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Get(0).(bookshelf.List)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) []bookshelf.Book); ok {
+		r1 = rf(ctx, token)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]bookshelf.Book)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, token)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Revoke provides a mock function with given fields: ctx, userID, listID, shareID
+func (_m *Share) Revoke(ctx context.Context, userID int, listID int, shareID int) error {
+	ret := _m.Called(ctx, userID, listID, shareID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, userID, listID, shareID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewShare creates a new instance of Share. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewShare(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Share {
+	mock := &Share{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenLibraryProvider looks up books with the Open Library Books API.
+type OpenLibraryProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOpenLibraryProvider(baseURL string, timeout time.Duration) *OpenLibraryProvider {
+	return &OpenLibraryProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+type openLibraryBook struct {
+	Title         string            `json:"title"`
+	Authors       []openLibraryName `json:"authors"`
+	Publishers    []openLibraryName `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	NumberOfPages int               `json:"number_of_pages"`
+}
+
+// Publish dates are free text such as "1965" or "September 1, 1990".
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// Lookup fetches the book with the ISBN. Failures to reach Open Library are
+// reported as ErrMetadataFailed.
+func (p *OpenLibraryProvider) Lookup(ctx context.Context, isbn string) (bookshelf.Book, error) {
+	query := url.Values{
+		"bibkeys": {"ISBN:" + isbn},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return bookshelf.Book{}, fmt.Errorf("open library: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return bookshelf.Book{}, fmt.Errorf("%w: open library: %w", ErrMetadataFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return bookshelf.Book{}, fmt.Errorf("%w: open library: unexpected status %d", ErrMetadataFailed, resp.StatusCode)
+	}
+
+	var books map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+		return bookshelf.Book{}, fmt.Errorf("%w: open library: %w", ErrMetadataFailed, err)
+	}
+	data, ok := books["ISBN:"+isbn]
+	if !ok {
+		return bookshelf.Book{}, ErrMetadataNotFound
+	}
+
+	book := bookshelf.Book{
+		Title:     data.Title,
+		PageCount: data.NumberOfPages,
+		ISBN:      isbn,
+	}
+	names := make([]string, len(data.Authors))
+	for i, author := range data.Authors {
+		names[i] = author.Name
+	}
+	// parseAuthors reads a comma as "Family, Given", authors are separated by
+	// semicolons instead.
+	book.Author = strings.Join(names, "; ")
+	if len(data.Publishers) > 0 {
+		book.Publisher = data.Publishers[0].Name
+	}
+	if year := yearPattern.FindString(data.PublishDate); year != "" {
+		book.PublicationYear, _ = strconv.Atoi(year)
+	}
+	return book, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenLibraryProvider_Lookup(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/books", r.URL.Path)
+		assert.Equal(t, "data", r.URL.Query().Get("jscmd"))
+		switch r.URL.Query().Get("bibkeys") {
+		case "ISBN:9780441172719":
+			w.Write([]byte(`{"ISBN:9780441172719": {
+				"title": "Dune",
+				"authors": [{"name": "Frank Herbert"}],
+				"publishers": [{"name": "Ace Books"}, {"name": "Chilton"}],
+				"publish_date": "September 1, 1990",
+				"number_of_pages": 535
+			}}`))
+		case "ISBN:9780765312716":
+			w.Write([]byte(`{"ISBN:9780765312716": {
+				"title": "Hunters of Dune",
+				"authors": [{"name": "Brian Herbert"}, {"name": "Kevin J. Anderson"}]
+			}}`))
+		case "ISBN:9780306406157":
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOpenLibraryProvider(srv.URL+"/", time.Second)
+
+	book, err := p.Lookup(ctx, "9780441172719")
+	require.NoError(t, err)
+	assert.Equal(t, bookshelf.Book{
+		Title:           "Dune",
+		Author:          "Frank Herbert",
+		Publisher:       "Ace Books",
+		PublicationYear: 1990,
+		PageCount:       535,
+		ISBN:            "9780441172719",
+	}, book)
+
+	book, err = p.Lookup(ctx, "9780765312716")
+	require.NoError(t, err)
+	assert.Equal(t, "Brian Herbert; Kevin J. Anderson", book.Author)
+	assert.Equal(t, []citationName{
+		{Family: "Herbert", Given: "Brian"},
+		{Family: "Anderson", Given: "Kevin J."},
+	}, parseAuthors(book.Author))
+
+	_, err = p.Lookup(ctx, "9780306406157")
+	assert.ErrorIs(t, err, ErrMetadataNotFound)
+
+	_, err = p.Lookup(ctx, "9780547928227")
+	assert.ErrorIs(t, err, ErrMetadataFailed)
+	assert.ErrorIs(t, err, bookshelf.ErrUpstream)
+	assert.NotErrorIs(t, err, bookshelf.ErrNotFound)
+	assert.Equal(t, "metadata_unavailable", bookshelf.Code(err))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = p.Lookup(canceled, "9780441172719")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, ErrMetadataFailed)
+
+	down := NewOpenLibraryProvider(srv.URL, time.Second)
+	srv.Close()
+	_, err = down.Lookup(ctx, "9780441172719")
+	assert.ErrorIs(t, err, ErrMetadataFailed)
+	message, _ := bookshelf.Message(err)
+	assert.Equal(t, "metadata provider is unavailable", message)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"crypto/sha1"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// legacySalt is the static salt of the SHA-1 scheme used before bcrypt.
+// It is only kept to verify hashes that have not been upgraded yet.
+const legacySalt = "fjdnj36rfebhf51u3"
+
+// bcryptPrefix is the modular crypt format prefix of bcrypt hashes
+// ($2a$, $2b$, $2y$). Hashes without it are treated as legacy SHA-1.
+const bcryptPrefix = "$2"
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrPasswordTooLong is returned for passwords bcrypt cannot hash. The
+// handler limit counts characters, so multibyte passwords can still get here.
+var ErrPasswordTooLong = bookshelf.NewError(bookshelf.ErrValidation, "password_too_long", "password is longer than 72 bytes")
+
+func hashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrPasswordTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verifyPassword reports whether password matches hash and whether the hash
+// should be replaced with a fresh one using the given cost.
+func verifyPassword(hash, password string, cost int) (ok bool, rehash bool, err error) {
+	if !strings.HasPrefix(hash, bcryptPrefix) {
+		legacy := legacyPasswordHash(password)
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(legacy)) == 1, true, nil
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	hashCost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, err
+	}
+	return true, hashCost != cost, nil
+}
+
+func legacyPasswordHash(password string) string {
+	hash := sha1.New()
+	hash.Write([]byte(password))
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(legacySalt)))
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+)
+
+type SearchService struct {
+	storage storage.Search
+}
+
+func NewSearchService(storage storage.Search) *SearchService {
+	return &SearchService{storage: storage}
+}
+
+// Search returns a page of books and lists of the user matching the query,
+// best matches first, and the cursor of the next page.
+func (s *SearchService) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, string, error) {
+	if err := query.Validate(); err != nil {
+		return nil, "", err
+	}
+	if query.Limit == 0 {
+		query.Limit = bookshelf.DefaultPageLimit
+	}
+
+	limit := query.Limit
+	query.Limit++
+	hits, err := s.storage.Search(ctx, userID, query)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(hits) <= limit {
+		return hits, "", nil
+	}
+	hits = hits[:limit]
+	last := hits[limit-1]
+	cursor := bookshelf.Cursor{Value: last.Rank, Kind: last.Kind, ID: last.ID}
+	return hits, cursor.Encode(), nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// searchStorageStub returns the first query.Limit hits and records the
+// query it was called with.
+type searchStorageStub struct {
+	hits  []bookshelf.SearchHit
+	query bookshelf.SearchQuery
+}
+
+func (s *searchStorageStub) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, error) {
+	s.query = query
+	if len(s.hits) > query.Limit {
+		return s.hits[:query.Limit], nil
+	}
+	return s.hits, nil
+}
+
+func TestSearchService_Search(t *testing.T) {
+	hits := []bookshelf.SearchHit{
+		{Kind: bookshelf.SearchKindBook, ID: 1, Title: "Dune", Headline: "<mark>Dune</mark>", Rank: 1},
+		{Kind: bookshelf.SearchKindList, ID: 2, Title: "Dune saga", Headline: "<mark>Dune</mark> saga", Rank: 0.5},
+		{Kind: bookshelf.SearchKindBook, ID: 3, Title: "Dune Messiah", Headline: "<mark>Dune</mark> Messiah", Rank: 0.25},
+	}
+
+	tests := []struct {
+		name       string
+		query      bookshelf.SearchQuery
+		want       []bookshelf.SearchHit
+		wantLimit  int
+		wantCursor string
+		wantErr    error
+	}{
+		{
+			name:      "Default limit",
+			query:     bookshelf.SearchQuery{Query: "dune"},
+			want:      hits,
+			wantLimit: bookshelf.DefaultPageLimit + 1,
+		},
+		{
+			name:       "Next page",
+			query:      bookshelf.SearchQuery{Query: "dune", Limit: 2},
+			want:       hits[:2],
+			wantLimit:  3,
+			wantCursor: bookshelf.Cursor{Value: 0.5, Kind: bookshelf.SearchKindList, ID: 2}.Encode(),
+		},
+		{
+			name:      "Last page",
+			query:     bookshelf.SearchQuery{Query: "dune", Limit: 3},
+			want:      hits,
+			wantLimit: 4,
+		},
+		{
+			name:    "Empty query",
+			query:   bookshelf.SearchQuery{},
+			wantErr: bookshelf.ErrValidation,
+		},
+		{
+			name:    "Blank query",
+			query:   bookshelf.SearchQuery{Query: " \t "},
+			wantErr: bookshelf.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &searchStorageStub{hits: hits}
+			s := NewSearchService(stub)
+
+			got, cursor, err := s.Search(context.Background(), 1, tt.query)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCursor, cursor)
+			assert.Equal(t, tt.wantLimit, stub.query.Limit)
+		})
+	}
+}
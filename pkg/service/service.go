@@ -2,43 +2,133 @@ package service
 
 import (
 	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/config"
 	"bookshelf-api/pkg/storage"
+	"context"
+	"io"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Authorization
 type Authorization interface {
-	CreateUser(user bookshelf.User) (int, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (int, error)
+	CreateUser(ctx context.Context, user bookshelf.User) (int, error)
+	GenerateToken(ctx context.Context, username, password string) (bookshelf.TokenPair, error)
+	Authenticate(ctx context.Context, username, password string) (int, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (bookshelf.TokenPair, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+	ParseToken(ctx context.Context, token string) (int, error)
+	JWKS() bookshelf.JWKS
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=List
 type List interface {
-	Create(userID int, list bookshelf.List) (int, error)
-	GetAll(userID int) ([]bookshelf.List, error)
-	GetByID(userID, listID int) (bookshelf.List, error)
-	Update(userID, listID int, input bookshelf.UpdateListInput) error
-	Delete(userID, listID int) error
+	Create(ctx context.Context, userID int, list bookshelf.List) (int, error)
+	GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, string, error)
+	GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error)
+	Update(ctx context.Context, userID, listID int, input bookshelf.UpdateListInput) error
+	Delete(ctx context.Context, userID, listID int) error
+	GetMembers(ctx context.Context, userID, listID int) ([]bookshelf.ListMember, error)
+	AddMember(ctx context.Context, userID, listID int, input bookshelf.AddListMemberInput) error
+	UpdateMember(ctx context.Context, userID, listID, memberID int, input bookshelf.UpdateListMemberInput) error
+	RemoveMember(ctx context.Context, userID, listID, memberID int) error
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Book
 type Book interface {
-	Create(userID, listID int, book bookshelf.Book) (int, error)
-	GetAll(userID, listID int) ([]bookshelf.Book, error)
-	GetByID(userID, bookID int) (bookshelf.Book, error)
-	Update(userID, bookID int, input bookshelf.UpdateBookInput) error
-	Delete(userID, bookID int) error
+	Create(ctx context.Context, userID, listID int, book bookshelf.Book) (int, error)
+	GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, string, error)
+	GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error)
+	GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error)
+	Refresh(ctx context.Context, userID, bookID int) (bookshelf.Book, error)
+	Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error
+	Delete(ctx context.Context, userID, bookID int) error
+	Attach(ctx context.Context, userID, listID, bookID int) error
+	Detach(ctx context.Context, userID, listID, bookID int) error
+	Move(ctx context.Context, userID, fromListID, toListID, bookID int) error
+	Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error)
+	GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error)
+	Merge(ctx context.Context, userID int, input bookshelf.MergeBooksInput) (bookshelf.MergeResult, error)
 }
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=APIToken
+type APIToken interface {
+	Create(ctx context.Context, userID int, input bookshelf.CreateAPITokenInput) (bookshelf.APIToken, string, error)
+	GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error)
+	Delete(ctx context.Context, userID, tokenID int) error
+	Authenticate(ctx context.Context, token string) (bookshelf.APIToken, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Share
+type Share interface {
+	Create(ctx context.Context, userID, listID int, input bookshelf.CreateListShareInput) (bookshelf.ListShare, string, error)
+	GetAll(ctx context.Context, userID, listID int) ([]bookshelf.ListShare, error)
+	Revoke(ctx context.Context, userID, listID, shareID int) error
+	Resolve(ctx context.Context, token string) (bookshelf.List, []bookshelf.Book, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Search
+type Search interface {
+	Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, string, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Import
+type Import interface {
+	ImportGoodreads(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error)
+	ImportJSON(ctx context.Context, userID int, r io.Reader) (bookshelf.ImportJob, error)
+	GetJob(ctx context.Context, userID int, jobID string) (bookshelf.ImportJob, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Export
+type Export interface {
+	Export(ctx context.Context, userID int, fn func(bookshelf.ExportRow) error) error
+	ExportList(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Cite
+type Cite interface {
+	CiteBook(ctx context.Context, userID, bookID int, format string, w io.Writer) error
+	CiteList(ctx context.Context, userID, listID int, format string, w io.Writer) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.0 --name=Backup
+type Backup interface {
+	Create(ctx context.Context, userID int, w io.Writer) error
+	Restore(ctx context.Context, userID int, archive []byte, members bool) (bookshelf.RestoreResult, error)
+}
+
 type Service struct {
 	Authorization
 	List
 	Book
+	APIToken
+	Share
+	Search
+	Import
+	Export
+	Cite
+	Backup
 }
 
-func New(storage *storage.Storage) *Service {
-	return &Service{
-		Authorization: NewAuthService(storage.Authorization),
-		List:          NewListService(storage.List),
-		Book:          NewBookService(storage.Book, storage.List),
+func New(storage *storage.Storage, cfg config.Config) (*Service, error) {
+	auth, err := NewAuthService(storage.Authorization, cfg.Auth)
+	if err != nil {
+		return nil, err
 	}
+	metadata, err := NewMetadataProvider(cfg.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	book := NewBookService(storage.Book, storage.List, metadata)
+	export := NewExportService(storage.List)
+	return &Service{
+		Authorization: auth,
+		List:          NewListService(storage.List, storage.Authorization),
+		Book:          book,
+		APIToken:      NewAPITokenService(storage.APIToken),
+		Share:         NewShareService(storage.Share, storage.List, storage.Book),
+		Search:        NewSearchService(storage.Search),
+		Import:        NewImportService(storage.List, book),
+		Export:        export,
+		Cite:          NewCiteService(book, export),
+		Backup:        NewBackupService(storage.Backup),
+	}, nil
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"errors"
+	"time"
+)
+
+type ShareService struct {
+	storage     storage.Share
+	listStorage storage.List
+	bookStorage storage.Book
+}
+
+func NewShareService(storage storage.Share, listStorage storage.List, bookStorage storage.Book) *ShareService {
+	return &ShareService{
+		storage:     storage,
+		listStorage: listStorage,
+		bookStorage: bookStorage,
+	}
+}
+
+func (s *ShareService) Create(ctx context.Context, userID, listID int, input bookshelf.CreateListShareInput) (bookshelf.ListShare, string, error) {
+	if err := s.requireOwner(ctx, userID, listID); err != nil {
+		return bookshelf.ListShare{}, "", err
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		return bookshelf.ListShare{}, "", bookshelf.NewError(bookshelf.ErrValidation, "invalid_expiration", "expiration time is in the past")
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return bookshelf.ListShare{}, "", err
+	}
+	share := bookshelf.ListShare{
+		ListID:    listID,
+		CreatedBy: userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	share.ID, err = s.storage.Create(ctx, share)
+	if err != nil {
+		return bookshelf.ListShare{}, "", err
+	}
+	return share, token, nil
+}
+
+func (s *ShareService) GetAll(ctx context.Context, userID, listID int) ([]bookshelf.ListShare, error) {
+	if err := s.requireOwner(ctx, userID, listID); err != nil {
+		return nil, err
+	}
+	return s.storage.GetAll(ctx, listID)
+}
+
+func (s *ShareService) Revoke(ctx context.Context, userID, listID, shareID int) error {
+	if err := s.requireOwner(ctx, userID, listID); err != nil {
+		return err
+	}
+	return s.storage.Revoke(ctx, listID, shareID)
+}
+
+// Resolve returns the shared list and its books. The list is read on behalf
+// of the user who created the link, so the link stops working once they
+// lose access to the list.
+func (s *ShareService) Resolve(ctx context.Context, token string) (bookshelf.List, []bookshelf.Book, error) {
+	share, err := s.storage.GetByHash(ctx, hashToken(token))
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return bookshelf.List{}, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return bookshelf.List{}, nil, err
+	}
+	if share.Revoked || (share.ExpiresAt != nil && share.ExpiresAt.Before(time.Now())) {
+		return bookshelf.List{}, nil, ErrInvalidToken
+	}
+
+	list, err := s.listStorage.GetByID(ctx, share.CreatedBy, share.ListID)
+	if errors.Is(err, bookshelf.ErrNotFound) {
+		return bookshelf.List{}, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return bookshelf.List{}, nil, err
+	}
+	books, err := s.bookStorage.GetAll(ctx, share.CreatedBy, share.ListID, bookshelf.BookFilter{})
+	if err != nil {
+		return bookshelf.List{}, nil, err
+	}
+	return list, books, nil
+}
+
+func (s *ShareService) requireOwner(ctx context.Context, userID, listID int) error {
+	role, err := s.listStorage.GetRole(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	if !bookshelf.HasRole(role, bookshelf.RoleOwner) {
+		return bookshelf.ErrForbidden
+	}
+	return nil
+}
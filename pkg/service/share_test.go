@@ -0,0 +1,181 @@
+package service
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type shareStorageStub struct {
+	shares map[int]bookshelf.ListShare
+}
+
+func (s *shareStorageStub) Create(ctx context.Context, share bookshelf.ListShare) (int, error) {
+	share.ID = len(s.shares) + 1
+	s.shares[share.ID] = share
+	return share.ID, nil
+}
+
+func (s *shareStorageStub) GetAll(ctx context.Context, listID int) ([]bookshelf.ListShare, error) {
+	var shares []bookshelf.ListShare
+	for id := 1; id <= len(s.shares); id++ {
+		if share := s.shares[id]; share.ListID == listID && !share.Revoked {
+			shares = append(shares, share)
+		}
+	}
+	return shares, nil
+}
+
+func (s *shareStorageStub) GetByHash(ctx context.Context, tokenHash string) (bookshelf.ListShare, error) {
+	for _, share := range s.shares {
+		if share.TokenHash == tokenHash {
+			return share, nil
+		}
+	}
+	return bookshelf.ListShare{}, bookshelf.ErrNotFound
+}
+
+func (s *shareStorageStub) Revoke(ctx context.Context, listID, shareID int) error {
+	share, ok := s.shares[shareID]
+	if !ok || share.ListID != listID || share.Revoked {
+		return bookshelf.ErrNotFound
+	}
+	share.Revoked = true
+	s.shares[shareID] = share
+	return nil
+}
+
+// shareListStorageStub lets the members of memberStorageStub read list 1.
+type shareListStorageStub struct {
+	*memberStorageStub
+}
+
+func (s shareListStorageStub) GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error) {
+	if _, ok := s.roles[userID]; !ok || listID != 1 {
+		return bookshelf.List{}, bookshelf.ErrNotFound
+	}
+	return bookshelf.List{ID: 1, Title: "Sci-fi"}, nil
+}
+
+func newShareService() (*ShareService, *shareStorageStub, *memberStorageStub) {
+	shares := &shareStorageStub{shares: map[int]bookshelf.ListShare{}}
+	members := newMemberStorageStub()
+	books := &bookStorageStub{books: []bookshelf.Book{{ID: 1, Title: "Dune"}}}
+	return NewShareService(shares, shareListStorageStub{members}, books), shares, members
+}
+
+func TestShareService_Create(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		userID  int
+		input   bookshelf.CreateListShareInput
+		wantErr error
+	}{
+		{name: "OK", userID: alice},
+		{name: "Editor", userID: bob, wantErr: bookshelf.ErrForbidden},
+		{name: "Not a member", userID: dave, wantErr: bookshelf.ErrNotFound},
+		{name: "Expired", userID: alice, input: bookshelf.CreateListShareInput{ExpiresAt: &past}, wantErr: bookshelf.ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, shares, _ := newShareService()
+
+			share, token, err := s.Create(context.Background(), tt.userID, 1, tt.input)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, shares.shares)
+				return
+			}
+			assert.NotEmpty(t, token)
+			assert.Equal(t, hashToken(token), shares.shares[share.ID].TokenHash)
+			assert.Equal(t, alice, shares.shares[share.ID].CreatedBy)
+		})
+	}
+}
+
+func TestShareService_GetAll(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newShareService()
+	share, _, err := s.Create(ctx, alice, 1, bookshelf.CreateListShareInput{})
+	require.NoError(t, err)
+
+	got, err := s.GetAll(ctx, alice, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.ListShare{share}, got)
+
+	_, err = s.GetAll(ctx, carol, 1)
+	assert.ErrorIs(t, err, bookshelf.ErrForbidden)
+}
+
+func TestShareService_Revoke(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newShareService()
+	share, token, err := s.Create(ctx, alice, 1, bookshelf.CreateListShareInput{})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.Revoke(ctx, bob, 1, share.ID), bookshelf.ErrForbidden)
+	_, _, err = s.Resolve(ctx, token)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Revoke(ctx, alice, 1, share.ID))
+	_, _, err = s.Resolve(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorIs(t, s.Revoke(ctx, alice, 1, share.ID), bookshelf.ErrNotFound)
+}
+
+func TestShareService_Resolve(t *testing.T) {
+	ctx := context.Background()
+	soon := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		change  func(shares *shareStorageStub, members *memberStorageStub)
+		token   string
+		wantErr error
+	}{
+		{name: "OK"},
+		{name: "Unknown token", token: "unknown", wantErr: ErrInvalidToken},
+		{
+			name: "Expired",
+			change: func(shares *shareStorageStub, members *memberStorageStub) {
+				share := shares.shares[1]
+				past := time.Now().Add(-time.Minute)
+				share.ExpiresAt = &past
+				shares.shares[1] = share
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "Creator left the list",
+			change: func(shares *shareStorageStub, members *memberStorageStub) {
+				delete(members.roles, alice)
+			},
+			wantErr: ErrInvalidToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, shares, members := newShareService()
+			_, token, err := s.Create(ctx, alice, 1, bookshelf.CreateListShareInput{ExpiresAt: &soon})
+			require.NoError(t, err)
+			if tt.change != nil {
+				tt.change(shares, members)
+			}
+			if tt.token != "" {
+				token = tt.token
+			}
+
+			list, books, err := s.Resolve(ctx, token)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, bookshelf.List{ID: 1, Title: "Sci-fi"}, list)
+				assert.Equal(t, []bookshelf.Book{{ID: 1, Title: "Dune"}}, books)
+			}
+		})
+	}
+}
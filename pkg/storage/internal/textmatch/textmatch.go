@@ -0,0 +1,118 @@
+// Package textmatch holds the text matching the storages without Postgres
+// need to behave like pg_trgm, websearch_to_tsquery and the duplicate key
+// of the Postgres storage, and the escaping of search headlines shared by
+// all storages.
+package textmatch
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var nonAlnum = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Tokenize splits s into lowercase words of letters and digits, like the
+// simple text search configuration.
+func Tokenize(s string) []string {
+	return strings.Fields(nonAlnum.ReplaceAllString(strings.ToLower(s), " "))
+}
+
+// DuplicateKey normalizes title and author by case, punctuation and spacing,
+// like the duplicate key of the Postgres storage.
+func DuplicateKey(title, author string) string {
+	return strings.Join(Tokenize(title), " ") + " / " + strings.Join(Tokenize(author), " ")
+}
+
+// Term is a word or a quoted phrase of a query.
+type Term struct {
+	Words   []string
+	Exclude bool
+}
+
+// Query is a parsed web search query: it matches when all terms of any of
+// the groups match.
+type Query [][]Term
+
+// ParseWebSearch parses the websearch_to_tsquery syntax: words and quoted
+// phrases must all match, OR separates alternatives and a leading - excludes
+// a word or phrase.
+func ParseWebSearch(query string) Query {
+	q := Query{nil}
+	for query != "" {
+		query = strings.TrimLeft(query, " \t\n")
+		exclude := strings.HasPrefix(query, "-")
+		if exclude {
+			query = query[1:]
+		}
+		var text string
+		if strings.HasPrefix(query, `"`) {
+			text, query, _ = strings.Cut(query[1:], `"`)
+		} else {
+			i := strings.IndexAny(query, " \t\n\"")
+			if i < 0 {
+				i = len(query)
+			}
+			text, query = query[:i], query[i:]
+		}
+		words := Tokenize(text)
+		switch {
+		case len(words) == 0:
+		case !exclude && len(words) == 1 && words[0] == "or" && len(q[len(q)-1]) > 0:
+			q = append(q, nil)
+		default:
+			q[len(q)-1] = append(q[len(q)-1], Term{Words: words, Exclude: exclude})
+		}
+	}
+	return q
+}
+
+// Words returns the words of the query that must or may occur in a match.
+func (q Query) Words() map[string]bool {
+	words := make(map[string]bool)
+	for _, group := range q {
+		for _, term := range group {
+			for _, word := range term.Words {
+				words[word] = !term.Exclude
+			}
+		}
+	}
+	return words
+}
+
+// Mark wraps the words of text that are in words in mark elements, like
+// ts_headline. The rest of text is HTML escaped.
+func Mark(text string, words map[string]bool) string {
+	var b strings.Builder
+	for len(text) > 0 {
+		loc := nonAlnum.FindStringIndex(text)
+		if loc == nil {
+			loc = []int{len(text), len(text)}
+		}
+		if word := text[:loc[0]]; words[strings.ToLower(word)] {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		text = text[loc[1]:]
+	}
+	return b.String()
+}
+
+// StartMark and StopMark delimit the matched words in the headlines built by
+// the databases. Control characters are used instead of mark elements, so
+// the text can be escaped afterwards, see Headline.
+const (
+	StartMark = "\x02"
+	StopMark  = "\x03"
+)
+
+var headlineReplacer = strings.NewReplacer(StartMark, "<mark>", StopMark, "</mark>")
+
+// Headline HTML-escapes a headline delimited by StartMark and StopMark and
+// turns the delimiters into mark elements. The titles and descriptions are
+// written by users, so they must not reach a client as markup.
+func Headline(marked string) string {
+	return headlineReplacer.Replace(html.EscapeString(marked))
+}
@@ -0,0 +1,56 @@
+package textmatch
+
+import (
+	"strings"
+)
+
+// SuggestThreshold is the lowest similarity of a suggestion. It is below the
+// 0.6 pg_trgm uses by default, as WordSimilarity scores typos a little lower.
+const SuggestThreshold = 0.5
+
+// trigrams returns the trigrams of the words of s the way pg_trgm builds
+// them: lowercased, every word padded with two spaces in front and one
+// behind.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range Tokenize(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// similarity is the share of trigrams a and b have in common, as in
+// pg_trgm's similarity.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// WordSimilarity approximates pg_trgm's word_similarity: the best similarity
+// between query and any run of consecutive words of text. pg_trgm also
+// considers runs starting or ending inside a word, so it can score a little
+// higher.
+func WordSimilarity(query, text string) float64 {
+	q := trigrams(query)
+	words := Tokenize(text)
+	// Runs much longer than the query only add trigrams it lacks.
+	span := len(Tokenize(query)) + 1
+	best := 0.0
+	for i := range words {
+		for j := i + 1; j <= len(words) && j-i <= span; j++ {
+			best = max(best, similarity(q, trigrams(strings.Join(words[i:j], " "))))
+		}
+	}
+	return best
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"slices"
+	"time"
+)
+
+type APITokenMemory struct {
+	db *DB
+}
+
+func NewAPITokenMemory(db *DB) *APITokenMemory {
+	return &APITokenMemory{db: db}
+}
+
+func (s *APITokenMemory) Create(ctx context.Context, token bookshelf.APIToken) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err := checkLength("name", token.Name, 255); err != nil {
+		return 0, err
+	}
+	if _, ok := s.db.users[token.UserID]; !ok {
+		return 0, notFound("user")
+	}
+	for _, t := range s.db.apiTokens {
+		if t.TokenHash == token.TokenHash {
+			return 0, conflict("api token")
+		}
+	}
+	token.ID = s.db.nextID("api_tokens")
+	token.Scopes = slices.Clone(token.Scopes)
+	token.LastUsedAt = nil
+	token.CreatedAt = time.Now()
+	s.db.apiTokens[token.ID] = token
+	return token.ID, nil
+}
+
+func (s *APITokenMemory) GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var tokens []bookshelf.APIToken
+	for _, token := range s.db.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, copyAPIToken(token))
+		}
+	}
+	slices.SortFunc(tokens, func(a, b bookshelf.APIToken) int {
+		return a.ID - b.ID
+	})
+	return tokens, nil
+}
+
+func (s *APITokenMemory) GetByHash(ctx context.Context, tokenHash string) (bookshelf.APIToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, token := range s.db.apiTokens {
+		if token.TokenHash == tokenHash {
+			return copyAPIToken(token), nil
+		}
+	}
+	return bookshelf.APIToken{}, notFound("api token")
+}
+
+func (s *APITokenMemory) Touch(ctx context.Context, tokenID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if token, ok := s.db.apiTokens[tokenID]; ok {
+		now := time.Now()
+		token.LastUsedAt = &now
+		s.db.apiTokens[tokenID] = token
+	}
+	return nil
+}
+
+func (s *APITokenMemory) Delete(ctx context.Context, userID, tokenID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if token, ok := s.db.apiTokens[tokenID]; !ok || token.UserID != userID {
+		return notFound("api token")
+	}
+	delete(s.db.apiTokens, tokenID)
+	return nil
+}
+
+// copyAPIToken copies the scopes, so callers cannot change the stored token.
+func copyAPIToken(token bookshelf.APIToken) bookshelf.APIToken {
+	token.Scopes = slices.Clone(token.Scopes)
+	return token
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"time"
+)
+
+type AuthMemory struct {
+	db *DB
+}
+
+func NewAuthMemory(db *DB) *AuthMemory {
+	return &AuthMemory{db: db}
+}
+
+func (s *AuthMemory) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err := checkLength("username", user.Username, 255); err != nil {
+		return 0, err
+	}
+	for _, u := range s.db.users {
+		if u.Username == user.Username {
+			return 0, conflict("username")
+		}
+	}
+	user.ID = s.db.nextID("users")
+	s.db.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (s *AuthMemory) GetUser(ctx context.Context, username string) (bookshelf.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, user := range s.db.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return bookshelf.User{}, notFound("user")
+}
+
+func (s *AuthMemory) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if user, ok := s.db.users[userID]; ok {
+		user.Password = hash
+		s.db.users[userID] = user
+	}
+	return nil
+}
+
+func (s *AuthMemory) CreateRefreshToken(ctx context.Context, token bookshelf.RefreshToken) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[token.UserID]; !ok {
+		return notFound("user")
+	}
+	for _, t := range s.db.refreshTokens {
+		if t.TokenHash == token.TokenHash {
+			return conflict("refresh token")
+		}
+	}
+	token.ID = s.db.nextID("refresh_tokens")
+	token.Used, token.Revoked = false, false
+	s.db.refreshTokens[token.ID] = token
+	return nil
+}
+
+func (s *AuthMemory) GetRefreshToken(ctx context.Context, tokenHash string) (bookshelf.RefreshToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, token := range s.db.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return bookshelf.RefreshToken{}, notFound("refresh token")
+}
+
+// UseRefreshToken marks the token as used and reports whether it was still
+// unused. Concurrent attempts to use the same token get false.
+func (s *AuthMemory) UseRefreshToken(ctx context.Context, id int) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	token, ok := s.db.refreshTokens[id]
+	if !ok || token.Used || token.Revoked {
+		return false, nil
+	}
+	token.Used = true
+	s.db.refreshTokens[id] = token
+	return true, nil
+}
+
+func (s *AuthMemory) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for id, token := range s.db.refreshTokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			s.db.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *AuthMemory) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.revokedTokens[jti]; !ok {
+		s.db.revokedTokens[jti] = expiresAt
+	}
+	now := time.Now()
+	for id, at := range s.db.revokedTokens {
+		if at.Before(now) {
+			delete(s.db.revokedTokens, id)
+		}
+	}
+	return nil
+}
+
+func (s *AuthMemory) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	_, ok := s.db.revokedTokens[jti]
+	return ok, nil
+}
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type BackupMemory struct {
+	db *DB
+}
+
+func NewBackupMemory(db *DB) *BackupMemory {
+	return &BackupMemory{db: db}
+}
+
+// Dump reads the lists of the user, their books and members.
+func (s *BackupMemory) Dump(ctx context.Context, userID int) (bookshelf.Backup, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	backup := bookshelf.Backup{
+		Lists:   []bookshelf.BackupList{},
+		Books:   []bookshelf.Book{},
+		Entries: []bookshelf.BackupEntry{},
+		Members: []bookshelf.BackupMember{},
+	}
+	listIDs := s.db.listIDs(userID)
+	for _, id := range listIDs {
+		backup.Lists = append(backup.Lists, bookshelf.BackupList{List: s.db.lists[id], Role: s.db.role(userID, id)})
+	}
+
+	for id := range s.db.accessibleBooks(userID) {
+		backup.Books = append(backup.Books, public(s.db.books[id]))
+	}
+	sortBooks(backup.Books)
+
+	for _, id := range listIDs {
+		for _, book := range s.db.listBooks(id) {
+			backup.Entries = append(backup.Entries, bookshelf.BackupEntry{ListID: id, BookID: book.ID})
+		}
+		var members []bookshelf.BackupMember
+		for m, role := range s.db.members {
+			if m.ListID == id && m.UserID != userID {
+				members = append(members, bookshelf.BackupMember{ListID: id, Username: s.db.users[m.UserID].Username, Role: role})
+			}
+		}
+		slices.SortFunc(members, func(a, b bookshelf.BackupMember) int {
+			return strings.Compare(a.Username, b.Username)
+		})
+		backup.Members = append(backup.Members, members...)
+	}
+	return backup, nil
+}
+
+// Restore recreates the backup for the user. Lists the user can edit are
+// reused by title and books of the user by ISBN. Members are only added to
+// lists created by the restore, merging must not grant access to lists the
+// user does not own. A failed restore leaves no changes behind.
+func (s *BackupMemory) Restore(ctx context.Context, userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	rollback := s.db.snapshot()
+	result, err := s.db.restore(userID, backup)
+	if err != nil {
+		rollback()
+		return bookshelf.RestoreResult{}, err
+	}
+	return result, nil
+}
+
+func (db *DB) restore(userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error) {
+	result := bookshelf.RestoreResult{
+		ListIDs:        make(map[int]int, len(backup.Lists)),
+		BookIDs:        make(map[int]int, len(backup.Books)),
+		SkippedMembers: []string{},
+	}
+	if _, ok := db.users[userID]; !ok {
+		return bookshelf.RestoreResult{}, notFound("user")
+	}
+
+	titles := make(map[string]int)
+	for _, id := range db.listIDs(userID) {
+		title := strings.ToLower(db.lists[id].Title)
+		if _, ok := titles[title]; !ok && bookshelf.HasRole(db.role(userID, id), bookshelf.RoleEditor) {
+			titles[title] = id
+		}
+	}
+
+	created := make(map[int]bool)
+	for _, list := range backup.Lists {
+		if id, ok := titles[strings.ToLower(list.Title)]; ok {
+			result.ListIDs[list.ID] = id
+			continue
+		}
+		if err := checkList(list.List); err != nil {
+			return bookshelf.RestoreResult{}, err
+		}
+		id := db.nextID("lists")
+		db.lists[id] = bookshelf.List{ID: id, Title: list.Title, Description: list.Description}
+		db.members[membership{UserID: userID, ListID: id}] = bookshelf.RoleOwner
+		titles[strings.ToLower(list.Title)] = id
+		result.ListIDs[list.ID] = id
+		created[id] = true
+		result.ListsCreated++
+	}
+
+	for _, book := range backup.Books {
+		id := 0
+		if book.ISBN != "" {
+			for _, b := range db.books {
+				if b.UserID == userID && b.ISBN == book.ISBN {
+					id = b.ID
+					break
+				}
+			}
+		}
+		if id == 0 {
+			if err := checkBook(book); err != nil {
+				return bookshelf.RestoreResult{}, err
+			}
+			id = db.nextID("books")
+			book.ID, book.UserID = id, userID
+			db.books[id] = book
+			result.BooksCreated++
+		}
+		result.BookIDs[book.ID] = id
+	}
+
+	for _, e := range backup.Entries {
+		listID, bookID := result.ListIDs[e.ListID], result.BookIDs[e.BookID]
+		if db.entries[entry{ListID: listID, BookID: bookID}] {
+			continue
+		}
+		if err := db.attach(listID, bookID); err != nil {
+			return bookshelf.RestoreResult{}, err
+		}
+		result.Entries++
+	}
+
+	for _, member := range backup.Members {
+		listID := result.ListIDs[member.ListID]
+		if !created[listID] {
+			continue
+		}
+		memberID := 0
+		for _, user := range db.users {
+			if user.Username == member.Username {
+				memberID = user.ID
+				break
+			}
+		}
+		if memberID == 0 {
+			result.SkippedMembers = append(result.SkippedMembers, member.Username)
+			continue
+		}
+		if memberID == userID {
+			continue
+		}
+		if err := checkRole(member.Role); err != nil {
+			return bookshelf.RestoreResult{}, err
+		}
+		m := membership{UserID: memberID, ListID: listID}
+		if _, ok := db.members[m]; !ok {
+			db.members[m] = member.Role
+		}
+		result.Members++
+	}
+	return result, nil
+}
+
+// snapshot copies the tables a restore writes to and returns a function that
+// puts the copies back.
+func (db *DB) snapshot() func() {
+	lists, members := maps.Clone(db.lists), maps.Clone(db.members)
+	books, entries := maps.Clone(db.books), maps.Clone(db.entries)
+	seq := maps.Clone(db.seq)
+	return func() {
+		db.lists, db.members = lists, members
+		db.books, db.entries = books, entries
+		db.seq = seq
+	}
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBackupMemory_Restore(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, _ := seed(t)
+	require.NoError(t, NewListMemory(db).AddMember(ctx, listID, other, bookshelf.RoleViewer))
+	backups := NewBackupMemory(db)
+	backup, err := backups.Dump(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, backup.Members, 1)
+
+	target := New()
+	auth := NewAuthMemory(target)
+	userID, err := auth.CreateUser(ctx, bookshelf.User{Username: "restored"})
+	require.NoError(t, err)
+	otherID, err := auth.CreateUser(ctx, bookshelf.User{Username: "other"})
+	require.NoError(t, err)
+
+	result, err := NewBackupMemory(target).Restore(ctx, userID, backup)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ListsCreated)
+	assert.Equal(t, 1, result.BooksCreated)
+	assert.Equal(t, 1, result.Entries)
+	assert.Equal(t, 1, result.Members)
+	role, err := NewListMemory(target).GetRole(ctx, otherID, result.ListIDs[listID])
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.RoleViewer, role)
+
+	restored, err := NewBackupMemory(target).Dump(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, backup.Books[0].Title, restored.Books[0].Title)
+}
+
+func TestBackupMemory_RestoreRollback(t *testing.T) {
+	ctx := context.Background()
+	db, owner, _, _, _ := seed(t)
+	backups := NewBackupMemory(db)
+	backup := bookshelf.Backup{
+		Lists:   []bookshelf.BackupList{{List: bookshelf.List{ID: 1, Title: "New"}, Role: bookshelf.RoleOwner}},
+		Entries: []bookshelf.BackupEntry{{ListID: 1, BookID: 5}},
+	}
+
+	_, err := backups.Restore(ctx, owner, backup)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	lists, err := NewListMemory(db).GetAll(ctx, owner, bookshelf.ListFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, lists, 1)
+}
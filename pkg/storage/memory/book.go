@@ -0,0 +1,357 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+type BookMemory struct {
+	db *DB
+}
+
+func NewBookMemory(db *DB) *BookMemory {
+	return &BookMemory{db: db}
+}
+
+func (s *BookMemory) Create(ctx context.Context, listID int, book bookshelf.Book) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err := checkBook(book); err != nil {
+		return 0, err
+	}
+	if _, ok := s.db.lists[listID]; !ok {
+		return 0, notFound("list")
+	}
+	if _, ok := s.db.users[book.UserID]; !ok && book.UserID != 0 {
+		return 0, notFound("user")
+	}
+	if s.db.isbnTaken(book.UserID, 0, book.ISBN) {
+		return 0, conflict("isbn")
+	}
+	book.ID = s.db.nextID("books")
+	s.db.books[book.ID] = book
+	s.db.entries[entry{ListID: listID, BookID: book.ID}] = true
+	return book.ID, nil
+}
+
+func (s *BookMemory) GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if s.db.role(userID, listID) == "" {
+		return nil, nil
+	}
+	var books []bookshelf.Book
+	for _, book := range s.db.listBooks(listID) {
+		if filter.Author != "" && !strings.EqualFold(book.Author, filter.Author) {
+			continue
+		}
+		if filter.Publisher != "" && !strings.EqualFold(book.Publisher, filter.Publisher) {
+			continue
+		}
+		if filter.YearFrom != nil && book.PublicationYear < *filter.YearFrom {
+			continue
+		}
+		if filter.YearTo != nil && book.PublicationYear > *filter.YearTo {
+			continue
+		}
+		books = append(books, public(book))
+	}
+
+	var key func(bookshelf.Book) any
+	field, desc := bookshelf.SortField(filter.Sort)
+	switch field {
+	case "title":
+		key = func(b bookshelf.Book) any { return b.Title }
+	case "author":
+		key = func(b bookshelf.Book) any { return b.Author }
+	case "publication_year":
+		key = func(b bookshelf.Book) any { return b.PublicationYear }
+	case "page_count":
+		key = func(b bookshelf.Book) any { return b.PageCount }
+	}
+	return page(books, func(b bookshelf.Book) int { return b.ID }, key, desc, filter.After, filter.Limit), nil
+}
+
+func (s *BookMemory) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if !s.db.canRead(userID, bookID) {
+		return bookshelf.Book{}, notFound("book")
+	}
+	return public(s.db.books[bookID]), nil
+}
+
+// GetByISBN returns a book of the user with the ISBN. Books the user created
+// come before books shared with the user.
+func (s *BookMemory) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var found []bookshelf.Book
+	for id := range s.db.accessibleBooks(userID) {
+		if book := s.db.books[id]; book.ISBN != "" && book.ISBN == isbn {
+			found = append(found, book)
+		}
+	}
+	if len(found) == 0 {
+		return bookshelf.Book{}, notFound("book")
+	}
+	slices.SortFunc(found, func(a, b bookshelf.Book) int {
+		if own := cmp.Compare(owner(b, userID), owner(a, userID)); own != 0 {
+			return own
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return public(found[0]), nil
+}
+
+// Update changes the book if the user can edit every list containing it. The
+// book is shared by all of its lists, so an editor of one list must not
+// change what the members of another list see.
+func (s *BookMemory) Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if !bookshelf.HasRole(s.db.bookRole(userID, bookID), bookshelf.RoleEditor) {
+		return notFound("book")
+	}
+	book := s.db.books[bookID]
+	if input.Title != nil {
+		book.Title = *input.Title
+	}
+	if input.Author != nil {
+		book.Author = *input.Author
+	}
+	if input.Publisher != nil {
+		book.Publisher = *input.Publisher
+	}
+	if input.PublicationYear != nil {
+		book.PublicationYear = *input.PublicationYear
+	}
+	if input.PageCount != nil {
+		book.PageCount = *input.PageCount
+	}
+	if input.ISBN != nil {
+		book.ISBN = *input.ISBN
+	}
+	if err := checkBook(book); err != nil {
+		return err
+	}
+	if s.db.isbnTaken(book.UserID, bookID, book.ISBN) {
+		return conflict("isbn")
+	}
+	s.db.books[bookID] = book
+	return nil
+}
+
+// Delete removes the book from every list the user can edit. The book itself
+// is deleted once it is no longer part of any list, so lists of other users
+// keep their copy.
+func (s *BookMemory) Delete(ctx context.Context, userID, bookID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	deleted := false
+	for e := range s.db.entries {
+		if e.BookID == bookID && bookshelf.HasRole(s.db.role(userID, e.ListID), bookshelf.RoleEditor) {
+			delete(s.db.entries, e)
+			deleted = true
+		}
+	}
+	if !deleted {
+		return notFound("book")
+	}
+	s.db.deleteOrphan(bookID)
+	return nil
+}
+
+// Attach adds an existing book to a list. Attaching a book that is already
+// in the list is a no-op.
+func (s *BookMemory) Attach(ctx context.Context, listID, bookID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.attach(listID, bookID)
+}
+
+// Detach removes a book from a list without deleting the book. It fails with
+// bookshelf.ErrConflict if the list is the last one holding the book.
+func (s *BookMemory) Detach(ctx context.Context, listID, bookID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	e := entry{ListID: listID, BookID: bookID}
+	if !s.db.entries[e] {
+		return notFound("list entry")
+	}
+	if s.db.countLists(bookID) == 1 {
+		return bookshelf.ErrConflict
+	}
+	delete(s.db.entries, e)
+	return nil
+}
+
+func (s *BookMemory) Move(ctx context.Context, fromListID, toListID, bookID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	from := entry{ListID: fromListID, BookID: bookID}
+	if !s.db.entries[from] {
+		return notFound("list entry")
+	}
+	if _, ok := s.db.lists[toListID]; !ok {
+		return notFound("list")
+	}
+	delete(s.db.entries, from)
+	s.db.entries[entry{ListID: toListID, BookID: bookID}] = true
+	return nil
+}
+
+func (s *BookMemory) CountLists(ctx context.Context, bookID int) (int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.db.countLists(bookID), nil
+}
+
+// GetRole returns the role the user has on the book, see DB.bookRole.
+func (s *BookMemory) GetRole(ctx context.Context, userID, bookID int) (string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	role := s.db.bookRole(userID, bookID)
+	if role == "" {
+		return "", notFound("book")
+	}
+	return role, nil
+}
+
+// Suggest looks up books the user created and can still reach whose title
+// or author resembles query, see textmatch.WordSimilarity.
+func (s *BookMemory) Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var suggestions []bookshelf.BookSuggestion
+	for id := range s.db.accessibleBooks(userID) {
+		book := s.db.books[id]
+		if book.UserID != userID {
+			continue
+		}
+		score := max(textmatch.WordSimilarity(query, book.Title), textmatch.WordSimilarity(query, book.Author))
+		if score >= textmatch.SuggestThreshold {
+			suggestions = append(suggestions, bookshelf.BookSuggestion{Book: public(book), Similarity: score})
+		}
+	}
+	slices.SortFunc(suggestions, func(a, b bookshelf.BookSuggestion) int {
+		if c := cmp.Compare(b.Similarity, a.Similarity); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// GetDuplicates groups the books of the user that share the same normalized
+// title and author or the same ISBN. A book can be part of both kinds of
+// groups.
+func (s *BookMemory) GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	byKey := make(map[string][]bookshelf.Book)
+	for id := range s.db.accessibleBooks(userID) {
+		book := s.db.books[id]
+		key := textmatch.DuplicateKey(book.Title, book.Author)
+		byKey[key] = append(byKey[key], public(book))
+		if book.ISBN != "" {
+			byKey["isbn:"+book.ISBN] = append(byKey["isbn:"+book.ISBN], public(book))
+		}
+	}
+
+	var groups []bookshelf.DuplicateGroup
+	for key, books := range byKey {
+		if len(books) > 1 {
+			sortBooks(books)
+			groups = append(groups, bookshelf.DuplicateGroup{Key: key, Books: books})
+		}
+	}
+	slices.SortFunc(groups, func(a, b bookshelf.DuplicateGroup) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return groups, nil
+}
+
+// Merge replaces the books with the canonical book in every list the user
+// can edit and deletes the books that are no longer part of any list. Lists
+// the user cannot edit keep their entries. It returns the lists that held
+// one of the books, including the ones that already held the canonical book.
+func (s *BookMemory) Merge(ctx context.Context, userID, canonicalID int, bookIDs []int) ([]int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	merged := make(map[int]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		merged[id] = true
+	}
+	if _, ok := s.db.books[canonicalID]; !ok {
+		return nil, notFound("book")
+	}
+
+	lists := make(map[int]bool)
+	for e := range s.db.entries {
+		if merged[e.BookID] && bookshelf.HasRole(s.db.role(userID, e.ListID), bookshelf.RoleEditor) {
+			lists[e.ListID] = true
+			delete(s.db.entries, e)
+		}
+	}
+	if len(lists) == 0 {
+		return nil, notFound("book")
+	}
+
+	listIDs := make([]int, 0, len(lists))
+	for id := range lists {
+		s.db.entries[entry{ListID: id, BookID: canonicalID}] = true
+		listIDs = append(listIDs, id)
+	}
+	for id := range merged {
+		s.db.deleteOrphan(id)
+	}
+	slices.Sort(listIDs)
+	return listIDs, nil
+}
+
+// attach adds the book to the list unless it is already there.
+func (db *DB) attach(listID, bookID int) error {
+	if _, ok := db.lists[listID]; !ok {
+		return notFound("list")
+	}
+	if _, ok := db.books[bookID]; !ok {
+		return notFound("book")
+	}
+	db.entries[entry{ListID: listID, BookID: bookID}] = true
+	return nil
+}
+
+func owner(book bookshelf.Book, userID int) int {
+	if book.UserID == userID {
+		return 1
+	}
+	return 0
+}
+
+// public drops the fields the Postgres storage does not read back.
+func public(book bookshelf.Book) bookshelf.Book {
+	book.UserID = 0
+	return book
+}
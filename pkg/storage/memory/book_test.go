@@ -0,0 +1,149 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBookMemory_GetAll(t *testing.T) {
+	ctx := context.Background()
+	db, owner, _, listID, bookID := seed(t)
+	books := NewBookMemory(db)
+	secondID, err := books.Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Anathem", Author: "Neal Stephenson", PublicationYear: 2008})
+	require.NoError(t, err)
+	thirdID, err := books.Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Emma", Author: "Jane Austen", PublicationYear: 1815})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		filter bookshelf.BookFilter
+		want   []int
+	}{
+		{
+			name: "OK",
+			want: []int{bookID, secondID, thirdID},
+		},
+		{
+			name:   "Sorted by title",
+			filter: bookshelf.BookFilter{Sort: "title"},
+			want:   []int{secondID, bookID, thirdID},
+		},
+		{
+			name:   "After cursor",
+			filter: bookshelf.BookFilter{Sort: "-publication_year", Limit: 1, After: &bookshelf.Cursor{Value: float64(2008), ID: secondID}},
+			want:   []int{thirdID},
+		},
+		{
+			name:   "Filtered by author",
+			filter: bookshelf.BookFilter{Author: "jane austen"},
+			want:   []int{thirdID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := books.GetAll(ctx, owner, listID, tt.filter)
+			assert.NoError(t, err)
+			var ids []int
+			for _, book := range got {
+				ids = append(ids, book.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestBookMemory_Update(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, bookID := seed(t)
+	books := NewBookMemory(db)
+	require.NoError(t, NewListMemory(db).AddMember(ctx, listID, other, bookshelf.RoleViewer))
+	isbn := "9780441172719"
+	title := "Dune Messiah"
+	_, err := books.Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Children of Dune", Author: "Frank Herbert", ISBN: "9780593098240"})
+	require.NoError(t, err)
+	taken := "9780593098240"
+
+	tests := []struct {
+		name    string
+		userID  int
+		input   bookshelf.UpdateBookInput
+		want    bookshelf.Book
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			userID: owner,
+			input:  bookshelf.UpdateBookInput{ISBN: &isbn},
+			want:   bookshelf.Book{ID: bookID, Title: "Dune", Author: "Frank Herbert", ISBN: isbn},
+		},
+		{
+			name:    "Viewer",
+			userID:  other,
+			input:   bookshelf.UpdateBookInput{Title: &title},
+			wantErr: bookshelf.ErrNotFound,
+		},
+		{
+			name:    "ISBN taken",
+			userID:  owner,
+			input:   bookshelf.UpdateBookInput{ISBN: &taken},
+			wantErr: bookshelf.ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := books.Update(ctx, tt.userID, bookID, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			got, err := books.GetByID(ctx, owner, bookID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBookMemory_Delete(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, _, bookID := seed(t)
+	books := NewBookMemory(db)
+	otherListID, err := NewListMemory(db).Create(ctx, other, bookshelf.List{Title: "Other"})
+	require.NoError(t, err)
+	require.NoError(t, books.Attach(ctx, otherListID, bookID))
+
+	assert.NoError(t, books.Delete(ctx, owner, bookID))
+	_, err = books.GetByID(ctx, owner, bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = books.GetByID(ctx, other, bookID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, books.Delete(ctx, other, bookID))
+	assert.ErrorIs(t, books.Delete(ctx, other, bookID), bookshelf.ErrNotFound)
+}
+
+func TestBookMemory_Suggest(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, bookID := seed(t)
+	books := NewBookMemory(db)
+
+	got, err := books.Suggest(ctx, owner, "Dunne", 5)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, bookID, got[0].ID)
+	}
+
+	got, err = books.Suggest(ctx, other, "Dune", 5)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	require.NoError(t, NewListMemory(db).AddMember(ctx, listID, other, bookshelf.RoleEditor))
+	got, err = books.Suggest(ctx, other, "Dune", 5)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
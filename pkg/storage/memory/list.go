@@ -0,0 +1,247 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+type ListMemory struct {
+	db *DB
+}
+
+func NewListMemory(db *DB) *ListMemory {
+	return &ListMemory{db: db}
+}
+
+func (s *ListMemory) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err := checkList(list); err != nil {
+		return 0, err
+	}
+	if _, ok := s.db.users[userID]; !ok {
+		return 0, notFound("user")
+	}
+	list.ID = s.db.nextID("lists")
+	s.db.lists[list.ID] = list
+	s.db.members[membership{UserID: userID, ListID: list.ID}] = bookshelf.RoleOwner
+	return list.ID, nil
+}
+
+func (s *ListMemory) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var lists []bookshelf.List
+	for m := range s.db.members {
+		if m.UserID == userID {
+			lists = append(lists, s.db.lists[m.ListID])
+		}
+	}
+
+	var key func(bookshelf.List) any
+	field, desc := bookshelf.SortField(filter.Sort)
+	if field == "title" {
+		key = func(l bookshelf.List) any { return l.Title }
+	}
+	return page(lists, func(l bookshelf.List) int { return l.ID }, key, desc, filter.After, filter.Limit), nil
+}
+
+// Export calls fn for every book in the lists of the user, ordered by list.
+// A listID other than zero restricts the export to that list. The rows are
+// collected first, so fn runs without holding the lock.
+func (s *ListMemory) Export(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error {
+	s.db.mu.RLock()
+	var rows []bookshelf.ExportRow
+	for _, id := range s.db.listIDs(userID) {
+		if listID != 0 && id != listID {
+			continue
+		}
+		list := s.db.lists[id]
+		books := s.db.listBooks(id)
+		if len(books) == 0 {
+			rows = append(rows, bookshelf.ExportRow{List: list})
+		}
+		for i := range books {
+			rows = append(rows, bookshelf.ExportRow{List: list, Book: &books[i]})
+		}
+	}
+	s.db.mu.RUnlock()
+
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ListMemory) GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if s.db.role(userID, listID) == "" {
+		return bookshelf.List{}, notFound("list")
+	}
+	return s.db.lists[listID], nil
+}
+
+func (s *ListMemory) Update(ctx context.Context, userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if input.Title != nil {
+		list.Title = *input.Title
+	}
+	if input.Description != nil {
+		list.Description = *input.Description
+	}
+	if s.db.role(userID, listID) != bookshelf.RoleOwner {
+		return notFound("list")
+	}
+	if err := checkList(list); err != nil {
+		return err
+	}
+	list.ID = listID
+	s.db.lists[listID] = list
+	return nil
+}
+
+// Delete removes the list together with the books that are not part of any
+// other list.
+func (s *ListMemory) Delete(ctx context.Context, userID, listID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.role(userID, listID) != bookshelf.RoleOwner {
+		return notFound("list")
+	}
+	delete(s.db.lists, listID)
+	for m := range s.db.members {
+		if m.ListID == listID {
+			delete(s.db.members, m)
+		}
+	}
+	for id, share := range s.db.shares {
+		if share.ListID == listID {
+			delete(s.db.shares, id)
+		}
+	}
+	var bookIDs []int
+	for e := range s.db.entries {
+		if e.ListID == listID {
+			delete(s.db.entries, e)
+			bookIDs = append(bookIDs, e.BookID)
+		}
+	}
+	for _, id := range bookIDs {
+		s.db.deleteOrphan(id)
+	}
+	return nil
+}
+
+func (s *ListMemory) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	role := s.db.role(userID, listID)
+	if role == "" {
+		return "", notFound("list member")
+	}
+	return role, nil
+}
+
+func (s *ListMemory) GetMembers(ctx context.Context, listID int) ([]bookshelf.ListMember, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var members []bookshelf.ListMember
+	for m, role := range s.db.members {
+		if m.ListID == listID {
+			members = append(members, bookshelf.ListMember{UserID: m.UserID, Username: s.db.users[m.UserID].Username, Role: role})
+		}
+	}
+	slices.SortFunc(members, func(a, b bookshelf.ListMember) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return members, nil
+}
+
+func (s *ListMemory) AddMember(ctx context.Context, listID, userID int, role string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err := checkRole(role); err != nil {
+		return err
+	}
+	if _, ok := s.db.users[userID]; !ok {
+		return notFound("user")
+	}
+	if _, ok := s.db.lists[listID]; !ok {
+		return notFound("list")
+	}
+	if s.db.role(userID, listID) != "" {
+		return conflict("list member")
+	}
+	s.db.members[membership{UserID: userID, ListID: listID}] = role
+	return nil
+}
+
+// UpdateMember changes the role of a member. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListMemory) UpdateMember(ctx context.Context, listID, userID int, role string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current := s.db.role(userID, listID)
+	if current == "" {
+		return notFound("list member")
+	}
+	if err := checkRole(role); err != nil {
+		return err
+	}
+	if current == bookshelf.RoleOwner && role != bookshelf.RoleOwner && s.db.owners(listID) == 1 {
+		return conflict("list must have at least one owner")
+	}
+	s.db.members[membership{UserID: userID, ListID: listID}] = role
+	return nil
+}
+
+// RemoveMember removes a member from the list. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListMemory) RemoveMember(ctx context.Context, listID, userID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current := s.db.role(userID, listID)
+	if current == "" {
+		return notFound("list member")
+	}
+	if current == bookshelf.RoleOwner && s.db.owners(listID) == 1 {
+		return conflict("list must have at least one owner")
+	}
+	delete(s.db.members, membership{UserID: userID, ListID: listID})
+	return nil
+}
+
+// owners returns the number of owners of the list.
+func (db *DB) owners(listID int) int {
+	count := 0
+	for m, role := range db.members {
+		if m.ListID == listID && role == bookshelf.RoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+func checkRole(role string) error {
+	if !bookshelf.HasRole(role, bookshelf.RoleViewer) {
+		return fmt.Errorf("%w: invalid role %q", bookshelf.ErrValidation, role)
+	}
+	return nil
+}
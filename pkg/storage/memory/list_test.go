@@ -0,0 +1,195 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// seed creates two users, a list owned by the first one and a book in it.
+func seed(t *testing.T) (*DB, int, int, int, int) {
+	t.Helper()
+	ctx := context.Background()
+	db := New()
+	auth := NewAuthMemory(db)
+	owner, err := auth.CreateUser(ctx, bookshelf.User{Username: "owner"})
+	require.NoError(t, err)
+	other, err := auth.CreateUser(ctx, bookshelf.User{Username: "other"})
+	require.NoError(t, err)
+	listID, err := NewListMemory(db).Create(ctx, owner, bookshelf.List{Title: "Reading", Description: "now"})
+	require.NoError(t, err)
+	bookID, err := NewBookMemory(db).Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	return db, owner, other, listID, bookID
+}
+
+func TestListMemory_GetByID(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, _ := seed(t)
+	lists := NewListMemory(db)
+
+	tests := []struct {
+		name    string
+		userID  int
+		listID  int
+		want    bookshelf.List
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			userID: owner,
+			listID: listID,
+			want:   bookshelf.List{ID: listID, Title: "Reading", Description: "now"},
+		},
+		{
+			name:    "Not a member",
+			userID:  other,
+			listID:  listID,
+			wantErr: bookshelf.ErrNotFound,
+		},
+		{
+			name:    "Not found",
+			userID:  owner,
+			listID:  100,
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lists.GetByID(ctx, tt.userID, tt.listID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestListMemory_Update(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, _ := seed(t)
+	lists := NewListMemory(db)
+	require.NoError(t, lists.AddMember(ctx, listID, other, bookshelf.RoleEditor))
+	title := "Done"
+	long := strings.Repeat("a", 256)
+
+	tests := []struct {
+		name    string
+		userID  int
+		input   bookshelf.UpdateListInput
+		want    bookshelf.List
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			userID: owner,
+			input:  bookshelf.UpdateListInput{Title: &title},
+			want:   bookshelf.List{ID: listID, Title: "Done", Description: "now"},
+		},
+		{
+			name:    "Not the owner",
+			userID:  other,
+			input:   bookshelf.UpdateListInput{Title: &title},
+			wantErr: bookshelf.ErrNotFound,
+		},
+		{
+			name:    "Too long",
+			userID:  owner,
+			input:   bookshelf.UpdateListInput{Title: &long},
+			wantErr: bookshelf.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := lists.GetByID(ctx, owner, listID)
+			require.NoError(t, err)
+			err = lists.Update(ctx, tt.userID, listID, list, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			got, err := lists.GetByID(ctx, owner, listID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestListMemory_Delete(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, bookID := seed(t)
+	lists := NewListMemory(db)
+	books := NewBookMemory(db)
+	require.NoError(t, lists.AddMember(ctx, listID, other, bookshelf.RoleEditor))
+	otherListID, err := lists.Create(ctx, owner, bookshelf.List{Title: "Other"})
+	require.NoError(t, err)
+	sharedID, err := books.Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Emma", Author: "Jane Austen"})
+	require.NoError(t, err)
+	require.NoError(t, books.Attach(ctx, otherListID, sharedID))
+
+	assert.ErrorIs(t, lists.Delete(ctx, other, listID), bookshelf.ErrNotFound)
+	assert.NoError(t, lists.Delete(ctx, owner, listID))
+
+	_, err = lists.GetRole(ctx, other, listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = books.GetByID(ctx, owner, bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = books.GetByID(ctx, owner, sharedID)
+	assert.NoError(t, err)
+}
+
+func TestListMemory_AddMember(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, _ := seed(t)
+	lists := NewListMemory(db)
+
+	tests := []struct {
+		name    string
+		userID  int
+		role    string
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			userID: other,
+			role:   bookshelf.RoleViewer,
+		},
+		{
+			name:    "Already a member",
+			userID:  owner,
+			role:    bookshelf.RoleViewer,
+			wantErr: bookshelf.ErrConflict,
+		},
+		{
+			name:    "Unknown user",
+			userID:  100,
+			role:    bookshelf.RoleViewer,
+			wantErr: bookshelf.ErrNotFound,
+		},
+		{
+			name:    "Invalid role",
+			userID:  other,
+			role:    "admin",
+			wantErr: bookshelf.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lists.AddMember(ctx, listID, tt.userID, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
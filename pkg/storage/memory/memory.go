@@ -0,0 +1,222 @@
+// Package memory implements the storage interfaces without a database. It
+// keeps the same tables as the Postgres schema in maps and enforces the same
+// constraints and access rules, so the API can run without Postgres.
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type membership struct {
+	UserID int
+	ListID int
+}
+
+type entry struct {
+	ListID int
+	BookID int
+}
+
+// DB is the shared state of the memory storage. Every method takes the lock
+// for the whole operation, which makes operations spanning several tables
+// atomic like the transactions of the Postgres storage.
+type DB struct {
+	mu sync.RWMutex
+
+	users         map[int]bookshelf.User
+	lists         map[int]bookshelf.List
+	members       map[membership]string
+	books         map[int]bookshelf.Book
+	entries       map[entry]bool
+	refreshTokens map[int]bookshelf.RefreshToken
+	revokedTokens map[string]time.Time
+	apiTokens     map[int]bookshelf.APIToken
+	shares        map[int]bookshelf.ListShare
+
+	// seq holds the last ID of every table, like a serial column.
+	seq map[string]int
+}
+
+func New() *DB {
+	return &DB{
+		users:         make(map[int]bookshelf.User),
+		lists:         make(map[int]bookshelf.List),
+		members:       make(map[membership]string),
+		books:         make(map[int]bookshelf.Book),
+		entries:       make(map[entry]bool),
+		refreshTokens: make(map[int]bookshelf.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
+		apiTokens:     make(map[int]bookshelf.APIToken),
+		shares:        make(map[int]bookshelf.ListShare),
+		seq:           make(map[string]int),
+	}
+}
+
+func (db *DB) nextID(table string) int {
+	db.seq[table]++
+	return db.seq[table]
+}
+
+// role returns the role of the user in the list, empty if the user is not a
+// member.
+func (db *DB) role(userID, listID int) string {
+	return db.members[membership{UserID: userID, ListID: listID}]
+}
+
+// canRead reports whether the book is in any list of the user.
+func (db *DB) canRead(userID, bookID int) bool {
+	for e := range db.entries {
+		if e.BookID == bookID && db.role(userID, e.ListID) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bookRole returns the lowest role of the user in the lists containing the
+// book, as the book is shared by all of them. Lists the user is not a member
+// of count as viewer, empty if the user cannot read the book at all.
+func (db *DB) bookRole(userID, bookID int) string {
+	lowest := ""
+	member := false
+	for e := range db.entries {
+		if e.BookID != bookID {
+			continue
+		}
+		role := db.role(userID, e.ListID)
+		if role == "" {
+			role = bookshelf.RoleViewer
+		} else {
+			member = true
+		}
+		if lowest == "" || bookshelf.HasRole(lowest, role) {
+			lowest = role
+		}
+	}
+	if !member {
+		return ""
+	}
+	return lowest
+}
+
+// accessibleBooks returns the IDs of the books in the lists of the user.
+func (db *DB) accessibleBooks(userID int) map[int]bool {
+	ids := make(map[int]bool)
+	for e := range db.entries {
+		if db.role(userID, e.ListID) != "" {
+			ids[e.BookID] = true
+		}
+	}
+	return ids
+}
+
+// listIDs returns the lists the user is a member of, ordered by ID.
+func (db *DB) listIDs(userID int) []int {
+	var ids []int
+	for m := range db.members {
+		if m.UserID == userID {
+			ids = append(ids, m.ListID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// listBooks returns the books of the list, ordered by ID.
+func (db *DB) listBooks(listID int) []bookshelf.Book {
+	var books []bookshelf.Book
+	for e := range db.entries {
+		if e.ListID == listID {
+			books = append(books, db.books[e.BookID])
+		}
+	}
+	sortBooks(books)
+	return books
+}
+
+// countLists returns the number of lists holding the book.
+func (db *DB) countLists(bookID int) int {
+	count := 0
+	for e := range db.entries {
+		if e.BookID == bookID {
+			count++
+		}
+	}
+	return count
+}
+
+// deleteOrphan deletes the book once it is not part of any list.
+func (db *DB) deleteOrphan(bookID int) {
+	for e := range db.entries {
+		if e.BookID == bookID {
+			return
+		}
+	}
+	delete(db.books, bookID)
+}
+
+// isbnTaken reports whether another book of the user has the ISBN.
+func (db *DB) isbnTaken(userID, bookID int, isbn string) bool {
+	if userID == 0 || isbn == "" {
+		return false
+	}
+	for _, book := range db.books {
+		if book.ID != bookID && book.UserID == userID && book.ISBN == isbn {
+			return true
+		}
+	}
+	return false
+}
+
+func sortBooks(books []bookshelf.Book) {
+	slices.SortFunc(books, func(a, b bookshelf.Book) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+func checkList(list bookshelf.List) error {
+	if err := checkLength("title", list.Title, 255); err != nil {
+		return err
+	}
+	return checkLength("description", list.Description, 255)
+}
+
+func checkBook(book bookshelf.Book) error {
+	if err := checkLength("title", book.Title, 255); err != nil {
+		return err
+	}
+	if err := checkLength("author", book.Author, 100); err != nil {
+		return err
+	}
+	if err := checkLength("publisher", book.Publisher, 100); err != nil {
+		return err
+	}
+	if book.ISBN != "" {
+		if isbn, err := bookshelf.NormalizeISBN(book.ISBN); err != nil || isbn != book.ISBN {
+			return fmt.Errorf("%w: isbn %q is not a normalized ISBN-13", bookshelf.ErrValidation, book.ISBN)
+		}
+	}
+	return nil
+}
+
+// checkLength mirrors the varchar limits of the Postgres schema.
+func checkLength(column, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("%w: %s is longer than %d characters", bookshelf.ErrValidation, column, max)
+	}
+	return nil
+}
+
+func notFound(what string) error {
+	return fmt.Errorf("%w: %s", bookshelf.ErrNotFound, what)
+}
+
+func conflict(what string) error {
+	return fmt.Errorf("%w: %s", bookshelf.ErrConflict, what)
+}
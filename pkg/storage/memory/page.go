@@ -0,0 +1,71 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"cmp"
+	"slices"
+)
+
+// page sorts items by the sort key with the ID as a tie breaker, drops the
+// items up to the cursor and applies the limit, like the keyset pagination
+// of the Postgres storage. A nil key sorts by ID only.
+func page[T any](items []T, id func(T) int, key func(T) any, desc bool, after *bookshelf.Cursor, limit int) []T {
+	compare := func(a, b T) int {
+		c := 0
+		if key != nil {
+			c = compareValues(key(a), key(b))
+		}
+		if c == 0 {
+			c = cmp.Compare(id(a), id(b))
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(items, compare)
+
+	if after != nil {
+		items = slices.DeleteFunc(items, func(item T) bool {
+			c := cmp.Compare(id(item), after.ID)
+			if key != nil {
+				if v := compareValues(key(item), after.Value); v != 0 {
+					c = v
+				}
+			}
+			if desc {
+				return c >= 0
+			}
+			return c <= 0
+		})
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// compareValues compares sort keys, which are strings or numbers. Cursor
+// values come from JSON, so numbers may arrive as float64.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		return cmp.Compare(a, b)
+	case int:
+		return cmp.Compare(float64(a), number(b))
+	case float64:
+		return cmp.Compare(a, number(b))
+	}
+	return 0
+}
+
+func number(v any) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+type SearchMemory struct {
+	db *DB
+}
+
+func NewSearchMemory(db *DB) *SearchMemory {
+	return &SearchMemory{db: db}
+}
+
+// Weights of the title, the author or description and the publisher, the
+// defaults of ts_rank for the weights A, B and C.
+var searchWeights = []float64{1, 0.4, 0.2}
+
+// Search matches the books and lists the user has access to. The query
+// syntax and ranking follow websearch_to_tsquery and ts_rank closely enough
+// for the order of the hits, the rank values differ from Postgres.
+func (s *SearchMemory) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	q := textmatch.ParseWebSearch(query.Query)
+	words := q.Words()
+	var hits []bookshelf.SearchHit
+	add := func(kind string, id int, title string, fields ...string) {
+		if r, ok := rank(q, fields); ok {
+			hits = append(hits, bookshelf.SearchHit{
+				Kind:     kind,
+				ID:       id,
+				Title:    title,
+				Headline: textmatch.Mark(strings.Join(slices.DeleteFunc(fields, func(f string) bool { return f == "" }), " "), words),
+				Rank:     r,
+			})
+		}
+	}
+	for id := range s.db.accessibleBooks(userID) {
+		book := s.db.books[id]
+		add("book", book.ID, book.Title, book.Title, book.Author, book.Publisher)
+	}
+	for _, id := range s.db.listIDs(userID) {
+		list := s.db.lists[id]
+		add("list", list.ID, list.Title, list.Title, list.Description)
+	}
+
+	compare := func(a, b bookshelf.SearchHit) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Kind, a.Kind); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	}
+	slices.SortFunc(hits, compare)
+	if after := query.After; after != nil {
+		cursor := bookshelf.SearchHit{Kind: after.Kind, ID: after.ID, Rank: number(after.Value)}
+		hits = slices.DeleteFunc(hits, func(hit bookshelf.SearchHit) bool {
+			return compare(hit, cursor) <= 0
+		})
+	}
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// rank reports whether the fields match the query and sums the weights of
+// the fields every matching term occurs in.
+func rank(q textmatch.Query, fields []string) (float64, bool) {
+	tokens := make([][]string, len(fields))
+	for i, field := range fields {
+		tokens[i] = textmatch.Tokenize(field)
+	}
+	document := slices.Concat(tokens...)
+
+	matched, total := false, 0.0
+	for _, group := range q {
+		ok := len(group) > 0
+		for _, term := range group {
+			if containsPhrase(document, term.Words) == term.Exclude {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		matched = true
+		for _, term := range group {
+			for i := range tokens {
+				if !term.Exclude && containsPhrase(tokens[i], term.Words) {
+					total += searchWeights[i]
+				}
+			}
+		}
+	}
+	return total, matched
+}
+
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		if slices.Equal(tokens[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
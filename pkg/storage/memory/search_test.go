@@ -0,0 +1,73 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchMemory_Search(t *testing.T) {
+	ctx := context.Background()
+	db, owner, other, listID, bookID := seed(t)
+	books := NewBookMemory(db)
+	emmaID, err := books.Create(ctx, listID, bookshelf.Book{UserID: owner, Title: "Emma", Author: "Jane Austen", Publisher: "Dune Press"})
+	require.NoError(t, err)
+	search := NewSearchMemory(db)
+
+	tests := []struct {
+		name   string
+		userID int
+		query  bookshelf.SearchQuery
+		want   []bookshelf.SearchHit
+	}{
+		{
+			name:   "Ranked by field",
+			userID: owner,
+			query:  bookshelf.SearchQuery{Query: "dune"},
+			want: []bookshelf.SearchHit{
+				{Kind: "book", ID: bookID, Title: "Dune", Headline: "<mark>Dune</mark> Frank Herbert", Rank: 1},
+				{Kind: "book", ID: emmaID, Title: "Emma", Headline: "Emma Jane Austen <mark>Dune</mark> Press", Rank: 0.2},
+			},
+		},
+		{
+			name:   "Excluded word",
+			userID: owner,
+			query:  bookshelf.SearchQuery{Query: "dune -austen"},
+			want: []bookshelf.SearchHit{
+				{Kind: "book", ID: bookID, Title: "Dune", Headline: "<mark>Dune</mark> Frank Herbert", Rank: 1},
+			},
+		},
+		{
+			name:   "Or",
+			userID: owner,
+			query:  bookshelf.SearchQuery{Query: `"jane austen" or reading`},
+			want: []bookshelf.SearchHit{
+				{Kind: "list", ID: listID, Title: "Reading", Headline: "<mark>Reading</mark> now", Rank: 1},
+				{Kind: "book", ID: emmaID, Title: "Emma", Headline: "Emma <mark>Jane</mark> <mark>Austen</mark> Dune Press", Rank: 0.4},
+			},
+		},
+		{
+			name:   "After cursor",
+			userID: owner,
+			query:  bookshelf.SearchQuery{Query: "dune", After: &bookshelf.Cursor{Value: float64(1), Kind: "book", ID: bookID}},
+			want: []bookshelf.SearchHit{
+				{Kind: "book", ID: emmaID, Title: "Emma", Headline: "Emma Jane Austen <mark>Dune</mark> Press", Rank: 0.2},
+			},
+		},
+		{
+			name:   "No access",
+			userID: other,
+			query:  bookshelf.SearchQuery{Query: "dune"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := search.Search(ctx, tt.userID, tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
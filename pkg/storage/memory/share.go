@@ -0,0 +1,82 @@
+package memory
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"slices"
+	"time"
+)
+
+type ShareMemory struct {
+	db *DB
+}
+
+func NewShareMemory(db *DB) *ShareMemory {
+	return &ShareMemory{db: db}
+}
+
+func (s *ShareMemory) Create(ctx context.Context, share bookshelf.ListShare) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.lists[share.ListID]; !ok {
+		return 0, notFound("list")
+	}
+	if _, ok := s.db.users[share.CreatedBy]; !ok {
+		return 0, notFound("user")
+	}
+	for _, sh := range s.db.shares {
+		if sh.TokenHash == share.TokenHash {
+			return 0, conflict("share")
+		}
+	}
+	share.ID = s.db.nextID("list_shares")
+	share.Revoked = false
+	share.CreatedAt = time.Now()
+	s.db.shares[share.ID] = share
+	return share.ID, nil
+}
+
+// GetAll returns the shares of the list that are neither revoked nor
+// expired.
+func (s *ShareMemory) GetAll(ctx context.Context, listID int) ([]bookshelf.ListShare, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	var shares []bookshelf.ListShare
+	for _, share := range s.db.shares {
+		if share.ListID == listID && !share.Revoked && (share.ExpiresAt == nil || share.ExpiresAt.After(now)) {
+			shares = append(shares, share)
+		}
+	}
+	slices.SortFunc(shares, func(a, b bookshelf.ListShare) int {
+		return a.ID - b.ID
+	})
+	return shares, nil
+}
+
+func (s *ShareMemory) GetByHash(ctx context.Context, tokenHash string) (bookshelf.ListShare, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, share := range s.db.shares {
+		if share.TokenHash == tokenHash {
+			return share, nil
+		}
+	}
+	return bookshelf.ListShare{}, notFound("share")
+}
+
+func (s *ShareMemory) Revoke(ctx context.Context, listID, shareID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	share, ok := s.db.shares[shareID]
+	if !ok || share.ListID != listID || share.Revoked {
+		return notFound("share")
+	}
+	share.Revoked = true
+	s.db.shares[shareID] = share
+	return nil
+}
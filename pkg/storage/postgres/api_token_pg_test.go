@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var apiTokenColumns = []string{"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at", "created_at"}
+
+func TestAPITokenPostgres_Create(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tokens := NewAPITokenPostgres(db)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	token := bookshelf.APIToken{
+		UserID:    1,
+		Name:      "script",
+		Prefix:    "bsk_abcdef",
+		TokenHash: "hash",
+		Scopes:    []string{bookshelf.ScopeListsRead, bookshelf.ScopeBooksWrite},
+		ExpiresAt: &expiresAt,
+	}
+
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+	mock.ExpectQuery("INSERT INTO api_tokens").
+		WithArgs(1, "script", "bsk_abcdef", "hash", "{\"lists:read\",\"books:write\"}", &expiresAt).
+		WillReturnRows(rows)
+
+	id, err := tokens.Create(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPITokenPostgres_GetByHash(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tokens := NewAPITokenPostgres(db)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastUsedAt := createdAt.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    bookshelf.APIToken
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				rows := sqlmock.NewRows(apiTokenColumns).
+					AddRow(1, 2, "script", "bsk_abcdef", "hash", "{lists:read,books:read}", nil, lastUsedAt, createdAt)
+				mock.ExpectQuery("SELECT (.+) FROM api_tokens WHERE token_hash=(.+)").WithArgs("hash").WillReturnRows(rows)
+			},
+			want: bookshelf.APIToken{
+				ID:         1,
+				UserID:     2,
+				Name:       "script",
+				Prefix:     "bsk_abcdef",
+				TokenHash:  "hash",
+				Scopes:     []string{bookshelf.ScopeListsRead, bookshelf.ScopeBooksRead},
+				LastUsedAt: &lastUsedAt,
+				CreatedAt:  createdAt,
+			},
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				mock.ExpectQuery("SELECT (.+) FROM api_tokens WHERE token_hash=(.+)").WithArgs("hash").WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := tokens.GetByHash(ctx, "hash")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAPITokenPostgres_Touch(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE api_tokens SET last_used_at=now\\(\\) WHERE id=(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, NewAPITokenPostgres(db).Touch(ctx, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPITokenPostgres_Delete(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tokens := NewAPITokenPostgres(db)
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectExec("DELETE FROM api_tokens WHERE (.+)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				mock.ExpectExec("DELETE FROM api_tokens WHERE (.+)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			assert.ErrorIs(t, tokens.Delete(ctx, 1, 2), tt.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -2,7 +2,9 @@ package postgres
 
 import (
 	bookshelf "bookshelf-api"
+	"context"
 	"database/sql"
+	"time"
 )
 
 type AuthPostgres struct {
@@ -13,19 +15,80 @@ func NewAuthPostgres(db *sql.DB) *AuthPostgres {
 	return &AuthPostgres{db: db}
 }
 
-func (s *AuthPostgres) CreateUser(user bookshelf.User) (int, error) {
+func (s *AuthPostgres) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
 	var id int
 	query := "INSERT INTO users(username, password_hash) values ($1, $2) RETURNING id"
-	row := s.db.QueryRow(query, user.Username, user.Password)
+	row := s.db.QueryRowContext(ctx, query, user.Username, user.Password)
 	if err := row.Scan(&id); err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 	return id, nil
 }
 
-func (s *AuthPostgres) GetUser(username, password string) (bookshelf.User, error) {
+func (s *AuthPostgres) GetUser(ctx context.Context, username string) (bookshelf.User, error) {
 	var user bookshelf.User
-	query := "SELECT id FROM users WHERE username=$1 AND password_hash=$2"
-	err := s.db.QueryRow(query, username, password).Scan(&user.ID)
-	return user, err
+	query := "SELECT id, username, password_hash FROM users WHERE username=$1"
+	err := s.db.QueryRowContext(ctx, query, username).Scan(&user.ID, &user.Username, &user.Password)
+	return user, wrapError(err)
+}
+
+func (s *AuthPostgres) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	query := "UPDATE users SET password_hash=$1 WHERE id=$2"
+	_, err := s.db.ExecContext(ctx, query, hash, userID)
+	return wrapError(err)
+}
+
+func (s *AuthPostgres) CreateRefreshToken(ctx context.Context, token bookshelf.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	_, err := s.db.ExecContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	return wrapError(err)
+}
+
+func (s *AuthPostgres) GetRefreshToken(ctx context.Context, tokenHash string) (bookshelf.RefreshToken, error) {
+	var token bookshelf.RefreshToken
+	query := "SELECT id, user_id, family_id, token_hash, expires_at, used_at IS NOT NULL, revoked_at IS NOT NULL FROM refresh_tokens WHERE token_hash=$1"
+	row := s.db.QueryRowContext(ctx, query, tokenHash)
+	err := row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.Used, &token.Revoked)
+	if err != nil {
+		return bookshelf.RefreshToken{}, wrapError(err)
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token as used and reports whether it was still
+// unused. Concurrent attempts to use the same token get false.
+func (s *AuthPostgres) UseRefreshToken(ctx context.Context, id int) (bool, error) {
+	query := "UPDATE refresh_tokens SET used_at=now() WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, wrapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return n == 1, nil
+}
+
+func (s *AuthPostgres) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at=now() WHERE family_id=$1 AND revoked_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, familyID)
+	return wrapError(err)
+}
+
+func (s *AuthPostgres) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := "INSERT INTO revoked_tokens(jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING"
+	if _, err := s.db.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		return wrapError(err)
+	}
+	cleanupQuery := "DELETE FROM revoked_tokens WHERE expires_at < now()"
+	_, err := s.db.ExecContext(ctx, cleanupQuery)
+	return wrapError(err)
+}
+
+func (s *AuthPostgres) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	query := "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti=$1)"
+	err := s.db.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, wrapError(err)
 }
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBackupPostgres_Restore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	backup := NewBackupPostgres(db)
+	input := bookshelf.Backup{
+		Lists: []bookshelf.BackupList{
+			{List: bookshelf.List{ID: 10, Title: "sci-fi"}, Role: bookshelf.RoleOwner},
+			{List: bookshelf.List{ID: 11, Title: "Classics", Description: "old"}, Role: bookshelf.RoleEditor},
+		},
+		Books: []bookshelf.Book{
+			{ID: 20, Title: "Dune", ISBN: "9780441172719"},
+			{ID: 21, Title: "Iliad"},
+		},
+		Entries: []bookshelf.BackupEntry{{ListID: 10, BookID: 20}, {ListID: 11, BookID: 21}},
+		Members: []bookshelf.BackupMember{
+			{ListID: 10, Username: "friend", Role: bookshelf.RoleViewer},
+			{ListID: 11, Username: "friend", Role: bookshelf.RoleEditor},
+			{ListID: 11, Username: "stranger", Role: bookshelf.RoleViewer},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    bookshelf.RestoreResult
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT l.id, l.title FROM lists l (.+) ul.role IN").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Sci-Fi"))
+				mock.ExpectQuery("INSERT INTO lists").
+					WithArgs("Classics", "old").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectExec("INSERT INTO users_lists").
+					WithArgs(1, 2, bookshelf.RoleOwner).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id FROM books WHERE user_id = (.+) AND isbn = (.+)").
+					WithArgs(1, "9780441172719").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+				mock.ExpectQuery("INSERT INTO books").
+					WithArgs(1, "Iliad", "", "", 0, 0, "").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+				mock.ExpectExec("INSERT INTO lists_books(.+) ON CONFLICT").
+					WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO lists_books(.+) ON CONFLICT").
+					WithArgs(2, 6).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id FROM users").
+					WithArgs("friend").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("INSERT INTO users_lists(.+) ON CONFLICT").
+					WithArgs(3, 2, bookshelf.RoleEditor).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id FROM users").
+					WithArgs("stranger").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectCommit()
+			},
+			want: bookshelf.RestoreResult{
+				ListIDs:        map[int]int{10: 1, 11: 2},
+				BookIDs:        map[int]int{20: 5, 21: 6},
+				ListsCreated:   1,
+				BooksCreated:   1,
+				Entries:        1,
+				Members:        1,
+				SkippedMembers: []string{"stranger"},
+			},
+		},
+		{
+			name: "Rollback",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT l.id, l.title FROM lists l (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "title"}))
+				mock.ExpectQuery("INSERT INTO lists").
+					WithArgs("sci-fi", "").WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := backup.Restore(ctx, 1, input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -2,7 +2,10 @@ package postgres
 
 import (
 	bookshelf "bookshelf-api"
+	"context"
 	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
 )
 
 type BookPostgres struct {
@@ -13,62 +16,115 @@ func NewBookPostgres(db *sql.DB) *BookPostgres {
 	return &BookPostgres{db: db}
 }
 
-func (s *BookPostgres) Create(listID int, book bookshelf.Book) (int, error) {
-	tx, err := s.db.Begin()
+func (s *BookPostgres) Create(ctx context.Context, listID int, book bookshelf.Book) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	var bookID int
-	createBookQuery := "INSERT INTO books(title, author, publisher, publication_year, page_count) VALUES ($1, $2, $3, $4, $5) RETURNING id"
-	row := tx.QueryRow(createBookQuery, book.Title, book.Author, book.Publisher, book.PublicationYear, book.PageCount)
+	createBookQuery := "INSERT INTO books(user_id, title, author, publisher, publication_year, page_count, isbn) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')) RETURNING id"
+	row := tx.QueryRowContext(ctx, createBookQuery, book.UserID, book.Title, book.Author, book.Publisher, book.PublicationYear, book.PageCount, book.ISBN)
 	err = row.Scan(&bookID)
 	if err != nil {
 		tx.Rollback()
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	createListsBooksQuery := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2)"
-	_, err = tx.Exec(createListsBooksQuery, listID, bookID)
+	_, err = tx.ExecContext(ctx, createListsBooksQuery, listID, bookID)
 	if err != nil {
 		tx.Rollback()
-		return 0, err
+		return 0, wrapError(err)
 	}
 	return bookID, tx.Commit()
 }
 
-func (s *BookPostgres) GetAll(userID, listID int) ([]bookshelf.Book, error) {
+// bookSortColumns maps sort fields to columns. Nullable columns sort as
+// their zero value, which is also what the cursor of such a row holds,
+// otherwise the keyset condition would skip rows with NULLs.
+var bookSortColumns = map[string]string{
+	"":                 "b.id",
+	"title":            "b.title",
+	"author":           "b.author",
+	"publication_year": "COALESCE(b.publication_year, 0)",
+	"page_count":       "COALESCE(b.page_count, 0)",
+}
+
+func (s *BookPostgres) GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, error) {
 	var books []bookshelf.Book
-	query := "SELECT b.title, b.author, b.publisher, b.publication_year, b.page_count FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.list_id = $1 AND ul.user_id = $2"
-	rows, err := s.db.Query(query, listID, userID)
+	conds := []string{"lb.list_id = $1", "ul.user_id = $2"}
+	args := []any{listID, userID}
+	if filter.Author != "" {
+		args = append(args, filter.Author)
+		conds = append(conds, fmt.Sprintf("lower(b.author) = lower($%d)", len(args)))
+	}
+	if filter.Publisher != "" {
+		args = append(args, filter.Publisher)
+		conds = append(conds, fmt.Sprintf("lower(b.publisher) = lower($%d)", len(args)))
+	}
+	if filter.YearFrom != nil {
+		args = append(args, *filter.YearFrom)
+		conds = append(conds, fmt.Sprintf("b.publication_year >= $%d", len(args)))
+	}
+	if filter.YearTo != nil {
+		args = append(args, *filter.YearTo)
+		conds = append(conds, fmt.Sprintf("b.publication_year <= $%d", len(args)))
+	}
+
+	field, desc := bookshelf.SortField(filter.Sort)
+	query, args := page(
+		"SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id",
+		conds, args,
+		bookSortColumns[field], "b.id", desc, filter.After, filter.Limit,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var book bookshelf.Book
-		err := rows.Scan(&book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount)
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
 		if err != nil {
-			return nil, err
+			return nil, wrapError(err)
 		}
 		books = append(books, book)
 	}
-	return books, nil
+	return books, wrapError(rows.Err())
+}
+
+func (s *BookPostgres) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	var book bookshelf.Book
+	query := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE b.id = $1 AND ul.user_id = $2 LIMIT 1"
+	row := s.db.QueryRowContext(ctx, query, bookID, userID)
+	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+	if err != nil {
+		return bookshelf.Book{}, wrapError(err)
+	}
+	return book, nil
 }
-func (s *BookPostgres) GetByID(userID, bookID int) (bookshelf.Book, error) {
+
+// GetByISBN returns a book of the user with the ISBN. Books the user created
+// come before books shared with the user.
+func (s *BookPostgres) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
 	var book bookshelf.Book
-	query := "SELECT b.title, b.author, b.publisher, b.publication_year, b.page_count FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE b.id = $1 AND ul.user_id = $2"
-	row := s.db.QueryRow(query, bookID, userID)
-	err := row.Scan(&book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount)
+	query := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b WHERE b.isbn = $1 AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $2) ORDER BY b.user_id = $2 DESC, b.id LIMIT 1"
+	row := s.db.QueryRowContext(ctx, query, isbn, userID)
+	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
 	if err != nil {
-		return bookshelf.Book{}, err
+		return bookshelf.Book{}, wrapError(err)
 	}
 	return book, nil
 }
-func (s *BookPostgres) Update(userID, bookID int, input bookshelf.UpdateBookInput) error {
-	book, err := s.GetByID(userID, bookID)
+
+// Update changes the book if the user can edit every list containing it. The
+// book is shared by all of its lists, so an editor of one list must not
+// change what the members of another list see.
+func (s *BookPostgres) Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error {
+	book, err := s.GetByID(ctx, userID, bookID)
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 	if input.Title == nil {
 		input.Title = &book.Title
@@ -85,12 +141,257 @@ func (s *BookPostgres) Update(userID, bookID int, input bookshelf.UpdateBookInpu
 	if input.PageCount == nil {
 		input.PageCount = &book.PageCount
 	}
-	query := "UPDATE books b SET title = $1, author = $2, publisher = $3, publication_year = $4, page_count = $5 FROM lists_books lb, users_lists ul WHERE b.id = lb.book_id AND lb.list_id = ul.list_id AND ul.user_id = $6 AND b.id = $7"
-	_, err = s.db.Exec(query, input.Title, input.Author, input.Publisher, input.PublicationYear, input.PageCount, userID, bookID)
-	return err
+	if input.ISBN == nil {
+		input.ISBN = &book.ISBN
+	}
+	query := "UPDATE books b SET title = $1, author = $2, publisher = $3, publication_year = $4, page_count = $5, isbn = NULLIF($6, '') WHERE b.id = $8 AND NOT EXISTS (SELECT 1 FROM lists_books lb LEFT JOIN users_lists ul ON lb.list_id = ul.list_id AND ul.user_id = $7 WHERE lb.book_id = b.id AND COALESCE(ul.role, '') NOT IN ('owner', 'editor'))"
+	res, err := s.db.ExecContext(ctx, query, input.Title, input.Author, input.Publisher, input.PublicationYear, input.PageCount, input.ISBN, userID, bookID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
+}
+
+// Delete removes the book from every list the user can edit. The book itself
+// is deleted once it is no longer part of any list, so lists of other users
+// keep their copy.
+func (s *BookPostgres) Delete(ctx context.Context, userID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	listsBooksQuery := "DELETE FROM lists_books lb USING users_lists ul WHERE lb.list_id = ul.list_id AND ul.user_id = $1 AND lb.book_id = $2 AND ul.role IN ('owner', 'editor')"
+	res, err := tx.ExecContext(ctx, listsBooksQuery, userID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	booksQuery := "DELETE FROM books b WHERE b.id = $1 AND NOT EXISTS (SELECT 1 FROM lists_books lb WHERE lb.book_id = b.id)"
+	_, err = tx.ExecContext(ctx, booksQuery, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	return tx.Commit()
+}
+
+// Attach adds an existing book to a list. Attaching a book that is already
+// in the list is a no-op.
+func (s *BookPostgres) Attach(ctx context.Context, listID, bookID int) error {
+	query := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2) ON CONFLICT (list_id, book_id) DO NOTHING"
+	_, err := s.db.ExecContext(ctx, query, listID, bookID)
+	return wrapError(err)
+}
+
+// Detach removes a book from a list without deleting the book. It fails with
+// bookshelf.ErrConflict if the list is the last one holding the book.
+// The book row is locked first, so concurrent detaches from different lists
+// see each other.
+func (s *BookPostgres) Detach(ctx context.Context, listID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	var id int
+	lockQuery := "SELECT id FROM books WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, lockQuery, bookID).Scan(&id); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+
+	query := "DELETE FROM lists_books WHERE list_id = $1 AND book_id = $2"
+	res, err := tx.ExecContext(ctx, query, listID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var count int
+	countQuery := "SELECT count(*) FROM lists_books WHERE book_id = $1"
+	if err := tx.QueryRowContext(ctx, countQuery, bookID).Scan(&count); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if count == 0 {
+		tx.Rollback()
+		return bookshelf.ErrConflict
+	}
+	return wrapError(tx.Commit())
+}
+
+func (s *BookPostgres) Move(ctx context.Context, fromListID, toListID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	detachQuery := "DELETE FROM lists_books WHERE list_id = $1 AND book_id = $2"
+	res, err := tx.ExecContext(ctx, detachQuery, fromListID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	attachQuery := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2) ON CONFLICT (list_id, book_id) DO NOTHING"
+	_, err = tx.ExecContext(ctx, attachQuery, toListID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	return tx.Commit()
+}
+
+func (s *BookPostgres) CountLists(ctx context.Context, bookID int) (int, error) {
+	var count int
+	query := "SELECT count(*) FROM lists_books WHERE book_id = $1"
+	err := s.db.QueryRowContext(ctx, query, bookID).Scan(&count)
+	return count, wrapError(err)
+}
+
+// GetRole returns the role the user has on the book, which is the lowest
+// role across the lists containing it. Lists the user is not a member of
+// count as viewer, as the book stays readable through the other lists.
+func (s *BookPostgres) GetRole(ctx context.Context, userID, bookID int) (string, error) {
+	var role string
+	query := "SELECT CASE WHEN bool_and(COALESCE(ul.role, '') = 'owner') THEN 'owner' WHEN bool_and(COALESCE(ul.role, '') IN ('owner', 'editor')) THEN 'editor' ELSE 'viewer' END FROM lists_books lb LEFT JOIN users_lists ul ON lb.list_id = ul.list_id AND ul.user_id = $2 WHERE lb.book_id = $1 HAVING count(ul.user_id) > 0"
+	err := s.db.QueryRowContext(ctx, query, bookID, userID).Scan(&role)
+	return role, wrapError(err)
 }
-func (s *BookPostgres) Delete(userID, bookID int) error {
-	query := "DELETE FROM books b USING lists_books lb, users_lists ul WHERE  b.id = lb.book_id AND lb.list_id = ul.list_id AND ul.user_id = $1 AND b.id = $2"
-	_, err := s.db.Exec(query, userID, bookID)
-	return err
+
+// Suggest looks up books the user created and can still reach whose title
+// or author resembles query. word_similarity matches query against the best
+// fitting part of the text, so a partial name still scores high.
+func (s *BookPostgres) Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error) {
+	var suggestions []bookshelf.BookSuggestion
+	sqlQuery := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, ''), greatest(word_similarity($1, b.title), word_similarity($1, b.author)) AS similarity FROM books b WHERE ($1 <% b.title OR $1 <% b.author) AND b.user_id = $2 AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $2) ORDER BY similarity DESC, b.id LIMIT $3"
+	rows, err := s.db.QueryContext(ctx, sqlQuery, query, userID, limit)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var suggestion bookshelf.BookSuggestion
+		book := &suggestion.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN, &suggestion.Similarity)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, wrapError(rows.Err())
+}
+
+// duplicateKey normalizes title and author by case, punctuation and spacing.
+const duplicateKey = "lower(trim(regexp_replace(b.title, '[^[:alnum:]]+', ' ', 'g'))) || ' / ' || lower(trim(regexp_replace(b.author, '[^[:alnum:]]+', ' ', 'g')))"
+
+// GetDuplicates groups the books of the user that share the same normalized
+// title and author or the same ISBN. A book can be part of both kinds of
+// groups.
+func (s *BookPostgres) GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error) {
+	var groups []bookshelf.DuplicateGroup
+	query := "SELECT d.key, d.id, d.title, d.author, d.publisher, d.publication_year, d.page_count, d.isbn FROM (" +
+		"SELECT b.id, b.title, b.author, COALESCE(b.publisher, '') AS publisher, COALESCE(b.publication_year, 0) AS publication_year, COALESCE(b.page_count, 0) AS page_count, COALESCE(b.isbn, '') AS isbn, k.key, count(*) OVER (PARTITION BY k.key) AS copies " +
+		"FROM books b CROSS JOIN LATERAL (VALUES (" + duplicateKey + "), ('isbn:' || b.isbn)) k(key) " +
+		"WHERE k.key IS NOT NULL AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $1)" +
+		") d WHERE d.copies > 1 ORDER BY d.key, d.id"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var book bookshelf.Book
+		err := rows.Scan(&key, &book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Key != key {
+			groups = append(groups, bookshelf.DuplicateGroup{Key: key})
+		}
+		group := &groups[len(groups)-1]
+		group.Books = append(group.Books, book)
+	}
+	return groups, wrapError(rows.Err())
+}
+
+// Merge replaces the books with the canonical book in every list the user
+// can edit and deletes the books that are no longer part of any list. Lists
+// the user cannot edit keep their entries. It returns the lists that held
+// one of the books, including the ones that already held the canonical book.
+func (s *BookPostgres) Merge(ctx context.Context, userID, canonicalID int, bookIDs []int) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	ids := make([]int64, len(bookIDs))
+	for i, id := range bookIDs {
+		ids[i] = int64(id)
+	}
+
+	var listIDs []int64
+	listsQuery := "SELECT DISTINCT lb.list_id FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = ANY($1) AND ul.user_id = $2 AND ul.role IN ('owner', 'editor') ORDER BY lb.list_id"
+	rows, err := tx.QueryContext(ctx, listsQuery, pq.Array(ids), userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, wrapError(err)
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+	if len(listIDs) == 0 {
+		tx.Rollback()
+		return nil, wrapError(sql.ErrNoRows)
+	}
+
+	rewriteQuery := "INSERT INTO lists_books(list_id, book_id) SELECT list_id, $1 FROM unnest($2::int[]) AS list_id ON CONFLICT (list_id, book_id) DO NOTHING"
+	if _, err := tx.ExecContext(ctx, rewriteQuery, canonicalID, pq.Array(listIDs)); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+
+	detachQuery := "DELETE FROM lists_books WHERE book_id = ANY($1) AND list_id = ANY($2)"
+	if _, err := tx.ExecContext(ctx, detachQuery, pq.Array(ids), pq.Array(listIDs)); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+
+	deleteQuery := "DELETE FROM books b WHERE b.id = ANY($1) AND NOT EXISTS (SELECT 1 FROM lists_books lb WHERE lb.book_id = b.id)"
+	if _, err := tx.ExecContext(ctx, deleteQuery, pq.Array(ids)); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+
+	result := make([]int, len(listIDs))
+	for i, id := range listIDs {
+		result[i] = int(id)
+	}
+	return result, wrapError(tx.Commit())
 }
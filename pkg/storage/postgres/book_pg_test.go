@@ -0,0 +1,420 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBookPostgres_GetAll(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	columns := []string{"id", "title", "author", "publisher", "publication_year", "page_count", "isbn"}
+	tests := []struct {
+		name    string
+		mock    func()
+		filter  bookshelf.BookFilter
+		want    []bookshelf.Book
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "title1", "author1", "publisher1", 2001, 100, "9780306406157").
+					AddRow(2, "title2", "author2", "publisher2", 2002, 200, "")
+				mock.ExpectQuery(`SELECT b.id, (.+) FROM books b (.+) WHERE lb.list_id = \$1 AND ul.user_id = \$2 ORDER BY b.id ASC$`).
+					WithArgs(1, 2).WillReturnRows(rows)
+			},
+			want: []bookshelf.Book{
+				{ID: 1, Title: "title1", Author: "author1", Publisher: "publisher1", PublicationYear: 2001, PageCount: 100, ISBN: "9780306406157"},
+				{ID: 2, Title: "title2", Author: "author2", Publisher: "publisher2", PublicationYear: 2002, PageCount: 200},
+			},
+		},
+		{
+			name: "Filtered page",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).
+					AddRow(3, "title3", "author", "publisher3", 2003, 300, "")
+				mock.ExpectQuery(`SELECT (.+) WHERE lb.list_id = \$1 AND ul.user_id = \$2 AND lower\(b.author\) = lower\(\$3\) AND b.publication_year >= \$4 AND b.publication_year <= \$5 AND \(COALESCE\(b.page_count, 0\), b.id\) > \(\$6, \$7\) ORDER BY COALESCE\(b.page_count, 0\) ASC, b.id ASC LIMIT \$8`).
+					WithArgs(1, 2, "Author", 2000, 2010, 200, 2, 10).WillReturnRows(rows)
+			},
+			filter: bookshelf.BookFilter{
+				Sort:     "page_count",
+				Author:   "Author",
+				YearFrom: intPointer(2000),
+				YearTo:   intPointer(2010),
+				Limit:    10,
+				After:    &bookshelf.Cursor{Sort: "page_count", Value: 200, ID: 2},
+			},
+			want: []bookshelf.Book{
+				{ID: 3, Title: "title3", Author: "author", Publisher: "publisher3", PublicationYear: 2003, PageCount: 300},
+			},
+		},
+		{
+			name: "Error",
+			mock: func() {
+				mock.ExpectQuery("SELECT (.+) FROM books b (.+)").
+					WithArgs(1, 2).WillReturnError(errors.New("some error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := book.GetAll(ctx, 2, 1, tt.filter)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookPostgres_Suggest(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	rows := sqlmock.NewRows([]string{"id", "title", "author", "publisher", "publication_year", "page_count", "isbn", "similarity"}).
+		AddRow(1, "Crime and Punishment", "Fyodor Dostoevsky", "", 1866, 671, "", 0.8)
+	mock.ExpectQuery(`SELECT b.id, b.title, b.author, COALESCE\(b.publisher, ''\), COALESCE\(b.publication_year, 0\), COALESCE\(b.page_count, 0\), (.+), greatest\(word_similarity\(\$1, b.title\), word_similarity\(\$1, b.author\)\) AS similarity FROM books b WHERE (.+) AND b.user_id = \$2 AND (.+) ORDER BY similarity DESC, b.id LIMIT \$3`).
+		WithArgs("Dostoyevsky", 1, 5).WillReturnRows(rows)
+
+	got, err := book.Suggest(ctx, 1, "Dostoyevsky", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.BookSuggestion{
+		{
+			Book:       bookshelf.Book{ID: 1, Title: "Crime and Punishment", Author: "Fyodor Dostoevsky", PublicationYear: 1866, PageCount: 671},
+			Similarity: 0.8,
+		},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookPostgres_GetDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	rows := sqlmock.NewRows([]string{"key", "id", "title", "author", "publisher", "publication_year", "page_count", "isbn"}).
+		AddRow("dune / frank herbert", 1, "Dune", "Frank Herbert", "", 1965, 412, "").
+		AddRow("dune / frank herbert", 4, "DUNE ", "Frank Herbert", "", 1965, 412, "").
+		// A legacy row with NULL details, as returned by the coalesced query.
+		AddRow("dune / frank herbert", 5, "Dune", "Frank Herbert", "", 0, 0, "").
+		AddRow("isbn:9780451169518", 2, "It", "Stephen King", "", 1986, 1138, "9780451169518").
+		AddRow("isbn:9780451169518", 3, "It", "S. King", "", 1986, 1138, "9780451169518")
+	mock.ExpectQuery(`SELECT d.key, (.+) COALESCE\(b.publisher, ''\) AS publisher, COALESCE\(b.publication_year, 0\) AS publication_year, COALESCE\(b.page_count, 0\) AS page_count, (.+) count\(\*\) OVER \(PARTITION BY k.key\) AS copies FROM books b CROSS JOIN LATERAL \(VALUES (.+), \('isbn:' \|\| b.isbn\)\) k\(key\) WHERE k.key IS NOT NULL (.+) d WHERE d.copies > 1 ORDER BY d.key, d.id`).
+		WithArgs(1).WillReturnRows(rows)
+
+	got, err := book.GetDuplicates(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.DuplicateGroup{
+		{
+			Key: "dune / frank herbert",
+			Books: []bookshelf.Book{
+				{ID: 1, Title: "Dune", Author: "Frank Herbert", PublicationYear: 1965, PageCount: 412},
+				{ID: 4, Title: "DUNE ", Author: "Frank Herbert", PublicationYear: 1965, PageCount: 412},
+				{ID: 5, Title: "Dune", Author: "Frank Herbert"},
+			},
+		},
+		{
+			Key: "isbn:9780451169518",
+			Books: []bookshelf.Book{
+				{ID: 2, Title: "It", Author: "Stephen King", PublicationYear: 1986, PageCount: 1138, ISBN: "9780451169518"},
+				{ID: 3, Title: "It", Author: "S. King", PublicationYear: 1986, PageCount: 1138, ISBN: "9780451169518"},
+			},
+		},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookPostgres_Merge(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	tests := []struct {
+		name    string
+		mock    func()
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectQuery(`SELECT DISTINCT lb.list_id FROM lists_books lb (.+) WHERE lb.book_id = ANY\(\$1\) AND ul.user_id = \$2 AND ul.role IN \('owner', 'editor'\)`).
+					WithArgs("{2,3}", 4).WillReturnRows(sqlmock.NewRows([]string{"list_id"}).AddRow(5).AddRow(6))
+				mock.
+					ExpectExec(`INSERT INTO lists_books(.+) SELECT list_id, \$1 FROM unnest\(\$2::int\[\]\) (.+) DO NOTHING`).
+					WithArgs(1, "{5,6}").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.
+					ExpectExec(`DELETE FROM lists_books WHERE book_id = ANY\(\$1\) AND list_id = ANY\(\$2\)`).
+					WithArgs("{2,3}", "{5,6}").WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.
+					ExpectExec(`DELETE FROM books b WHERE b.id = ANY\(\$1\) AND NOT EXISTS`).
+					WithArgs("{2,3}").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			want: []int{5, 6},
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectQuery("SELECT DISTINCT lb.list_id (.+)").
+					WithArgs("{2,3}", 4).WillReturnRows(sqlmock.NewRows([]string{"list_id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := book.Merge(ctx, 4, 1, []int{2, 3})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookPostgres_Delete(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	type args struct {
+		userID int
+		bookID int
+	}
+	tests := []struct {
+		name    string
+		mock    func()
+		input   args
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectExec("DELETE FROM lists_books lb USING users_lists ul WHERE (.+)").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.
+					ExpectExec("DELETE FROM books b WHERE (.+) NOT EXISTS (.+)").
+					WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			input: args{
+				userID: 1,
+				bookID: 2,
+			},
+		},
+		{
+			name: "Error",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectExec("DELETE FROM lists_books lb USING users_lists ul WHERE (.+)").
+					WithArgs(1, 2).WillReturnError(errors.New("some error"))
+				mock.ExpectRollback()
+			},
+			input: args{
+				userID: 1,
+				bookID: 2,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			err := book.Delete(ctx, tt.input.userID, tt.input.bookID)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookPostgres_Detach(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM books WHERE id = \$1 FOR UPDATE`).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("DELETE FROM lists_books WHERE (.+)").
+					WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`SELECT count\(\*\) FROM lists_books WHERE book_id = \$1`).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Last list",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM books WHERE id = \$1 FOR UPDATE`).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("DELETE FROM lists_books WHERE (.+)").
+					WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`SELECT count\(\*\) FROM lists_books WHERE book_id = \$1`).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectRollback()
+			},
+			wantErr: bookshelf.ErrConflict,
+		},
+		{
+			name: "Not in list",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM books WHERE id = \$1 FOR UPDATE`).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("DELETE FROM lists_books WHERE (.+)").
+					WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			err := book.Detach(ctx, 1, 3)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookPostgres_Move(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	book := NewBookPostgres(db)
+	type args struct {
+		fromListID int
+		toListID   int
+		bookID     int
+	}
+	tests := []struct {
+		name    string
+		mock    func()
+		input   args
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectExec("DELETE FROM lists_books WHERE (.+)").
+					WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.
+					ExpectExec("INSERT INTO lists_books(.+) ON CONFLICT (.+) DO NOTHING").
+					WithArgs(2, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			input: args{
+				fromListID: 1,
+				toListID:   2,
+				bookID:     3,
+			},
+		},
+		{
+			name: "Not in list",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.
+					ExpectExec("DELETE FROM lists_books WHERE (.+)").
+					WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			input: args{
+				fromListID: 1,
+				toListID:   2,
+				bookID:     3,
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			err := book.Move(ctx, tt.input.fromListID, tt.input.toListID, tt.input.bookID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func intPointer(n int) *int {
+	return &n
+}
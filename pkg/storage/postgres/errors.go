@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// wrapError translates driver errors into the error kinds of the bookshelf
+// package. The original error stays in the chain for logging.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", bookshelf.ErrNotFound, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return fmt.Errorf("%w: %w", bookshelf.ErrConflict, err)
+		case "23503":
+			return fmt.Errorf("%w: %w", bookshelf.ErrNotFound, err)
+		case "23502", "23514", "22001", "22P02":
+			return fmt.Errorf("%w: %w", bookshelf.ErrValidation, err)
+		}
+	}
+	return err
+}
+
+// checkAffected reports ErrNotFound when a statement changed no rows.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w", bookshelf.ErrNotFound, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package postgres
 
 import (
 	bookshelf "bookshelf-api"
+	"context"
 	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
 )
 
 type ListPostgres struct {
@@ -13,74 +16,242 @@ func NewListPostgres(db *sql.DB) *ListPostgres {
 	return &ListPostgres{db: db}
 }
 
-func (s *ListPostgres) Create(userID int, list bookshelf.List) (int, error) {
-	tx, err := s.db.Begin()
+func (s *ListPostgres) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	var id int
 	listsQuery := "INSERT INTO lists(title, description) VALUES ($1, $2) RETURNING id"
-	row := tx.QueryRow(listsQuery, list.Title, list.Description)
+	row := tx.QueryRowContext(ctx, listsQuery, list.Title, list.Description)
 	if err := row.Scan(&id); err != nil {
 		tx.Rollback()
-		return 0, err
+		return 0, wrapError(err)
 	}
 
-	usersListsQuery := "INSERT INTO users_lists(user_id, list_id) VALUES ($1, $2)"
-	_, err = tx.Exec(usersListsQuery, userID, id)
+	usersListsQuery := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3)"
+	_, err = tx.ExecContext(ctx, usersListsQuery, userID, id, bookshelf.RoleOwner)
 	if err != nil {
 		tx.Rollback()
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	return id, tx.Commit()
 }
 
-func (s *ListPostgres) GetAll(userID int) ([]bookshelf.List, error) {
+var listSortColumns = map[string]string{
+	"":      "l.id",
+	"title": "l.title",
+}
+
+func (s *ListPostgres) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, error) {
 	var lists []bookshelf.List
-	query := "SELECT l.id, l.title, l.description FROM lists l INNER JOIN users_lists ul ON l.id=ul.list_id WHERE ul.user_id=$1"
-	rows, err := s.db.Query(query, userID)
+	field, desc := bookshelf.SortField(filter.Sort)
+	query, args := page(
+		"SELECT l.id, l.title, l.description FROM lists l INNER JOIN users_lists ul ON l.id=ul.list_id",
+		[]string{"ul.user_id=$1"}, []any{userID},
+		listSortColumns[field], "l.id", desc, filter.After, filter.Limit,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var list bookshelf.List
 		err := rows.Scan(&list.ID, &list.Title, &list.Description)
 		if err != nil {
-			return nil, err
+			return nil, wrapError(err)
 		}
 		lists = append(lists, list)
 	}
-	return lists, err
+	return lists, wrapError(rows.Err())
+}
+
+// Export calls fn for every book in the lists of the user, ordered by list.
+// A listID other than zero restricts the export to that list. Rows are read
+// one at a time, so fn can write them out without buffering the account.
+func (s *ListPostgres) Export(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error {
+	query := "SELECT l.id, l.title, COALESCE(l.description, ''), COALESCE(b.id, 0), COALESCE(b.title, ''), COALESCE(b.author, ''), COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') " +
+		"FROM lists l INNER JOIN users_lists ul ON l.id = ul.list_id LEFT JOIN lists_books lb ON l.id = lb.list_id LEFT JOIN books b ON lb.book_id = b.id " +
+		"WHERE ul.user_id = $1 AND ($2 = 0 OR l.id = $2) ORDER BY l.id, b.id"
+	rows, err := s.db.QueryContext(ctx, query, userID, listID)
+	if err != nil {
+		return wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row bookshelf.ExportRow
+		var book bookshelf.Book
+		err := rows.Scan(&row.List.ID, &row.List.Title, &row.List.Description, &book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		if err != nil {
+			return wrapError(err)
+		}
+		if book.ID != 0 {
+			row.Book = &book
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return wrapError(rows.Err())
 }
 
-func (s *ListPostgres) GetByID(userID, listID int) (bookshelf.List, error) {
+func (s *ListPostgres) GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error) {
 	var list bookshelf.List
 	query := "SELECT l.id, l.title, l.description FROM lists l INNER JOIN users_lists ul ON l.id=ul.list_id WHERE ul.user_id=$1 AND ul.list_id=$2"
-	row := s.db.QueryRow(query, userID, listID)
+	row := s.db.QueryRowContext(ctx, query, userID, listID)
 	err := row.Scan(&list.ID, &list.Title, &list.Description)
 	if err != nil {
-		return bookshelf.List{}, err
+		return bookshelf.List{}, wrapError(err)
 	}
 	return list, nil
 }
 
-func (s *ListPostgres) Update(userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error {
+func (s *ListPostgres) Update(ctx context.Context, userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error {
 	if input.Title == nil {
 		input.Title = &list.Title
 	}
 	if input.Description == nil {
 		input.Description = &list.Description
 	}
-	query := "UPDATE lists l SET title = $1, description = $2 FROM users_lists ul WHERE l.id = ul.list_id AND ul.list_id = $3 AND ul.user_id = $4"
-	_, err := s.db.Exec(query, input.Title, input.Description, listID, userID)
-	return err
+	query := "UPDATE lists l SET title = $1, description = $2 FROM users_lists ul WHERE l.id = ul.list_id AND ul.list_id = $3 AND ul.user_id = $4 AND ul.role = 'owner'"
+	res, err := s.db.ExecContext(ctx, query, input.Title, input.Description, listID, userID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
 }
 
-func (s *ListPostgres) Delete(userID, listID int) error {
-	query := "DELETE FROM lists l USING users_lists ul WHERE l.id=ul.list_id AND ul.user_id=$1 AND ul.list_id=$2"
-	_, err := s.db.Exec(query, userID, listID)
-	return err
+// Delete removes the list together with the books that are not part of any
+// other list.
+func (s *ListPostgres) Delete(ctx context.Context, userID, listID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	var bookIDs []int64
+	booksQuery := "SELECT book_id FROM lists_books WHERE list_id=$1"
+	rows, err := tx.QueryContext(ctx, booksQuery, listID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return wrapError(err)
+		}
+		bookIDs = append(bookIDs, id)
+	}
+	rows.Close()
+
+	listQuery := "DELETE FROM lists l USING users_lists ul WHERE l.id=ul.list_id AND ul.user_id=$1 AND ul.list_id=$2 AND ul.role='owner'"
+	res, err := tx.ExecContext(ctx, listQuery, userID, listID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(bookIDs) > 0 {
+		orphansQuery := "DELETE FROM books b WHERE b.id = ANY($1) AND NOT EXISTS (SELECT 1 FROM lists_books lb WHERE lb.book_id = b.id)"
+		_, err = tx.ExecContext(ctx, orphansQuery, pq.Array(bookIDs))
+		if err != nil {
+			tx.Rollback()
+			return wrapError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *ListPostgres) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	var role string
+	query := "SELECT role FROM users_lists WHERE user_id=$1 AND list_id=$2"
+	err := s.db.QueryRowContext(ctx, query, userID, listID).Scan(&role)
+	return role, wrapError(err)
+}
+
+func (s *ListPostgres) GetMembers(ctx context.Context, listID int) ([]bookshelf.ListMember, error) {
+	var members []bookshelf.ListMember
+	query := "SELECT u.id, u.username, ul.role FROM users u INNER JOIN users_lists ul ON u.id=ul.user_id WHERE ul.list_id=$1 ORDER BY u.id"
+	rows, err := s.db.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member bookshelf.ListMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role); err != nil {
+			return nil, wrapError(err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *ListPostgres) AddMember(ctx context.Context, listID, userID int, role string) error {
+	query := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3)"
+	_, err := s.db.ExecContext(ctx, query, userID, listID, role)
+	return wrapError(err)
+}
+
+// UpdateMember changes the role of a member. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListPostgres) UpdateMember(ctx context.Context, listID, userID int, role string) error {
+	query := "UPDATE users_lists SET role=$3 WHERE list_id=$1 AND user_id=$2"
+	return s.changeMember(ctx, listID, query, listID, userID, role)
+}
+
+// RemoveMember removes a member from the list. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListPostgres) RemoveMember(ctx context.Context, listID, userID int) error {
+	query := "DELETE FROM users_lists WHERE list_id=$1 AND user_id=$2"
+	return s.changeMember(ctx, listID, query, listID, userID)
+}
+
+// changeMember runs a change of the members of the list and rolls it back if
+// no owner is left. The list row is locked first, so concurrent changes of
+// the same list see each other and two owners cannot demote each other.
+func (s *ListPostgres) changeMember(ctx context.Context, listID int, query string, args ...any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	var id int
+	lockQuery := "SELECT id FROM lists WHERE id=$1 FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, lockQuery, listID).Scan(&id); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var owners int
+	ownersQuery := "SELECT count(*) FROM users_lists WHERE list_id=$1 AND role='owner'"
+	if err := tx.QueryRowContext(ctx, ownersQuery, listID).Scan(&owners); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if owners == 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: list must have at least one owner", bookshelf.ErrConflict)
+	}
+	return wrapError(tx.Commit())
 }
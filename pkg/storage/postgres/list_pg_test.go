@@ -2,6 +2,7 @@ package postgres
 
 import (
 	bookshelf "bookshelf-api"
+	"context"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,7 @@ import (
 )
 
 func TestListPostgres_Create(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -35,7 +37,7 @@ func TestListPostgres_Create(t *testing.T) {
 				mock.ExpectBegin()
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
 				mock.ExpectQuery("INSERT INTO lists").WithArgs("title", "description").WillReturnRows(rows)
-				mock.ExpectExec("INSERT INTO users_lists").WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO users_lists").WithArgs(1, 1, bookshelf.RoleOwner).WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
 			input: args{
@@ -69,7 +71,7 @@ func TestListPostgres_Create(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			got, err := list.Create(tt.input.userID, tt.input.list)
+			got, err := list.Create(ctx, tt.input.userID, tt.input.list)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -82,6 +84,7 @@ func TestListPostgres_Create(t *testing.T) {
 }
 
 func TestListPostgres_GetAll(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -94,6 +97,7 @@ func TestListPostgres_GetAll(t *testing.T) {
 		name    string
 		mock    func()
 		userID  int
+		filter  bookshelf.ListFilter
 		want    []bookshelf.List
 		wantErr bool
 	}{
@@ -114,6 +118,25 @@ func TestListPostgres_GetAll(t *testing.T) {
 				{3, "title3", "description3"},
 			},
 		},
+		{
+			name: "Sorted page",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "title", "description"}).
+					AddRow(3, "b", "description3")
+
+				mock.ExpectQuery(`SELECT (.+) FROM lists l INNER JOIN users_lists ul ON (.+) WHERE ul.user_id=\$1 AND \(l.title, l.id\) < \(\$2, \$3\) ORDER BY l.title DESC, l.id DESC LIMIT \$4`).
+					WithArgs(1, "c", 5, 2).WillReturnRows(rows)
+			},
+			userID: 1,
+			filter: bookshelf.ListFilter{
+				Sort:  "-title",
+				Limit: 2,
+				After: &bookshelf.Cursor{Sort: "-title", Value: "c", ID: 5},
+			},
+			want: []bookshelf.List{
+				{ID: 3, Title: "b", Description: "description3"},
+			},
+		},
 		{
 			name: "Error",
 			mock: func() {
@@ -126,7 +149,7 @@ func TestListPostgres_GetAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := list.GetAll(tt.userID)
+			got, err := list.GetAll(ctx, tt.userID, tt.filter)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -139,6 +162,7 @@ func TestListPostgres_GetAll(t *testing.T) {
 }
 
 func TestListPostgres_GetByID(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -189,7 +213,7 @@ func TestListPostgres_GetByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			got, err := list.GetByID(tt.input.userID, tt.input.listID)
+			got, err := list.GetByID(ctx, tt.input.userID, tt.input.listID)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -202,6 +226,7 @@ func TestListPostgres_GetByID(t *testing.T) {
 }
 
 func TestListPostgres_Update(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -259,13 +284,31 @@ func TestListPostgres_Update(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Not found",
+			mock: func() {
+				mock.
+					ExpectExec("UPDATE lists l SET (.+) FROM users_lists ul WHERE (.+)").
+					WithArgs("title", "description", 2, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			input: args{
+				userID: 1,
+				listID: 2,
+				listItem: bookshelf.List{
+					ID:          2,
+					Title:       "title",
+					Description: "description",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			err := list.Update(tt.input.userID, tt.input.listID, tt.input.listItem, tt.input.input)
+			err := list.Update(ctx, tt.input.userID, tt.input.listID, tt.input.listItem, tt.input.input)
 			if tt.wantErr {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, bookshelf.ErrNotFound)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -275,6 +318,7 @@ func TestListPostgres_Update(t *testing.T) {
 }
 
 func TestListPostgres_Delete(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -295,20 +339,43 @@ func TestListPostgres_Delete(t *testing.T) {
 		{
 			name: "OK",
 			mock: func() {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"book_id"}).AddRow(1).AddRow(2)
+				mock.ExpectQuery("SELECT book_id FROM lists_books WHERE (.+)").WithArgs(1).WillReturnRows(rows)
 				mock.
 					ExpectExec("DELETE FROM lists l USING users_lists ul WHERE (.+)").
 					WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.
+					ExpectExec("DELETE FROM books b WHERE (.+) NOT EXISTS (.+)").
+					WithArgs("{1,2}").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			input: args{
 				userID: 1,
 				listID: 1,
 			},
 		},
+		{
+			name: "Empty list",
+			mock: func() {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"book_id"})
+				mock.ExpectQuery("SELECT book_id FROM lists_books WHERE (.+)").WithArgs(2).WillReturnRows(rows)
+				mock.
+					ExpectExec("DELETE FROM lists l USING users_lists ul WHERE (.+)").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			input: args{
+				userID: 1,
+				listID: 2,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			err := list.Delete(tt.input.userID, tt.input.listID)
+			err := list.Delete(ctx, tt.input.userID, tt.input.listID)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -322,3 +389,102 @@ func TestListPostgres_Delete(t *testing.T) {
 func stringPointer(s string) *string {
 	return &s
 }
+
+func TestListPostgres_UpdateMember(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	list := NewListPostgres(db)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		role    string
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM lists WHERE (.+) FOR UPDATE").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE users_lists SET role").WithArgs(1, 2, bookshelf.RoleEditor).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT count(.+) FROM users_lists").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			role: bookshelf.RoleEditor,
+		},
+		{
+			name: "Last owner",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM lists WHERE (.+) FOR UPDATE").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE users_lists SET role").WithArgs(1, 2, bookshelf.RoleViewer).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT count(.+) FROM users_lists").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectRollback()
+			},
+			role:    bookshelf.RoleViewer,
+			wantErr: bookshelf.ErrConflict,
+		},
+		{
+			name: "Not a member",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM lists WHERE (.+) FOR UPDATE").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE users_lists SET role").WithArgs(1, 2, bookshelf.RoleEditor).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			role:    bookshelf.RoleEditor,
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := list.UpdateMember(ctx, 1, 2, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestListPostgres_Export(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	list := NewListPostgres(db)
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "book_id", "book_title", "author", "publisher", "publication_year", "page_count", "isbn"}).
+		AddRow(1, "Sci-fi", "", 3, "Dune", "Frank Herbert", "Ace", 1965, 412, "9780441172719").
+		AddRow(2, "Empty", "nothing yet", 0, "", "", "", 0, 0, "")
+	mock.ExpectQuery(`SELECT (.+) FROM lists l INNER JOIN users_lists ul ON (.+) LEFT JOIN lists_books lb ON (.+) LEFT JOIN books b ON (.+) WHERE ul.user_id = \$1 AND \(\$2 = 0 OR l.id = \$2\) ORDER BY l.id, b.id`).
+		WithArgs(1, 0).WillReturnRows(rows)
+
+	var got []bookshelf.ExportRow
+	err = list.Export(ctx, 1, 0, func(row bookshelf.ExportRow) error {
+		got = append(got, row)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.ExportRow{
+		{
+			List: bookshelf.List{ID: 1, Title: "Sci-fi"},
+			Book: &bookshelf.Book{ID: 3, Title: "Dune", Author: "Frank Herbert", Publisher: "Ace", PublicationYear: 1965, PageCount: 412, ISBN: "9780441172719"},
+		},
+		{
+			List: bookshelf.List{ID: 2, Title: "Empty", Description: "nothing yet"},
+		},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,270 @@
+package postgres
+
+import (
+	"bookshelf-api/db"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// migrationLockID is the key of the advisory lock held while migrating, so
+// instances started at the same time do not apply a migration twice.
+const migrationLockID = 7283019224
+
+// MigrationStatus is a known migration and when it has been applied, nil if
+// it is pending.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies schema migrations and records them in the
+// schema_migrations table. Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []db.Migration
+}
+
+func NewMigrator(conn *sql.DB, migrations []db.Migration) *Migrator {
+	return &Migrator{db: conn, migrations: migrations}
+}
+
+// Up applies every pending migration and returns the applied ones.
+func (m *Migrator) Up() ([]db.Migration, error) {
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+	return m.To(m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the latest applied migration.
+func (m *Migrator) Down() ([]db.Migration, error) {
+	var done []db.Migration
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		versions := appliedVersions(applied)
+		if len(versions) == 0 {
+			return nil
+		}
+		migration, err := m.find(versions[len(versions)-1])
+		if err != nil {
+			return err
+		}
+		if err := revert(conn, migration); err != nil {
+			return err
+		}
+		done = append(done, migration)
+		return nil
+	})
+	return done, err
+}
+
+// To migrates up or down until version is the latest applied migration.
+// Version 0 reverts every migration.
+func (m *Migrator) To(version int) ([]db.Migration, error) {
+	if version != 0 {
+		if _, err := m.find(version); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []db.Migration
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		if len(applied) == 0 && version != 0 {
+			if err := checkUnmanaged(conn); err != nil {
+				return err
+			}
+		}
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			migration, err := m.find(versions[i])
+			if err != nil {
+				return err
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Force records the migrations up to version as applied and the later ones
+// as pending, without running any of them. It baselines databases whose
+// schema was set up by hand and repairs the history after a failed manual
+// fix. The migrations whose record changed are returned.
+func (m *Migrator) Force(version int) ([]db.Migration, error) {
+	if version != 0 {
+		if _, err := m.find(version); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []db.Migration
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		return inTx(conn, func(tx *sql.Tx) error {
+			for _, migration := range m.migrations {
+				_, ok := applied[migration.Version]
+				switch {
+				case migration.Version <= version && !ok:
+					_, err := tx.Exec("INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+					if err != nil {
+						return err
+					}
+				case migration.Version > version && ok:
+					if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+						return err
+					}
+				default:
+					continue
+				}
+				done = append(done, migration)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+// Status lists the known migrations together with when they were applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.locked(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, migration := range m.migrations {
+			s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if at, ok := applied[migration.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+	return status, err
+}
+
+func (m *Migrator) find(version int) (db.Migration, error) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, nil
+		}
+	}
+	return db.Migration{}, fmt.Errorf("migration %d is unknown", version)
+}
+
+// locked runs fn on a single connection holding the migration lock, with the
+// versions applied so far. Advisory locks belong to a session, so everything
+// has to use the same connection.
+func (m *Migrator) locked(fn func(conn *sql.Conn, applied map[int]time.Time) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	createQuery := "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint primary key, name text not null, applied_at timestamptz not null default now())"
+	if _, err := conn.ExecContext(ctx, createQuery); err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return fn(conn, applied)
+}
+
+// checkUnmanaged fails if the schema exists but none of it was migrated,
+// which is the case for databases set up by hand with psql. Applying the
+// first migration would fail on the existing tables halfway through.
+func checkUnmanaged(conn *sql.Conn) error {
+	var exists bool
+	if err := conn.QueryRowContext(context.Background(), "SELECT to_regclass('users') IS NOT NULL").Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("schema exists but no migration has been recorded, run migrate force with the version it matches")
+	}
+	return nil
+}
+
+func apply(conn *sql.Conn, migration db.Migration) error {
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec("INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		return err
+	})
+}
+
+func revert(conn *sql.Conn, migration db.Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+	}
+	return inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+func inTx(conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func appliedVersions(applied map[int]time.Time) []int {
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
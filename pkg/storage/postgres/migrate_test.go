@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"bookshelf-api/db"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMigrator(t *testing.T) {
+	migrations := []db.Migration{
+		{Version: 1, Name: "init", Up: "CREATE TABLE users", Down: "DROP TABLE users"},
+		{Version: 2, Name: "books", Up: "CREATE TABLE books", Down: "DROP TABLE books"},
+		{Version: 3, Name: "lists", Up: "CREATE TABLE lists"},
+	}
+	appliedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	expectLock := func(mock sqlmock.Sqlmock, applied ...int) {
+		mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+		rows := sqlmock.NewRows([]string{"version", "applied_at"})
+		for _, version := range applied {
+			rows.AddRow(version, appliedAt)
+		}
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+	}
+	expectUnlock := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	expectSchema := func(mock sqlmock.Sqlmock, exists bool) {
+		mock.ExpectQuery("SELECT to_regclass").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+	}
+
+	tests := []struct {
+		name     string
+		run      func(m *Migrator) ([]db.Migration, error)
+		mock     func(mock sqlmock.Sqlmock)
+		wantDone []int
+		wantErr  string
+	}{
+		{
+			name: "Up",
+			run:  (*Migrator).Up,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				for _, m := range migrations[1:] {
+					mock.ExpectBegin()
+					mock.ExpectExec(m.Up).WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(m.Version, m.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				}
+				expectUnlock(mock)
+			},
+			wantDone: []int{2, 3},
+		},
+		{
+			name: "Down",
+			run:  (*Migrator).Down,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock, 1, 2)
+				mock.ExpectBegin()
+				mock.ExpectExec("DROP TABLE books").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectUnlock(mock)
+			},
+			wantDone: []int{2},
+		},
+		{
+			name: "To",
+			run: func(m *Migrator) ([]db.Migration, error) {
+				return m.To(1)
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock, 1, 2)
+				mock.ExpectBegin()
+				mock.ExpectExec("DROP TABLE books").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectUnlock(mock)
+			},
+			wantDone: []int{2},
+		},
+		{
+			name: "Irreversible",
+			run: func(m *Migrator) ([]db.Migration, error) {
+				return m.To(2)
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock, 1, 2, 3)
+				expectUnlock(mock)
+			},
+			wantErr: "migration 3_lists cannot be reverted",
+		},
+		{
+			name: "Unknown version",
+			run: func(m *Migrator) ([]db.Migration, error) {
+				return m.To(4)
+			},
+			mock:    func(mock sqlmock.Sqlmock) {},
+			wantErr: "migration 4 is unknown",
+		},
+		{
+			name: "Unmanaged schema",
+			run:  (*Migrator).Up,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectSchema(mock, true)
+				expectUnlock(mock)
+			},
+			wantErr: "schema exists but no migration has been recorded, run migrate force with the version it matches",
+		},
+		{
+			name: "Force baseline",
+			run: func(m *Migrator) ([]db.Migration, error) {
+				return m.Force(2)
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(1, "init").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "books").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectUnlock(mock)
+			},
+			wantDone: []int{1, 2},
+		},
+		{
+			name: "Force down",
+			run: func(m *Migrator) ([]db.Migration, error) {
+				return m.Force(1)
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock, 1, 2, 3)
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectUnlock(mock)
+			},
+			wantDone: []int{2, 3},
+		},
+		{
+			name: "Failed migration",
+			run:  (*Migrator).Up,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectSchema(mock, false)
+				mock.ExpectBegin()
+				mock.ExpectExec("CREATE TABLE users").WillReturnError(errors.New("syntax error"))
+				mock.ExpectRollback()
+				expectUnlock(mock)
+			},
+			wantErr: "migration 1_init: syntax error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer conn.Close()
+			tt.mock(mock)
+
+			done, err := tt.run(NewMigrator(conn, migrations))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			var versions []int
+			for _, m := range done {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tt.wantDone, versions)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	appliedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, appliedAt))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	migrator := NewMigrator(conn, []db.Migration{{Version: 1, Name: "init"}, {Version: 2, Name: "books"}})
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, []MigrationStatus{
+		{Version: 1, Name: "init", AppliedAt: &appliedAt},
+		{Version: 2, Name: "books"},
+	}, status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"fmt"
+	"strings"
+)
+
+// page appends the conditions, the keyset condition of the cursor, the order
+// and the limit to query. Rows are ordered by sortColumn with idColumn as a
+// tie breaker, so the cursor always points at a single row.
+func page(query string, conds []string, args []any, sortColumn, idColumn string, desc bool, after *bookshelf.Cursor, limit int) (string, []any) {
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+
+	if after != nil {
+		if sortColumn == idColumn {
+			args = append(args, after.ID)
+			conds = append(conds, fmt.Sprintf("%s %s $%d", idColumn, op, len(args)))
+		} else {
+			args = append(args, after.Value, after.ID)
+			conds = append(conds, fmt.Sprintf("(%s, %s) %s ($%d, $%d)", sortColumn, idColumn, op, len(args)-1, len(args)))
+		}
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	if sortColumn == idColumn {
+		query += fmt.Sprintf(" ORDER BY %s %s", idColumn, dir)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, %s %s", sortColumn, dir, idColumn, dir)
+	}
+
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
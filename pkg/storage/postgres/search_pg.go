@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type SearchPostgres struct {
+	db *sql.DB
+}
+
+func NewSearchPostgres(db *sql.DB) *SearchPostgres {
+	return &SearchPostgres{db: db}
+}
+
+// searchHitsQuery matches the books and lists the user has access to. The
+// headline is only built for the rows of the requested page, delimited by
+// textmatch.StartMark and textmatch.StopMark, as ts_headline does not
+// escape the text around the matches.
+const searchHitsQuery = `SELECT kind, id, title, document, rank FROM (
+	SELECT 'book' AS kind, b.id, b.title, concat_ws(' ', b.title, b.author, b.publisher) AS document, ts_rank(b.search, q) AS rank
+	FROM books b, websearch_to_tsquery('simple', $1) q
+	WHERE b.search @@ q AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $2)
+	UNION ALL
+	SELECT 'list' AS kind, l.id, l.title, concat_ws(' ', l.title, l.description) AS document, ts_rank(l.search, q) AS rank
+	FROM lists l INNER JOIN users_lists ul ON l.id = ul.list_id, websearch_to_tsquery('simple', $1) q
+	WHERE l.search @@ q AND ul.user_id = $2
+) hits`
+
+func (s *SearchPostgres) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, error) {
+	var hits []bookshelf.SearchHit
+	args := []any{query.Query, userID}
+	where := ""
+	if query.After != nil {
+		args = append(args, query.After.Value, query.After.Kind, query.After.ID)
+		where = " WHERE (rank, kind, id) < ($3, $4, $5)"
+	}
+	limit := ""
+	if query.Limit > 0 {
+		args = append(args, query.Limit)
+		limit = fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	sqlQuery := "SELECT kind, id, title, ts_headline('simple', document, websearch_to_tsquery('simple', $1), 'StartSel=' || chr(2) || ', StopSel=' || chr(3)), rank FROM (" +
+		searchHitsQuery + where + " ORDER BY rank DESC, kind DESC, id DESC" + limit +
+		") page ORDER BY rank DESC, kind DESC, id DESC"
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit bookshelf.SearchHit
+		if err := rows.Scan(&hit.Kind, &hit.ID, &hit.Title, &hit.Headline, &hit.Rank); err != nil {
+			return nil, wrapError(err)
+		}
+		hit.Headline = textmatch.Headline(hit.Headline)
+		hits = append(hits, hit)
+	}
+	return hits, wrapError(rows.Err())
+}
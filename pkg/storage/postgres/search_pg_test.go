@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSearchPostgres_Search(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	search := NewSearchPostgres(db)
+	columns := []string{"kind", "id", "title", "headline", "rank"}
+	tests := []struct {
+		name  string
+		mock  func()
+		query bookshelf.SearchQuery
+		want  []bookshelf.SearchHit
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).
+					AddRow("book", 1, "Dune", "\x02Dune\x03 Frank Herbert &", 0.6).
+					AddRow("list", 2, "Sci-fi", "Sci-fi <b>like</b> \x02Dune\x03", 0.3)
+				mock.ExpectQuery(`SELECT kind, id, title, ts_headline\(.+ ORDER BY rank DESC, kind DESC, id DESC LIMIT \$3\) page`).
+					WithArgs("dune", 1, 10).WillReturnRows(rows)
+			},
+			query: bookshelf.SearchQuery{Query: "dune", Limit: 10},
+			want: []bookshelf.SearchHit{
+				{Kind: "book", ID: 1, Title: "Dune", Headline: "<mark>Dune</mark> Frank Herbert &amp;", Rank: 0.6},
+				{Kind: "list", ID: 2, Title: "Sci-fi", Headline: "Sci-fi &lt;b&gt;like&lt;/b&gt; <mark>Dune</mark>", Rank: 0.3},
+			},
+		},
+		{
+			name: "After cursor",
+			mock: func() {
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(`WHERE \(rank, kind, id\) < \(\$3, \$4, \$5\) ORDER BY (.+) LIMIT \$6\) page`).
+					WithArgs("dune", 1, 0.3, "list", 2, 10).WillReturnRows(rows)
+			},
+			query: bookshelf.SearchQuery{
+				Query: "dune",
+				Limit: 10,
+				After: &bookshelf.Cursor{Value: 0.3, Kind: "list", ID: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			got, err := search.Search(ctx, 1, tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+)
+
+type SharePostgres struct {
+	db *sql.DB
+}
+
+func NewSharePostgres(db *sql.DB) *SharePostgres {
+	return &SharePostgres{db: db}
+}
+
+func (s *SharePostgres) Create(ctx context.Context, share bookshelf.ListShare) (int, error) {
+	var id int
+	query := "INSERT INTO list_shares(list_id, created_by, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id"
+	row := s.db.QueryRowContext(ctx, query, share.ListID, share.CreatedBy, share.TokenHash, share.ExpiresAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, wrapError(err)
+	}
+	return id, nil
+}
+
+func (s *SharePostgres) GetAll(ctx context.Context, listID int) ([]bookshelf.ListShare, error) {
+	var shares []bookshelf.ListShare
+	query := "SELECT id, list_id, created_by, token_hash, expires_at, revoked_at IS NOT NULL, created_at FROM list_shares WHERE list_id=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now()) ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		share, err := scanListShare(rows)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *SharePostgres) GetByHash(ctx context.Context, tokenHash string) (bookshelf.ListShare, error) {
+	query := "SELECT id, list_id, created_by, token_hash, expires_at, revoked_at IS NOT NULL, created_at FROM list_shares WHERE token_hash=$1"
+	return scanListShare(s.db.QueryRowContext(ctx, query, tokenHash))
+}
+
+func (s *SharePostgres) Revoke(ctx context.Context, listID, shareID int) error {
+	query := "UPDATE list_shares SET revoked_at=now() WHERE list_id=$1 AND id=$2 AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, listID, shareID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
+}
+
+func scanListShare(row scanner) (bookshelf.ListShare, error) {
+	var share bookshelf.ListShare
+	var expiresAt sql.NullTime
+	err := row.Scan(&share.ID, &share.ListID, &share.CreatedBy, &share.TokenHash, &expiresAt, &share.Revoked, &share.CreatedAt)
+	if err != nil {
+		return bookshelf.ListShare{}, wrapError(err)
+	}
+	if expiresAt.Valid {
+		share.ExpiresAt = &expiresAt.Time
+	}
+	return share, nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSharePostgres_GetAll(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "list_id", "created_by", "token_hash", "expires_at", "revoked", "created_at"}).
+		AddRow(1, 2, 3, "hash", expiresAt, false, createdAt)
+	// Revoked and expired links are left out.
+	mock.ExpectQuery("SELECT (.+) FROM list_shares WHERE list_id=(.+) AND revoked_at IS NULL AND \\(expires_at IS NULL OR expires_at > now\\(\\)\\)").
+		WithArgs(2).WillReturnRows(rows)
+
+	got, err := NewSharePostgres(db).GetAll(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.ListShare{
+		{ID: 1, ListID: 2, CreatedBy: 3, TokenHash: "hash", ExpiresAt: &expiresAt, CreatedAt: createdAt},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSharePostgres_Revoke(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	shares := NewSharePostgres(db)
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectExec("UPDATE list_shares SET revoked_at=now\\(\\) WHERE (.+) AND revoked_at IS NULL").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Already revoked",
+			mock: func() {
+				mock.ExpectExec("UPDATE list_shares SET revoked_at=now\\(\\) WHERE (.+) AND revoked_at IS NULL").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: bookshelf.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			assert.ErrorIs(t, shares.Revoke(ctx, 1, 2), tt.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
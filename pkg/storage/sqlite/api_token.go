@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+type APITokenSQLite struct {
+	db *sql.DB
+}
+
+func NewAPITokenSQLite(db *sql.DB) *APITokenSQLite {
+	return &APITokenSQLite{db: db}
+}
+
+func (s *APITokenSQLite) Create(ctx context.Context, token bookshelf.APIToken) (int, error) {
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	query := "INSERT INTO api_tokens(user_id, name, prefix, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	row := s.db.QueryRowContext(ctx, query, token.UserID, token.Name, token.Prefix, token.TokenHash, string(scopes), token.ExpiresAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, wrapError(err)
+	}
+	return id, nil
+}
+
+func (s *APITokenSQLite) GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error) {
+	var tokens []bookshelf.APIToken
+	query := "SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE user_id=$1 ORDER BY id"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *APITokenSQLite) GetByHash(ctx context.Context, tokenHash string) (bookshelf.APIToken, error) {
+	query := "SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE token_hash=$1"
+	return scanAPIToken(s.db.QueryRowContext(ctx, query, tokenHash))
+}
+
+func (s *APITokenSQLite) Touch(ctx context.Context, tokenID int) error {
+	query := "UPDATE api_tokens SET last_used_at=CURRENT_TIMESTAMP WHERE id=$1"
+	_, err := s.db.ExecContext(ctx, query, tokenID)
+	return wrapError(err)
+}
+
+func (s *APITokenSQLite) Delete(ctx context.Context, userID, tokenID int) error {
+	query := "DELETE FROM api_tokens WHERE user_id=$1 AND id=$2"
+	res, err := s.db.ExecContext(ctx, query, userID, tokenID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIToken(row scanner) (bookshelf.APIToken, error) {
+	var token bookshelf.APIToken
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.TokenHash,
+		&scopes, &expiresAt, &lastUsedAt, &token.CreatedAt)
+	if err != nil {
+		return bookshelf.APIToken{}, wrapError(err)
+	}
+	if err := json.Unmarshal([]byte(scopes), &token.Scopes); err != nil {
+		return bookshelf.APIToken{}, err
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type AuthSQLite struct {
+	db *sql.DB
+}
+
+func NewAuthSQLite(db *sql.DB) *AuthSQLite {
+	return &AuthSQLite{db: db}
+}
+
+func (s *AuthSQLite) CreateUser(ctx context.Context, user bookshelf.User) (int, error) {
+	var id int
+	query := "INSERT INTO users(username, password_hash) VALUES ($1, $2) RETURNING id"
+	row := s.db.QueryRowContext(ctx, query, user.Username, user.Password)
+	if err := row.Scan(&id); err != nil {
+		return 0, wrapError(err)
+	}
+	return id, nil
+}
+
+func (s *AuthSQLite) GetUser(ctx context.Context, username string) (bookshelf.User, error) {
+	var user bookshelf.User
+	query := "SELECT id, username, password_hash FROM users WHERE username=$1"
+	err := s.db.QueryRowContext(ctx, query, username).Scan(&user.ID, &user.Username, &user.Password)
+	return user, wrapError(err)
+}
+
+func (s *AuthSQLite) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	query := "UPDATE users SET password_hash=$1 WHERE id=$2"
+	_, err := s.db.ExecContext(ctx, query, hash, userID)
+	return wrapError(err)
+}
+
+func (s *AuthSQLite) CreateRefreshToken(ctx context.Context, token bookshelf.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	_, err := s.db.ExecContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	return wrapError(err)
+}
+
+func (s *AuthSQLite) GetRefreshToken(ctx context.Context, tokenHash string) (bookshelf.RefreshToken, error) {
+	var token bookshelf.RefreshToken
+	query := "SELECT id, user_id, family_id, token_hash, expires_at, used_at IS NOT NULL, revoked_at IS NOT NULL FROM refresh_tokens WHERE token_hash=$1"
+	row := s.db.QueryRowContext(ctx, query, tokenHash)
+	err := row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.Used, &token.Revoked)
+	if err != nil {
+		return bookshelf.RefreshToken{}, wrapError(err)
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token as used and reports whether it was still
+// unused. Concurrent attempts to use the same token get false.
+func (s *AuthSQLite) UseRefreshToken(ctx context.Context, id int) (bool, error) {
+	query := "UPDATE refresh_tokens SET used_at=CURRENT_TIMESTAMP WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, wrapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return n == 1, nil
+}
+
+func (s *AuthSQLite) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at=CURRENT_TIMESTAMP WHERE family_id=$1 AND revoked_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, familyID)
+	return wrapError(err)
+}
+
+func (s *AuthSQLite) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := "INSERT INTO revoked_tokens(jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING"
+	if _, err := s.db.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		return wrapError(err)
+	}
+	cleanupQuery := "DELETE FROM revoked_tokens WHERE julianday(expires_at) < julianday('now')"
+	_, err := s.db.ExecContext(ctx, cleanupQuery)
+	return wrapError(err)
+}
+
+func (s *AuthSQLite) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	query := "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti=$1)"
+	err := s.db.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, wrapError(err)
+}
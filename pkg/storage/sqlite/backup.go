@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type BackupSQLite struct {
+	db *sql.DB
+}
+
+func NewBackupSQLite(db *sql.DB) *BackupSQLite {
+	return &BackupSQLite{db: db}
+}
+
+// Dump reads the lists of the user, their books and members. All queries run
+// in one read only transaction, so the backup is consistent.
+func (s *BackupSQLite) Dump(ctx context.Context, userID int) (bookshelf.Backup, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return bookshelf.Backup{}, wrapError(err)
+	}
+	defer tx.Rollback()
+
+	backup := bookshelf.Backup{
+		Lists:   []bookshelf.BackupList{},
+		Books:   []bookshelf.Book{},
+		Entries: []bookshelf.BackupEntry{},
+		Members: []bookshelf.BackupMember{},
+	}
+
+	listsQuery := "SELECT l.id, l.title, COALESCE(l.description, ''), ul.role FROM lists l INNER JOIN users_lists ul ON l.id = ul.list_id WHERE ul.user_id = $1 ORDER BY l.id"
+	err = query(ctx, tx, listsQuery, userID, func(rows *sql.Rows) error {
+		var list bookshelf.BackupList
+		err := rows.Scan(&list.ID, &list.Title, &list.Description, &list.Role)
+		backup.Lists = append(backup.Lists, list)
+		return err
+	})
+	if err != nil {
+		return bookshelf.Backup{}, err
+	}
+
+	booksQuery := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b WHERE EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $1) ORDER BY b.id"
+	err = query(ctx, tx, booksQuery, userID, func(rows *sql.Rows) error {
+		var book bookshelf.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		backup.Books = append(backup.Books, book)
+		return err
+	})
+	if err != nil {
+		return bookshelf.Backup{}, err
+	}
+
+	entriesQuery := "SELECT lb.list_id, lb.book_id FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE ul.user_id = $1 ORDER BY lb.list_id, lb.book_id"
+	err = query(ctx, tx, entriesQuery, userID, func(rows *sql.Rows) error {
+		var entry bookshelf.BackupEntry
+		err := rows.Scan(&entry.ListID, &entry.BookID)
+		backup.Entries = append(backup.Entries, entry)
+		return err
+	})
+	if err != nil {
+		return bookshelf.Backup{}, err
+	}
+
+	membersQuery := "SELECT m.list_id, u.username, m.role FROM users_lists ul INNER JOIN users_lists m ON ul.list_id = m.list_id INNER JOIN users u ON m.user_id = u.id WHERE ul.user_id = $1 AND m.user_id <> $1 ORDER BY m.list_id, u.username"
+	err = query(ctx, tx, membersQuery, userID, func(rows *sql.Rows) error {
+		var member bookshelf.BackupMember
+		err := rows.Scan(&member.ListID, &member.Username, &member.Role)
+		backup.Members = append(backup.Members, member)
+		return err
+	})
+	if err != nil {
+		return bookshelf.Backup{}, err
+	}
+	return backup, nil
+}
+
+// Restore recreates the backup for the user in a single transaction. Lists
+// the user can edit are reused by title and books of the user by ISBN.
+// Members are only added to lists created by the restore, merging must not
+// grant access to lists the user does not own.
+func (s *BackupSQLite) Restore(ctx context.Context, userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return bookshelf.RestoreResult{}, wrapError(err)
+	}
+	result, err := restore(ctx, tx, userID, backup)
+	if err != nil {
+		tx.Rollback()
+		return bookshelf.RestoreResult{}, err
+	}
+	return result, wrapError(tx.Commit())
+}
+
+func restore(ctx context.Context, tx *sql.Tx, userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error) {
+	result := bookshelf.RestoreResult{
+		ListIDs:        make(map[int]int, len(backup.Lists)),
+		BookIDs:        make(map[int]int, len(backup.Books)),
+		SkippedMembers: []string{},
+	}
+
+	titles := make(map[string]int)
+	existingQuery := "SELECT l.id, l.title FROM lists l INNER JOIN users_lists ul ON l.id = ul.list_id WHERE ul.user_id = $1 AND ul.role IN ('owner', 'editor') ORDER BY l.id"
+	err := query(ctx, tx, existingQuery, userID, func(rows *sql.Rows) error {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return err
+		}
+		if _, ok := titles[strings.ToLower(title)]; !ok {
+			titles[strings.ToLower(title)] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return bookshelf.RestoreResult{}, err
+	}
+
+	created := make(map[int]bool)
+	for _, list := range backup.Lists {
+		if id, ok := titles[strings.ToLower(list.Title)]; ok {
+			result.ListIDs[list.ID] = id
+			continue
+		}
+		var id int
+		listQuery := "INSERT INTO lists(title, description) VALUES ($1, $2) RETURNING id"
+		if err := tx.QueryRowContext(ctx, listQuery, list.Title, list.Description).Scan(&id); err != nil {
+			return bookshelf.RestoreResult{}, wrapError(err)
+		}
+		ownerQuery := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3)"
+		if _, err := tx.ExecContext(ctx, ownerQuery, userID, id, bookshelf.RoleOwner); err != nil {
+			return bookshelf.RestoreResult{}, wrapError(err)
+		}
+		titles[strings.ToLower(list.Title)] = id
+		result.ListIDs[list.ID] = id
+		created[id] = true
+		result.ListsCreated++
+	}
+
+	for _, book := range backup.Books {
+		var id int
+		if book.ISBN != "" {
+			isbnQuery := "SELECT id FROM books WHERE user_id = $1 AND isbn = $2"
+			err := tx.QueryRowContext(ctx, isbnQuery, userID, book.ISBN).Scan(&id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return bookshelf.RestoreResult{}, wrapError(err)
+			}
+		}
+		if id == 0 {
+			bookQuery := "INSERT INTO books(user_id, title, author, publisher, publication_year, page_count, isbn) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')) RETURNING id"
+			err := tx.QueryRowContext(ctx, bookQuery, userID, book.Title, book.Author, book.Publisher, book.PublicationYear, book.PageCount, book.ISBN).Scan(&id)
+			if err != nil {
+				return bookshelf.RestoreResult{}, wrapError(err)
+			}
+			result.BooksCreated++
+		}
+		result.BookIDs[book.ID] = id
+	}
+
+	for _, entry := range backup.Entries {
+		entryQuery := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2) ON CONFLICT (list_id, book_id) DO NOTHING"
+		res, err := tx.ExecContext(ctx, entryQuery, result.ListIDs[entry.ListID], result.BookIDs[entry.BookID])
+		if err != nil {
+			return bookshelf.RestoreResult{}, wrapError(err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return bookshelf.RestoreResult{}, err
+		}
+		result.Entries += int(n)
+	}
+
+	for _, member := range backup.Members {
+		listID := result.ListIDs[member.ListID]
+		if !created[listID] {
+			continue
+		}
+		var memberID int
+		userQuery := "SELECT id FROM users WHERE username = $1"
+		err := tx.QueryRowContext(ctx, userQuery, member.Username).Scan(&memberID)
+		if errors.Is(err, sql.ErrNoRows) {
+			result.SkippedMembers = append(result.SkippedMembers, member.Username)
+			continue
+		}
+		if err != nil {
+			return bookshelf.RestoreResult{}, wrapError(err)
+		}
+		if memberID == userID {
+			continue
+		}
+		memberQuery := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3) ON CONFLICT (user_id, list_id) DO NOTHING"
+		if _, err := tx.ExecContext(ctx, memberQuery, memberID, listID, member.Role); err != nil {
+			return bookshelf.RestoreResult{}, wrapError(err)
+		}
+		result.Members++
+	}
+	return result, nil
+}
+
+// query runs a query with a single user ID argument and calls scan for every
+// row.
+func query(ctx context.Context, tx *sql.Tx, q string, userID int, scan func(*sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, q, userID)
+	if err != nil {
+		return wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return wrapError(err)
+		}
+	}
+	return wrapError(rows.Err())
+}
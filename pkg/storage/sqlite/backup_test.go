@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBackupSQLite_Restore(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, _ := newTestDB(t)
+	backup, err := NewBackupSQLite(conn).Dump(ctx, userID)
+	require.NoError(t, err)
+
+	target, targetUserID, _, _ := newTestDB(t)
+	result, err := NewBackupSQLite(target).Restore(ctx, targetUserID, backup)
+	assert.NoError(t, err)
+	// The list is merged into the list with the same title, the book is new.
+	assert.Equal(t, 0, result.ListsCreated)
+	assert.Equal(t, 1, result.BooksCreated)
+	assert.Equal(t, 1, result.Entries)
+	count, err := NewBookSQLite(target).CountLists(ctx, result.BookIDs[backup.Books[0].ID])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, map[int]int{listID: 1}, result.ListIDs)
+}
+
+func TestBackupSQLite_RestoreRollback(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, _, _ := newTestDB(t)
+	backup := bookshelf.Backup{
+		Lists:   []bookshelf.BackupList{{List: bookshelf.List{ID: 1, Title: "New"}, Role: bookshelf.RoleOwner}},
+		Entries: []bookshelf.BackupEntry{{ListID: 1, BookID: 5}},
+	}
+
+	_, err := NewBackupSQLite(conn).Restore(ctx, userID, backup)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	lists, err := NewListSQLite(conn).GetAll(ctx, userID, bookshelf.ListFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, lists, 1)
+}
@@ -0,0 +1,405 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+type BookSQLite struct {
+	db *sql.DB
+}
+
+func NewBookSQLite(db *sql.DB) *BookSQLite {
+	return &BookSQLite{db: db}
+}
+
+func (s *BookSQLite) Create(ctx context.Context, listID int, book bookshelf.Book) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	var bookID int
+	createBookQuery := "INSERT INTO books(user_id, title, author, publisher, publication_year, page_count, isbn) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')) RETURNING id"
+	row := tx.QueryRowContext(ctx, createBookQuery, book.UserID, book.Title, book.Author, book.Publisher, book.PublicationYear, book.PageCount, book.ISBN)
+	err = row.Scan(&bookID)
+	if err != nil {
+		tx.Rollback()
+		return 0, wrapError(err)
+	}
+
+	createListsBooksQuery := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2)"
+	_, err = tx.ExecContext(ctx, createListsBooksQuery, listID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return 0, wrapError(err)
+	}
+	return bookID, tx.Commit()
+}
+
+// bookSortColumns maps sort fields to columns. Nullable columns sort as
+// their zero value, which is also what the cursor of such a row holds,
+// otherwise the keyset condition would skip rows with NULLs.
+var bookSortColumns = map[string]string{
+	"":                 "b.id",
+	"title":            "b.title",
+	"author":           "b.author",
+	"publication_year": "COALESCE(b.publication_year, 0)",
+	"page_count":       "COALESCE(b.page_count, 0)",
+}
+
+func (s *BookSQLite) GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, error) {
+	var books []bookshelf.Book
+	conds := []string{"lb.list_id = $1", "ul.user_id = $2"}
+	args := []any{listID, userID}
+	if filter.Author != "" {
+		args = append(args, filter.Author)
+		conds = append(conds, fmt.Sprintf("lower(b.author) = lower($%d)", len(args)))
+	}
+	if filter.Publisher != "" {
+		args = append(args, filter.Publisher)
+		conds = append(conds, fmt.Sprintf("lower(b.publisher) = lower($%d)", len(args)))
+	}
+	if filter.YearFrom != nil {
+		args = append(args, *filter.YearFrom)
+		conds = append(conds, fmt.Sprintf("b.publication_year >= $%d", len(args)))
+	}
+	if filter.YearTo != nil {
+		args = append(args, *filter.YearTo)
+		conds = append(conds, fmt.Sprintf("b.publication_year <= $%d", len(args)))
+	}
+
+	field, desc := bookshelf.SortField(filter.Sort)
+	query, args := page(
+		"SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id",
+		conds, args,
+		bookSortColumns[field], "b.id", desc, filter.After, filter.Limit,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book bookshelf.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		books = append(books, book)
+	}
+	return books, wrapError(rows.Err())
+}
+
+func (s *BookSQLite) GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error) {
+	var book bookshelf.Book
+	query := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b INNER JOIN lists_books lb ON b.id = lb.book_id INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE b.id = $1 AND ul.user_id = $2 LIMIT 1"
+	row := s.db.QueryRowContext(ctx, query, bookID, userID)
+	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+	if err != nil {
+		return bookshelf.Book{}, wrapError(err)
+	}
+	return book, nil
+}
+
+// GetByISBN returns a book of the user with the ISBN. Books the user created
+// come before books shared with the user.
+func (s *BookSQLite) GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error) {
+	var book bookshelf.Book
+	query := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b WHERE b.isbn = $1 AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $2) ORDER BY b.user_id = $2 DESC, b.id LIMIT 1"
+	row := s.db.QueryRowContext(ctx, query, isbn, userID)
+	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+	if err != nil {
+		return bookshelf.Book{}, wrapError(err)
+	}
+	return book, nil
+}
+
+// Update changes the book if the user can edit every list containing it. The
+// book is shared by all of its lists, so an editor of one list must not
+// change what the members of another list see.
+func (s *BookSQLite) Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error {
+	book, err := s.GetByID(ctx, userID, bookID)
+	if err != nil {
+		return wrapError(err)
+	}
+	if input.Title == nil {
+		input.Title = &book.Title
+	}
+	if input.Author == nil {
+		input.Author = &book.Author
+	}
+	if input.Publisher == nil {
+		input.Publisher = &book.Publisher
+	}
+	if input.PublicationYear == nil {
+		input.PublicationYear = &book.PublicationYear
+	}
+	if input.PageCount == nil {
+		input.PageCount = &book.PageCount
+	}
+	if input.ISBN == nil {
+		input.ISBN = &book.ISBN
+	}
+	query := "UPDATE books SET title = $1, author = $2, publisher = $3, publication_year = $4, page_count = $5, isbn = NULLIF($6, '') WHERE id = $8 AND NOT EXISTS (SELECT 1 FROM lists_books lb LEFT JOIN users_lists ul ON lb.list_id = ul.list_id AND ul.user_id = $7 WHERE lb.book_id = $8 AND COALESCE(ul.role, '') NOT IN ('owner', 'editor'))"
+	res, err := s.db.ExecContext(ctx, query, input.Title, input.Author, input.Publisher, input.PublicationYear, input.PageCount, input.ISBN, userID, bookID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
+}
+
+// Delete removes the book from every list the user can edit. The book itself
+// is deleted once it is no longer part of any list, so lists of other users
+// keep their copy.
+func (s *BookSQLite) Delete(ctx context.Context, userID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	listsBooksQuery := "DELETE FROM lists_books WHERE book_id = $2 AND list_id IN (SELECT list_id FROM users_lists WHERE user_id = $1 AND role IN ('owner', 'editor'))"
+	res, err := tx.ExecContext(ctx, listsBooksQuery, userID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	booksQuery := "DELETE FROM books WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM lists_books WHERE book_id = $1)"
+	_, err = tx.ExecContext(ctx, booksQuery, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	return tx.Commit()
+}
+
+// Attach adds an existing book to a list. Attaching a book that is already
+// in the list is a no-op.
+func (s *BookSQLite) Attach(ctx context.Context, listID, bookID int) error {
+	query := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2) ON CONFLICT (list_id, book_id) DO NOTHING"
+	_, err := s.db.ExecContext(ctx, query, listID, bookID)
+	return wrapError(err)
+}
+
+// Detach removes a book from a list without deleting the book. It fails with
+// bookshelf.ErrConflict if the list is the last one holding the book. The
+// single connection serializes the transactions of concurrent detaches.
+func (s *BookSQLite) Detach(ctx context.Context, listID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	query := "DELETE FROM lists_books WHERE list_id = $1 AND book_id = $2"
+	res, err := tx.ExecContext(ctx, query, listID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var count int
+	countQuery := "SELECT count(*) FROM lists_books WHERE book_id = $1"
+	if err := tx.QueryRowContext(ctx, countQuery, bookID).Scan(&count); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if count == 0 {
+		tx.Rollback()
+		return bookshelf.ErrConflict
+	}
+	return wrapError(tx.Commit())
+}
+
+func (s *BookSQLite) Move(ctx context.Context, fromListID, toListID, bookID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	detachQuery := "DELETE FROM lists_books WHERE list_id = $1 AND book_id = $2"
+	res, err := tx.ExecContext(ctx, detachQuery, fromListID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	attachQuery := "INSERT INTO lists_books(list_id, book_id) VALUES ($1, $2) ON CONFLICT (list_id, book_id) DO NOTHING"
+	_, err = tx.ExecContext(ctx, attachQuery, toListID, bookID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	return tx.Commit()
+}
+
+func (s *BookSQLite) CountLists(ctx context.Context, bookID int) (int, error) {
+	var count int
+	query := "SELECT count(*) FROM lists_books WHERE book_id = $1"
+	err := s.db.QueryRowContext(ctx, query, bookID).Scan(&count)
+	return count, wrapError(err)
+}
+
+// GetRole returns the role the user has on the book, which is the lowest
+// role across the lists containing it. Lists the user is not a member of
+// count as viewer, as the book stays readable through the other lists.
+func (s *BookSQLite) GetRole(ctx context.Context, userID, bookID int) (string, error) {
+	var role string
+	query := "SELECT CASE min(CASE COALESCE(ul.role, '') WHEN 'owner' THEN 2 WHEN 'editor' THEN 1 ELSE 0 END) WHEN 2 THEN 'owner' WHEN 1 THEN 'editor' ELSE 'viewer' END FROM lists_books lb LEFT JOIN users_lists ul ON lb.list_id = ul.list_id AND ul.user_id = $2 WHERE lb.book_id = $1 HAVING count(ul.user_id) > 0"
+	err := s.db.QueryRowContext(ctx, query, bookID, userID).Scan(&role)
+	return role, wrapError(err)
+}
+
+// Suggest looks up books the user created and can still reach whose title
+// or author resembles query. SQLite has no trigram index, the books are
+// scored in Go, see textmatch.WordSimilarity.
+func (s *BookSQLite) Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error) {
+	books, err := s.accessible(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	var suggestions []bookshelf.BookSuggestion
+	for _, book := range books {
+		score := max(textmatch.WordSimilarity(query, book.Title), textmatch.WordSimilarity(query, book.Author))
+		if score >= textmatch.SuggestThreshold {
+			suggestions = append(suggestions, bookshelf.BookSuggestion{Book: book, Similarity: score})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Similarity > suggestions[j].Similarity
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// GetDuplicates groups the books of the user that share the same normalized
+// title and author or the same ISBN. A book can be part of both kinds of
+// groups. The keys are built in Go, as SQLite lacks regexp_replace.
+func (s *BookSQLite) GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error) {
+	books, err := s.accessible(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string][]bookshelf.Book)
+	for _, book := range books {
+		key := textmatch.DuplicateKey(book.Title, book.Author)
+		byKey[key] = append(byKey[key], book)
+		if book.ISBN != "" {
+			byKey["isbn:"+book.ISBN] = append(byKey["isbn:"+book.ISBN], book)
+		}
+	}
+
+	var groups []bookshelf.DuplicateGroup
+	for key, books := range byKey {
+		if len(books) > 1 {
+			groups = append(groups, bookshelf.DuplicateGroup{Key: key, Books: books})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+// accessible returns the books in the lists of the user, ordered by ID. With
+// own set, only the books the user created are returned.
+func (s *BookSQLite) accessible(ctx context.Context, userID int, own bool) ([]bookshelf.Book, error) {
+	var books []bookshelf.Book
+	query := "SELECT b.id, b.title, b.author, COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') FROM books b WHERE EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $1)"
+	if own {
+		query += " AND b.user_id = $1"
+	}
+	query += " ORDER BY b.id"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book bookshelf.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		books = append(books, book)
+	}
+	return books, wrapError(rows.Err())
+}
+
+// Merge replaces the books with the canonical book in every list the user
+// can edit and deletes the books that are no longer part of any list. Lists
+// the user cannot edit keep their entries. It returns the lists that held
+// one of the books, including the ones that already held the canonical book.
+func (s *BookSQLite) Merge(ctx context.Context, userID, canonicalID int, bookIDs []int) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	ids := jsonArray(bookIDs)
+
+	var listIDs []int
+	listsQuery := "SELECT DISTINCT lb.list_id FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id IN (SELECT value FROM json_each($1)) AND ul.user_id = $2 AND ul.role IN ('owner', 'editor') ORDER BY lb.list_id"
+	rows, err := tx.QueryContext(ctx, listsQuery, ids, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, wrapError(err)
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+	if len(listIDs) == 0 {
+		tx.Rollback()
+		return nil, wrapError(sql.ErrNoRows)
+	}
+	lists := jsonArray(listIDs)
+
+	// WHERE true keeps SQLite from parsing ON CONFLICT as a join constraint.
+	rewriteQuery := "INSERT INTO lists_books(list_id, book_id) SELECT value, $1 FROM json_each($2) WHERE true ON CONFLICT (list_id, book_id) DO NOTHING"
+	if _, err := tx.ExecContext(ctx, rewriteQuery, canonicalID, lists); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+
+	detachQuery := "DELETE FROM lists_books WHERE book_id IN (SELECT value FROM json_each($1)) AND list_id IN (SELECT value FROM json_each($2))"
+	if _, err := tx.ExecContext(ctx, detachQuery, ids, lists); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+
+	deleteQuery := "DELETE FROM books WHERE id IN (SELECT value FROM json_each($1)) AND NOT EXISTS (SELECT 1 FROM lists_books lb WHERE lb.book_id = books.id)"
+	if _, err := tx.ExecContext(ctx, deleteQuery, ids); err != nil {
+		tx.Rollback()
+		return nil, wrapError(err)
+	}
+	return listIDs, wrapError(tx.Commit())
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBookSQLite_Suggest(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	_, err := books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen"})
+	require.NoError(t, err)
+
+	got, err := books.Suggest(ctx, userID, "Herbrt", 5)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, bookID, got[0].ID)
+		assert.GreaterOrEqual(t, got[0].Similarity, 0.5)
+	}
+
+	_, err = conn.ExecContext(ctx, "UPDATE books SET publisher = NULL, publication_year = NULL, page_count = NULL WHERE id = $1", bookID)
+	require.NoError(t, err)
+	got, err = books.Suggest(ctx, userID, "Herbrt", 5)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+
+	got, err = books.Suggest(ctx, userID+1, "Herbert", 5)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	otherID, err := NewAuthSQLite(conn).CreateUser(ctx, bookshelf.User{Username: "bob", Password: "hash"})
+	require.NoError(t, err)
+	require.NoError(t, NewListSQLite(conn).AddMember(ctx, listID, otherID, bookshelf.RoleEditor))
+	got, err = books.Suggest(ctx, otherID, "Herbert", 5)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestBookSQLite_GetAllNullColumns(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	otherID, err := books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen", PublicationYear: 1815, PageCount: 474})
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "UPDATE books SET publisher = NULL, publication_year = NULL, page_count = NULL WHERE id = $1", bookID)
+	require.NoError(t, err)
+
+	for _, sort := range []string{"publication_year", "page_count"} {
+		filter := bookshelf.BookFilter{Sort: sort, Limit: 1}
+		got, err := books.GetAll(ctx, userID, listID, filter)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, bookID, got[0].ID)
+
+		filter.After = &bookshelf.Cursor{Sort: sort, Value: 0, ID: bookID}
+		got, err = books.GetAll(ctx, userID, listID, filter)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, otherID, got[0].ID)
+	}
+}
+
+func TestBookSQLite_GetByIDNullColumns(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, _, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	_, err := conn.ExecContext(ctx, "UPDATE books SET isbn = '9780441172719', publisher = NULL, publication_year = NULL, page_count = NULL WHERE id = $1", bookID)
+	require.NoError(t, err)
+	want := bookshelf.Book{ID: bookID, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"}
+
+	got, err := books.GetByID(ctx, userID, bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = books.GetByISBN(ctx, userID, "9780441172719")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestBookSQLite_GetDuplicates(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	copyID, err := books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "DUNE!", Author: "Frank  Herbert", ISBN: "9780441172719"})
+	require.NoError(t, err)
+	_, err = books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen"})
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "UPDATE books SET publisher = NULL, publication_year = NULL, page_count = NULL WHERE id = $1", copyID)
+	require.NoError(t, err)
+
+	groups, err := books.GetDuplicates(ctx, userID)
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, "dune / frank herbert", groups[0].Key)
+		assert.Equal(t, bookID, groups[0].Books[0].ID)
+		assert.Equal(t, copyID, groups[0].Books[1].ID)
+	}
+}
+
+func TestBookSQLite_Merge(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	otherListID, err := NewListSQLite(conn).Create(ctx, userID, bookshelf.List{Title: "Favourites"})
+	require.NoError(t, err)
+	copyID, err := books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	require.NoError(t, books.Attach(ctx, otherListID, copyID))
+
+	listIDs, err := books.Merge(ctx, userID, bookID, []int{copyID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{listID, otherListID}, listIDs)
+	count, err := books.CountLists(ctx, bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	_, err = books.GetByID(ctx, userID, copyID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+
+	_, err = books.Merge(ctx, userID, bookID, []int{copyID})
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+}
@@ -0,0 +1,262 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type ListSQLite struct {
+	db *sql.DB
+}
+
+func NewListSQLite(db *sql.DB) *ListSQLite {
+	return &ListSQLite{db: db}
+}
+
+func (s *ListSQLite) Create(ctx context.Context, userID int, list bookshelf.List) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	var id int
+	listsQuery := "INSERT INTO lists(title, description) VALUES ($1, $2) RETURNING id"
+	row := tx.QueryRowContext(ctx, listsQuery, list.Title, list.Description)
+	if err := row.Scan(&id); err != nil {
+		tx.Rollback()
+		return 0, wrapError(err)
+	}
+
+	usersListsQuery := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3)"
+	_, err = tx.ExecContext(ctx, usersListsQuery, userID, id, bookshelf.RoleOwner)
+	if err != nil {
+		tx.Rollback()
+		return 0, wrapError(err)
+	}
+
+	return id, tx.Commit()
+}
+
+var listSortColumns = map[string]string{
+	"":      "l.id",
+	"title": "l.title",
+}
+
+func (s *ListSQLite) GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, error) {
+	var lists []bookshelf.List
+	field, desc := bookshelf.SortField(filter.Sort)
+	query, args := page(
+		"SELECT l.id, l.title, l.description FROM lists l INNER JOIN users_lists ul ON l.id=ul.list_id",
+		[]string{"ul.user_id=$1"}, []any{userID},
+		listSortColumns[field], "l.id", desc, filter.After, filter.Limit,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var list bookshelf.List
+		err := rows.Scan(&list.ID, &list.Title, &list.Description)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		lists = append(lists, list)
+	}
+	return lists, wrapError(rows.Err())
+}
+
+// Export calls fn for every book in the lists of the user, ordered by list.
+// A listID other than zero restricts the export to that list. The handle has
+// a single connection, so the rows are read into memory before fn is called:
+// a slow client must not hold the connection every other request waits for.
+func (s *ListSQLite) Export(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error {
+	exportRows, err := s.exportRows(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	for _, row := range exportRows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ListSQLite) exportRows(ctx context.Context, userID, listID int) ([]bookshelf.ExportRow, error) {
+	query := "SELECT l.id, l.title, COALESCE(l.description, ''), COALESCE(b.id, 0), COALESCE(b.title, ''), COALESCE(b.author, ''), COALESCE(b.publisher, ''), COALESCE(b.publication_year, 0), COALESCE(b.page_count, 0), COALESCE(b.isbn, '') " +
+		"FROM lists l INNER JOIN users_lists ul ON l.id = ul.list_id LEFT JOIN lists_books lb ON l.id = lb.list_id LEFT JOIN books b ON lb.book_id = b.id " +
+		"WHERE ul.user_id = $1 AND ($2 = 0 OR l.id = $2) ORDER BY l.id, b.id"
+	rows, err := s.db.QueryContext(ctx, query, userID, listID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	var exportRows []bookshelf.ExportRow
+	for rows.Next() {
+		var row bookshelf.ExportRow
+		var book bookshelf.Book
+		err := rows.Scan(&row.List.ID, &row.List.Title, &row.List.Description, &book.ID, &book.Title, &book.Author, &book.Publisher, &book.PublicationYear, &book.PageCount, &book.ISBN)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		if book.ID != 0 {
+			row.Book = &book
+		}
+		exportRows = append(exportRows, row)
+	}
+	return exportRows, wrapError(rows.Err())
+}
+
+func (s *ListSQLite) GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error) {
+	var list bookshelf.List
+	query := "SELECT l.id, l.title, l.description FROM lists l INNER JOIN users_lists ul ON l.id=ul.list_id WHERE ul.user_id=$1 AND ul.list_id=$2"
+	row := s.db.QueryRowContext(ctx, query, userID, listID)
+	err := row.Scan(&list.ID, &list.Title, &list.Description)
+	if err != nil {
+		return bookshelf.List{}, wrapError(err)
+	}
+	return list, nil
+}
+
+func (s *ListSQLite) Update(ctx context.Context, userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error {
+	if input.Title == nil {
+		input.Title = &list.Title
+	}
+	if input.Description == nil {
+		input.Description = &list.Description
+	}
+	query := "UPDATE lists SET title = $1, description = $2 WHERE id = $3 AND EXISTS (SELECT 1 FROM users_lists WHERE list_id = $3 AND user_id = $4 AND role = 'owner')"
+	res, err := s.db.ExecContext(ctx, query, input.Title, input.Description, listID, userID)
+	if err != nil {
+		return wrapError(err)
+	}
+	return checkAffected(res)
+}
+
+// Delete removes the list together with the books that are not part of any
+// other list.
+func (s *ListSQLite) Delete(ctx context.Context, userID, listID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	var bookIDs []int
+	booksQuery := "SELECT book_id FROM lists_books WHERE list_id=$1"
+	rows, err := tx.QueryContext(ctx, booksQuery, listID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return wrapError(err)
+		}
+		bookIDs = append(bookIDs, id)
+	}
+	rows.Close()
+
+	listQuery := "DELETE FROM lists WHERE id=$2 AND EXISTS (SELECT 1 FROM users_lists WHERE list_id=$2 AND user_id=$1 AND role='owner')"
+	res, err := tx.ExecContext(ctx, listQuery, userID, listID)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(bookIDs) > 0 {
+		orphansQuery := "DELETE FROM books WHERE id IN (SELECT value FROM json_each($1)) AND NOT EXISTS (SELECT 1 FROM lists_books lb WHERE lb.book_id = books.id)"
+		_, err = tx.ExecContext(ctx, orphansQuery, jsonArray(bookIDs))
+		if err != nil {
+			tx.Rollback()
+			return wrapError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *ListSQLite) GetRole(ctx context.Context, userID, listID int) (string, error) {
+	var role string
+	query := "SELECT role FROM users_lists WHERE user_id=$1 AND list_id=$2"
+	err := s.db.QueryRowContext(ctx, query, userID, listID).Scan(&role)
+	return role, wrapError(err)
+}
+
+func (s *ListSQLite) GetMembers(ctx context.Context, listID int) ([]bookshelf.ListMember, error) {
+	var members []bookshelf.ListMember
+	query := "SELECT u.id, u.username, ul.role FROM users u INNER JOIN users_lists ul ON u.id=ul.user_id WHERE ul.list_id=$1 ORDER BY u.id"
+	rows, err := s.db.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member bookshelf.ListMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role); err != nil {
+			return nil, wrapError(err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *ListSQLite) AddMember(ctx context.Context, listID, userID int, role string) error {
+	query := "INSERT INTO users_lists(user_id, list_id, role) VALUES ($1, $2, $3)"
+	_, err := s.db.ExecContext(ctx, query, userID, listID, role)
+	return wrapError(err)
+}
+
+// UpdateMember changes the role of a member. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListSQLite) UpdateMember(ctx context.Context, listID, userID int, role string) error {
+	query := "UPDATE users_lists SET role=$3 WHERE list_id=$1 AND user_id=$2"
+	return s.changeMember(ctx, listID, query, listID, userID, role)
+}
+
+// RemoveMember removes a member from the list. It fails with ErrConflict when
+// the list would be left without an owner.
+func (s *ListSQLite) RemoveMember(ctx context.Context, listID, userID int) error {
+	query := "DELETE FROM users_lists WHERE list_id=$1 AND user_id=$2"
+	return s.changeMember(ctx, listID, query, listID, userID)
+}
+
+// changeMember runs a change of the members of the list and rolls it back if
+// no owner is left. SQLite runs one write transaction at a time, so
+// concurrent changes of the same list see each other.
+func (s *ListSQLite) changeMember(ctx context.Context, listID int, query string, args ...any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var owners int
+	ownersQuery := "SELECT count(*) FROM users_lists WHERE list_id=$1 AND role='owner'"
+	if err := tx.QueryRowContext(ctx, ownersQuery, listID).Scan(&owners); err != nil {
+		tx.Rollback()
+		return wrapError(err)
+	}
+	if owners == 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: list must have at least one owner", bookshelf.ErrConflict)
+	}
+	return wrapError(tx.Commit())
+}
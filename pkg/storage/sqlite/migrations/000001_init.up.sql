@@ -0,0 +1,137 @@
+CREATE TABLE users
+(
+    id integer primary key,
+    username varchar(255) not null unique check (length(username) <= 255),
+    password_hash varchar(255) not null check (length(password_hash) <= 255)
+);
+
+CREATE TABLE lists
+(
+    id integer primary key,
+    title varchar(255) not null check (length(title) <= 255),
+    description varchar(255) check (length(description) <= 255)
+);
+
+CREATE TABLE users_lists
+(
+    user_id int not null,
+    list_id int not null,
+    role varchar(16) not null default 'owner' check (role IN ('owner', 'editor', 'viewer')),
+    primary key (user_id, list_id),
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (list_id) references lists(id) on delete cascade
+);
+
+CREATE TABLE books
+(
+    id integer primary key,
+    user_id int references users(id) on delete set null,
+    title varchar(255) not null check (length(title) <= 255),
+    author varchar(100) not null check (length(author) <= 100),
+    publisher varchar(100) check (length(publisher) <= 100),
+    publication_year int,
+    page_count int,
+    isbn char(13) check (isbn GLOB '97[89][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]')
+);
+
+CREATE UNIQUE INDEX books_user_isbn_key ON books (user_id, isbn);
+
+CREATE INDEX books_isbn_idx ON books (isbn);
+
+CREATE TABLE lists_books
+(
+    list_id int not null,
+    book_id int not null,
+    primary key (list_id, book_id),
+    foreign key (list_id) references lists(id) on delete cascade,
+    foreign key (book_id) references books(id) on delete cascade
+);
+
+CREATE INDEX lists_books_book_id_idx ON lists_books (book_id);
+
+CREATE TABLE refresh_tokens
+(
+    id integer primary key,
+    user_id int not null,
+    family_id varchar(64) not null,
+    token_hash varchar(64) not null unique,
+    expires_at datetime not null,
+    used_at datetime,
+    revoked_at datetime,
+    created_at datetime not null default CURRENT_TIMESTAMP,
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+CREATE TABLE revoked_tokens
+(
+    jti varchar(64) primary key,
+    expires_at datetime not null
+);
+
+-- scopes holds a JSON array.
+CREATE TABLE api_tokens
+(
+    id integer primary key,
+    user_id int not null,
+    name varchar(255) not null check (length(name) <= 255),
+    prefix varchar(16) not null,
+    token_hash varchar(64) not null unique,
+    scopes text not null check (json_valid(scopes)),
+    expires_at datetime,
+    last_used_at datetime,
+    created_at datetime not null default CURRENT_TIMESTAMP,
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+CREATE TABLE list_shares
+(
+    id integer primary key,
+    list_id int not null,
+    created_by int not null,
+    token_hash varchar(64) not null unique,
+    expires_at datetime,
+    revoked_at datetime,
+    created_at datetime not null default CURRENT_TIMESTAMP,
+    foreign key (list_id) references lists(id) on delete cascade,
+    foreign key (created_by) references users(id) on delete cascade
+);
+
+-- The full text indexes mirror the search columns of the Postgres schema,
+-- the triggers keep them in sync with their tables.
+CREATE VIRTUAL TABLE books_search USING fts5 (
+    title, author, publisher,
+    content = 'books', content_rowid = 'id', tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER books_search_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_search (rowid, title, author, publisher) VALUES (new.id, new.title, new.author, new.publisher);
+END;
+
+CREATE TRIGGER books_search_delete AFTER DELETE ON books BEGIN
+    INSERT INTO books_search (books_search, rowid, title, author, publisher) VALUES ('delete', old.id, old.title, old.author, old.publisher);
+END;
+
+CREATE TRIGGER books_search_update AFTER UPDATE ON books BEGIN
+    INSERT INTO books_search (books_search, rowid, title, author, publisher) VALUES ('delete', old.id, old.title, old.author, old.publisher);
+    INSERT INTO books_search (rowid, title, author, publisher) VALUES (new.id, new.title, new.author, new.publisher);
+END;
+
+CREATE VIRTUAL TABLE lists_search USING fts5 (
+    title, description,
+    content = 'lists', content_rowid = 'id', tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER lists_search_insert AFTER INSERT ON lists BEGIN
+    INSERT INTO lists_search (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER lists_search_delete AFTER DELETE ON lists BEGIN
+    INSERT INTO lists_search (lists_search, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+
+CREATE TRIGGER lists_search_update AFTER UPDATE ON lists BEGIN
+    INSERT INTO lists_search (lists_search, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO lists_search (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
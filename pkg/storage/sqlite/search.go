@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type SearchSQLite struct {
+	db *sql.DB
+}
+
+func NewSearchSQLite(db *sql.DB) *SearchSQLite {
+	return &SearchSQLite{db: db}
+}
+
+// searchHitsQuery matches the books and lists the user has access to. The
+// bm25 weights of the columns are the ts_rank defaults for the weights the
+// Postgres schema gives them. bm25 is lower for better matches, so it is
+// negated to order like ts_rank. The matches are delimited by
+// textmatch.StartMark and textmatch.StopMark.
+const searchHitsQuery = `SELECT kind, id, title, headline, rank FROM (
+	SELECT 'book' AS kind, b.id, b.title,
+		concat_ws(' ', highlight(books_search, 0, char(2), char(3)), highlight(books_search, 1, char(2), char(3)), NULLIF(highlight(books_search, 2, char(2), char(3)), '')) AS headline,
+		-bm25(books_search, 1.0, 0.4, 0.2) AS rank
+	FROM books_search INNER JOIN books b ON b.id = books_search.rowid
+	WHERE books_search MATCH $1 AND EXISTS (SELECT 1 FROM lists_books lb INNER JOIN users_lists ul ON lb.list_id = ul.list_id WHERE lb.book_id = b.id AND ul.user_id = $2)
+	UNION ALL
+	SELECT 'list' AS kind, l.id, l.title,
+		concat_ws(' ', highlight(lists_search, 0, char(2), char(3)), NULLIF(highlight(lists_search, 1, char(2), char(3)), '')) AS headline,
+		-bm25(lists_search, 1.0, 0.4) AS rank
+	FROM lists_search INNER JOIN lists l ON l.id = lists_search.rowid INNER JOIN users_lists ul ON l.id = ul.list_id
+	WHERE lists_search MATCH $1 AND ul.user_id = $2
+) hits`
+
+func (s *SearchSQLite) Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, error) {
+	var hits []bookshelf.SearchHit
+	match := matchExpression(textmatch.ParseWebSearch(query.Query))
+	if match == "" {
+		return nil, nil
+	}
+	args := []any{match, userID}
+	where := ""
+	if query.After != nil {
+		args = append(args, query.After.Value, query.After.Kind, query.After.ID)
+		where = " WHERE (rank, kind, id) < ($3, $4, $5)"
+	}
+	limit := ""
+	if query.Limit > 0 {
+		args = append(args, query.Limit)
+		limit = fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, searchHitsQuery+where+" ORDER BY rank DESC, kind DESC, id DESC"+limit, args...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit bookshelf.SearchHit
+		if err := rows.Scan(&hit.Kind, &hit.ID, &hit.Title, &hit.Headline, &hit.Rank); err != nil {
+			return nil, wrapError(err)
+		}
+		hit.Headline = textmatch.Headline(hit.Headline)
+		hits = append(hits, hit)
+	}
+	return hits, wrapError(rows.Err())
+}
+
+// matchExpression translates a web search query into an FTS5 query. FTS5
+// has no unary NOT, so groups made only of excluded terms are dropped.
+func matchExpression(q textmatch.Query) string {
+	var groups []string
+	for _, group := range q {
+		var include, exclude []string
+		for _, term := range group {
+			phrase := `"` + strings.Join(term.Words, " ") + `"`
+			if term.Exclude {
+				exclude = append(exclude, phrase)
+			} else {
+				include = append(include, phrase)
+			}
+		}
+		if len(include) == 0 {
+			continue
+		}
+		expr := "(" + strings.Join(include, " AND ") + ")"
+		for _, phrase := range exclude {
+			expr += " NOT " + phrase
+		}
+		groups = append(groups, "("+expr+")")
+	}
+	return strings.Join(groups, " OR ")
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/internal/textmatch"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Words",
+			query: "Frank Herbert",
+			want:  `(("frank" AND "herbert"))`,
+		},
+		{
+			name:  "Phrase and exclusion",
+			query: `"jane austen" -emma`,
+			want:  `(("jane austen") NOT "emma")`,
+		},
+		{
+			name:  "Or",
+			query: "dune or emma",
+			want:  `(("dune")) OR (("emma"))`,
+		},
+		{
+			name:  "Only excluded",
+			query: "-dune",
+			want:  "",
+		},
+		{
+			name:  "Operators are quoted",
+			query: "NOT* near(a)",
+			want:  `(("not" AND "near a"))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchExpression(textmatch.ParseWebSearch(tt.query)))
+		})
+	}
+}
+
+func TestSearchSQLite_Search(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, bookID := newTestDB(t)
+	books := NewBookSQLite(conn)
+	emmaID, err := books.Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen"})
+	require.NoError(t, err)
+	otherID, err := NewAuthSQLite(conn).CreateUser(ctx, bookshelf.User{Username: "bob", Password: "hash"})
+	require.NoError(t, err)
+	title := "Dune Messiah"
+	require.NoError(t, books.Update(ctx, userID, bookID, bookshelf.UpdateBookInput{Title: &title}))
+	search := NewSearchSQLite(conn)
+
+	hits, err := search.Search(ctx, userID, bookshelf.SearchQuery{Query: "messiah"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, bookID, hits[0].ID)
+		assert.Equal(t, "book", hits[0].Kind)
+		assert.Equal(t, "Dune <mark>Messiah</mark> Frank Herbert Chilton Books", hits[0].Headline)
+	}
+
+	hits, err = search.Search(ctx, userID, bookshelf.SearchQuery{Query: "austen or reading"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 2) {
+		assert.Equal(t, "list", hits[0].Kind)
+		assert.Equal(t, "<mark>Reading</mark> Books I am <mark>reading</mark>", hits[0].Headline)
+		assert.Equal(t, emmaID, hits[1].ID)
+
+		next, err := search.Search(ctx, userID, bookshelf.SearchQuery{
+			Query: "austen or reading",
+			After: &bookshelf.Cursor{Value: hits[0].Rank, Kind: hits[0].Kind, ID: hits[0].ID},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, hits[1:], next)
+	}
+
+	hits, err = search.Search(ctx, otherID, bookshelf.SearchQuery{Query: "dune"})
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+
+	// Deleted books leave the index.
+	require.NoError(t, books.Delete(ctx, userID, bookID))
+	hits, err = search.Search(ctx, userID, bookshelf.SearchQuery{Query: "messiah"})
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+func TestSearchSQLite_EscapesHeadline(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, _ := newTestDB(t)
+	_, err := NewBookSQLite(conn).Create(ctx, listID, bookshelf.Book{UserID: userID, Title: `<script>alert("dune")</script>`, Author: "Mallory"})
+	require.NoError(t, err)
+
+	hits, err := NewSearchSQLite(conn).Search(ctx, userID, bookshelf.SearchQuery{Query: "mallory"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, "&lt;script&gt;alert(&#34;dune&#34;)&lt;/script&gt; <mark>Mallory</mark>", hits[0].Headline)
+	}
+}
@@ -0,0 +1,104 @@
+// Package sqlite implements the storage interfaces on SQLite, for
+// self-hosting without a Postgres server. The schema follows the Postgres
+// one, with FTS5 tables in place of the tsvector columns.
+package sqlite
+
+import (
+	"bookshelf-api/db"
+	"bookshelf-api/pkg/config"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	_ "modernc.org/sqlite"
+	"net/url"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func New(cfg config.Config) (*sql.DB, error) {
+	return Open(cfg.Storage.SQLite.Path)
+}
+
+// Open opens the database file at path, ":memory:" opens a database that
+// lives as long as the returned handle. Foreign keys are enforced and times
+// are stored in a format the SQLite date functions understand.
+//
+// The handle uses a single connection: SQLite serializes writes anyway, and
+// waiting for the connection is simpler than retrying busy transactions.
+// Queries must therefore not hold the connection while waiting on a client.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	if path != ":memory:" {
+		params.Add("_pragma", "journal_mode(WAL)")
+	}
+	params.Set("_time_format", "sqlite")
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	conn.SetMaxOpenConns(1)
+	// A closed connection would take an in-memory database with it.
+	conn.SetConnMaxLifetime(0)
+	conn.SetConnMaxIdleTime(0)
+	return conn, nil
+}
+
+// Migrate applies the pending migrations of the SQLite schema and returns
+// them. Every migration runs in its own transaction and is recorded in the
+// schema_migrations table.
+func Migrate(conn *sql.DB) ([]db.Migration, error) {
+	all, err := db.Load(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	createQuery := "CREATE TABLE IF NOT EXISTS schema_migrations(version integer primary key, name text not null, applied_at datetime not null default CURRENT_TIMESTAMP)"
+	if _, err := conn.Exec(createQuery); err != nil {
+		return nil, err
+	}
+	var latest int
+	if err := conn.QueryRow("SELECT COALESCE(max(version), 0) FROM schema_migrations").Scan(&latest); err != nil {
+		return nil, err
+	}
+
+	var done []db.Migration
+	for _, migration := range all {
+		if migration.Version <= latest {
+			continue
+		}
+		if err := apply(conn, migration); err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func apply(conn *sql.DB, migration db.Migration) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(migration.Up); err != nil {
+		tx.Rollback()
+		return err
+	}
+	recordQuery := "INSERT INTO schema_migrations(version, name) VALUES ($1, $2)"
+	if _, err := tx.Exec(recordQuery, migration.Version, migration.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// jsonArray encodes ids for json_each, which stands in for the Postgres
+// arrays.
+func jsonArray(ids []int) string {
+	data, _ := json.Marshal(ids)
+	return string(data)
+}
@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	bookshelf "bookshelf-api"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDB opens a migrated in-memory database with a user and a list with
+// a book. It returns the IDs of the user, the list and the book.
+func newTestDB(t *testing.T) (*sql.DB, int, int, int) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	_, err = Migrate(conn)
+	require.NoError(t, err)
+
+	userID, err := NewAuthSQLite(conn).CreateUser(ctx, bookshelf.User{Username: "alice", Password: "hash"})
+	require.NoError(t, err)
+	listID, err := NewListSQLite(conn).Create(ctx, userID, bookshelf.List{Title: "Reading", Description: "Books I am reading"})
+	require.NoError(t, err)
+	bookID, err := NewBookSQLite(conn).Create(ctx, listID, bookshelf.Book{UserID: userID, Title: "Dune", Author: "Frank Herbert", Publisher: "Chilton Books"})
+	require.NoError(t, err)
+	return conn, userID, listID, bookID
+}
+
+func TestMigrate(t *testing.T) {
+	conn, err := Open(":memory:")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	done, err := Migrate(conn)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, done)
+
+	done, err = Migrate(conn)
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+}
+
+func TestBookSQLite_Create(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, _ := newTestDB(t)
+	books := NewBookSQLite(conn)
+
+	tests := []struct {
+		name    string
+		book    bookshelf.Book
+		wantErr error
+	}{
+		{
+			name: "OK",
+			book: bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587"},
+		},
+		{
+			name:    "Invalid ISBN",
+			book:    bookshelf.Book{UserID: userID, Title: "Emma", Author: "Jane Austen", ISBN: "0141439580"},
+			wantErr: bookshelf.ErrValidation,
+		},
+		{
+			name:    "Author too long",
+			book:    bookshelf.Book{UserID: userID, Title: "Emma", Author: strings.Repeat("a", 101)},
+			wantErr: bookshelf.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := books.Create(ctx, listID, tt.book)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAPITokenSQLite(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, _, _ := newTestDB(t)
+	tokens := NewAPITokenSQLite(conn)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	id, err := tokens.Create(ctx, bookshelf.APIToken{
+		UserID:    userID,
+		Name:      "reader",
+		Prefix:    "bsk_abc",
+		TokenHash: "hash",
+		Scopes:    []string{bookshelf.ScopeListsRead, bookshelf.ScopeBooksRead},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	require.NoError(t, tokens.Touch(ctx, id))
+
+	token, err := tokens.GetByHash(ctx, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{bookshelf.ScopeListsRead, bookshelf.ScopeBooksRead}, token.Scopes)
+	if assert.NotNil(t, token.ExpiresAt) {
+		assert.True(t, expiresAt.Equal(*token.ExpiresAt))
+	}
+	assert.NotNil(t, token.LastUsedAt)
+	assert.False(t, token.CreatedAt.IsZero())
+
+	assert.ErrorIs(t, tokens.Delete(ctx, userID+1, id), bookshelf.ErrNotFound)
+	assert.NoError(t, tokens.Delete(ctx, userID, id))
+}
+
+func TestShareSQLite_GetAll(t *testing.T) {
+	ctx := context.Background()
+	conn, userID, listID, _ := newTestDB(t)
+	shares := NewShareSQLite(conn)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	activeID, err := shares.Create(ctx, bookshelf.ListShare{ListID: listID, CreatedBy: userID, TokenHash: "active", ExpiresAt: &future})
+	require.NoError(t, err)
+	_, err = shares.Create(ctx, bookshelf.ListShare{ListID: listID, CreatedBy: userID, TokenHash: "expired", ExpiresAt: &past})
+	require.NoError(t, err)
+	revokedID, err := shares.Create(ctx, bookshelf.ListShare{ListID: listID, CreatedBy: userID, TokenHash: "revoked"})
+	require.NoError(t, err)
+	require.NoError(t, shares.Revoke(ctx, listID, revokedID))
+	assert.ErrorIs(t, shares.Revoke(ctx, listID, revokedID), bookshelf.ErrNotFound)
+
+	got, err := shares.GetAll(ctx, listID)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, activeID, got[0].ID)
+	}
+}
+
+func TestAuthSQLite_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+	conn, _, _, _ := newTestDB(t)
+	auth := NewAuthSQLite(conn)
+
+	require.NoError(t, auth.RevokeToken(ctx, "expired", time.Now().Add(-time.Minute)))
+	require.NoError(t, auth.RevokeToken(ctx, "active", time.Now().Add(time.Hour)))
+
+	revoked, err := auth.IsTokenRevoked(ctx, "active")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	// Revoking a token removes the entries that expired meanwhile.
+	revoked, err = auth.IsTokenRevoked(ctx, "expired")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestListSQLite_CanceledContext(t *testing.T) {
+	conn, userID, listID, _ := newTestDB(t)
+	lists := NewListSQLite(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := lists.GetAll(ctx, userID, bookshelf.ListFilter{})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = lists.GetByID(ctx, userID, listID)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestListSQLite_ExportReleasesConnection(t *testing.T) {
+	conn, userID, listID, bookID := newTestDB(t)
+	lists := NewListSQLite(conn)
+
+	// The single connection must be free while fn runs, a query from fn
+	// would wait for it forever otherwise.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var rows []bookshelf.ExportRow
+	err := lists.Export(ctx, userID, 0, func(row bookshelf.ExportRow) error {
+		if _, err := lists.GetByID(ctx, userID, row.List.ID); err != nil {
+			return err
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, listID, rows[0].List.ID)
+		assert.Equal(t, bookID, rows[0].Book.ID)
+	}
+}
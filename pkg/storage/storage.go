@@ -2,35 +2,89 @@ package storage
 
 import (
 	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage/memory"
 	"bookshelf-api/pkg/storage/postgres"
+	"bookshelf-api/pkg/storage/sqlite"
+	"context"
 	"database/sql"
+	"time"
 )
 
 type Authorization interface {
-	CreateUser(user bookshelf.User) (int, error)
-	GetUser(username, password string) (bookshelf.User, error)
+	CreateUser(ctx context.Context, user bookshelf.User) (int, error)
+	GetUser(ctx context.Context, username string) (bookshelf.User, error)
+	UpdatePasswordHash(ctx context.Context, userID int, hash string) error
+	CreateRefreshToken(ctx context.Context, token bookshelf.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (bookshelf.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id int) (bool, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
 }
 
 type List interface {
-	Create(userID int, list bookshelf.List) (int, error)
-	GetAll(userID int) ([]bookshelf.List, error)
-	GetByID(userID, listID int) (bookshelf.List, error)
-	Update(userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error
-	Delete(userID, listID int) error
+	Create(ctx context.Context, userID int, list bookshelf.List) (int, error)
+	GetAll(ctx context.Context, userID int, filter bookshelf.ListFilter) ([]bookshelf.List, error)
+	GetByID(ctx context.Context, userID, listID int) (bookshelf.List, error)
+	Update(ctx context.Context, userID, listID int, list bookshelf.List, input bookshelf.UpdateListInput) error
+	Delete(ctx context.Context, userID, listID int) error
+	GetRole(ctx context.Context, userID, listID int) (string, error)
+	GetMembers(ctx context.Context, listID int) ([]bookshelf.ListMember, error)
+	AddMember(ctx context.Context, listID, userID int, role string) error
+	UpdateMember(ctx context.Context, listID, userID int, role string) error
+	RemoveMember(ctx context.Context, listID, userID int) error
+	Export(ctx context.Context, userID, listID int, fn func(bookshelf.ExportRow) error) error
 }
 
 type Book interface {
-	Create(listID int, book bookshelf.Book) (int, error)
-	GetAll(userID, listID int) ([]bookshelf.Book, error)
-	GetByID(userID, bookID int) (bookshelf.Book, error)
-	Update(userID, bookID int, input bookshelf.UpdateBookInput) error
-	Delete(userID, bookID int) error
+	Create(ctx context.Context, listID int, book bookshelf.Book) (int, error)
+	GetAll(ctx context.Context, userID, listID int, filter bookshelf.BookFilter) ([]bookshelf.Book, error)
+	GetByID(ctx context.Context, userID, bookID int) (bookshelf.Book, error)
+	GetByISBN(ctx context.Context, userID int, isbn string) (bookshelf.Book, error)
+	Update(ctx context.Context, userID, bookID int, input bookshelf.UpdateBookInput) error
+	Delete(ctx context.Context, userID, bookID int) error
+	GetRole(ctx context.Context, userID, bookID int) (string, error)
+	Attach(ctx context.Context, listID, bookID int) error
+	Detach(ctx context.Context, listID, bookID int) error
+	Move(ctx context.Context, fromListID, toListID, bookID int) error
+	CountLists(ctx context.Context, bookID int) (int, error)
+	Suggest(ctx context.Context, userID int, query string, limit int) ([]bookshelf.BookSuggestion, error)
+	GetDuplicates(ctx context.Context, userID int) ([]bookshelf.DuplicateGroup, error)
+	Merge(ctx context.Context, userID, canonicalID int, bookIDs []int) ([]int, error)
+}
+
+type APIToken interface {
+	Create(ctx context.Context, token bookshelf.APIToken) (int, error)
+	GetAll(ctx context.Context, userID int) ([]bookshelf.APIToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (bookshelf.APIToken, error)
+	Touch(ctx context.Context, tokenID int) error
+	Delete(ctx context.Context, userID, tokenID int) error
+}
+
+type Share interface {
+	Create(ctx context.Context, share bookshelf.ListShare) (int, error)
+	GetAll(ctx context.Context, listID int) ([]bookshelf.ListShare, error)
+	GetByHash(ctx context.Context, tokenHash string) (bookshelf.ListShare, error)
+	Revoke(ctx context.Context, listID, shareID int) error
+}
+
+type Search interface {
+	Search(ctx context.Context, userID int, query bookshelf.SearchQuery) ([]bookshelf.SearchHit, error)
+}
+
+type Backup interface {
+	Dump(ctx context.Context, userID int) (bookshelf.Backup, error)
+	Restore(ctx context.Context, userID int, backup bookshelf.Backup) (bookshelf.RestoreResult, error)
 }
 
 type Storage struct {
 	Authorization
 	List
 	Book
+	APIToken
+	Share
+	Search
+	Backup
 }
 
 func New(db *sql.DB) *Storage {
@@ -38,5 +92,33 @@ func New(db *sql.DB) *Storage {
 		Authorization: postgres.NewAuthPostgres(db),
 		List:          postgres.NewListPostgres(db),
 		Book:          postgres.NewBookPostgres(db),
+		APIToken:      postgres.NewAPITokenPostgres(db),
+		Share:         postgres.NewSharePostgres(db),
+		Search:        postgres.NewSearchPostgres(db),
+		Backup:        postgres.NewBackupPostgres(db),
+	}
+}
+
+func NewSQLite(db *sql.DB) *Storage {
+	return &Storage{
+		Authorization: sqlite.NewAuthSQLite(db),
+		List:          sqlite.NewListSQLite(db),
+		Book:          sqlite.NewBookSQLite(db),
+		APIToken:      sqlite.NewAPITokenSQLite(db),
+		Share:         sqlite.NewShareSQLite(db),
+		Search:        sqlite.NewSearchSQLite(db),
+		Backup:        sqlite.NewBackupSQLite(db),
+	}
+}
+
+func NewMemory(db *memory.DB) *Storage {
+	return &Storage{
+		Authorization: memory.NewAuthMemory(db),
+		List:          memory.NewListMemory(db),
+		Book:          memory.NewBookMemory(db),
+		APIToken:      memory.NewAPITokenMemory(db),
+		Share:         memory.NewShareMemory(db),
+		Search:        memory.NewSearchMemory(db),
+		Backup:        memory.NewBackupMemory(db),
 	}
 }
@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"bookshelf-api/db"
+	"bookshelf-api/pkg/storage"
+	"bookshelf-api/pkg/storage/memory"
+	"bookshelf-api/pkg/storage/postgres"
+	"bookshelf-api/pkg/storage/sqlite"
+	"bookshelf-api/pkg/storage/storagetest"
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestStorage_Memory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		return storage.NewMemory(memory.New())
+	})
+}
+
+func TestStorage_SQLite(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		conn, err := sqlite.Open(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if _, err := sqlite.Migrate(conn); err != nil {
+			t.Fatal(err)
+		}
+		return storage.NewSQLite(conn)
+	})
+}
+
+// TestStorage_Postgres runs against the database in BOOKSHELF_TEST_DSN. It
+// migrates the database and empties every table, never point it at data
+// you want to keep.
+func TestStorage_Postgres(t *testing.T) {
+	dsn := os.Getenv("BOOKSHELF_TEST_DSN")
+	if dsn == "" {
+		t.Skip("BOOKSHELF_TEST_DSN is not set")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	migrations, err := db.Load(db.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postgres.NewMigrator(conn, migrations).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		truncate := "TRUNCATE users, lists, users_lists, books, lists_books, refresh_tokens, revoked_tokens, api_tokens, list_shares RESTART IDENTITY CASCADE"
+		if _, err := conn.Exec(truncate); err != nil {
+			t.Fatal(err)
+		}
+		return storage.New(conn)
+	})
+}
@@ -0,0 +1,380 @@
+// Package storagetest checks that a storage backend behaves like the
+// others. The checks go through the storage interfaces only, so they
+// describe the semantics the services rely on instead of the queries a
+// backend runs.
+package storagetest
+
+import (
+	bookshelf "bookshelf-api"
+	"bookshelf-api/pkg/storage"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// Run runs the conformance suite. newStorage must return an empty storage on
+// every call, each subtest starts from a clean state.
+func Run(t *testing.T, newStorage func(t *testing.T) *storage.Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s *storage.Storage)
+	}{
+		{"Ownership", testOwnership},
+		{"Members", testMembers},
+		{"SharedBook", testSharedBook},
+		{"LastOwner", testLastOwner},
+		{"Merge", testMerge},
+		{"ListDeleteCascade", testListDeleteCascade},
+		{"BookDelete", testBookDelete},
+		{"PartialUpdate", testPartialUpdate},
+		{"NotFound", testNotFound},
+		{"Conflict", testConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// fixture is a list with a book, owned by alice. bob is another user
+// without access to it.
+type fixture struct {
+	alice, bob int
+	listID     int
+	bookID     int
+}
+
+func newFixture(t *testing.T, s *storage.Storage) fixture {
+	t.Helper()
+	ctx := context.Background()
+	var f fixture
+	var err error
+	f.alice, err = s.CreateUser(ctx, bookshelf.User{Username: "alice", Password: "alice-hash"})
+	require.NoError(t, err)
+	f.bob, err = s.CreateUser(ctx, bookshelf.User{Username: "bob", Password: "bob-hash"})
+	require.NoError(t, err)
+	f.listID, err = s.List.Create(ctx, f.alice, bookshelf.List{Title: "Reading", Description: "Books I am reading"})
+	require.NoError(t, err)
+	f.bookID, err = s.Book.Create(ctx, f.listID, bookshelf.Book{
+		UserID:          f.alice,
+		Title:           "Dune",
+		Author:          "Frank Herbert",
+		Publisher:       "Chilton Books",
+		PublicationYear: 1965,
+		PageCount:       412,
+		ISBN:            "9780441172719",
+	})
+	require.NoError(t, err)
+	return f
+}
+
+func testOwnership(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	title := "Mine now"
+
+	lists, err := s.List.GetAll(ctx, f.bob, bookshelf.ListFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, lists)
+	_, err = s.List.GetByID(ctx, f.bob, f.listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.List.GetRole(ctx, f.bob, f.listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	list := bookshelf.List{ID: f.listID, Title: "Reading", Description: "Books I am reading"}
+	assert.ErrorIs(t, s.List.Update(ctx, f.bob, f.listID, list, bookshelf.UpdateListInput{Title: &title}), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.Delete(ctx, f.bob, f.listID), bookshelf.ErrNotFound)
+
+	books, err := s.Book.GetAll(ctx, f.bob, f.listID, bookshelf.BookFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, books)
+	_, err = s.Book.GetByID(ctx, f.bob, f.bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.Book.GetByISBN(ctx, f.bob, "9780441172719")
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.Book.GetRole(ctx, f.bob, f.bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Update(ctx, f.bob, f.bookID, bookshelf.UpdateBookInput{Title: &title}), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Delete(ctx, f.bob, f.bookID), bookshelf.ErrNotFound)
+
+	// Nothing bob tried changed the data of alice.
+	got, err := s.List.GetByID(ctx, f.alice, f.listID)
+	assert.NoError(t, err)
+	assert.Equal(t, list, got)
+	book, err := s.Book.GetByID(ctx, f.alice, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Dune", book.Title)
+	lists, err = s.List.GetAll(ctx, f.alice, bookshelf.ListFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.List{list}, lists)
+}
+
+func testMembers(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	title := "Dune Messiah"
+
+	require.NoError(t, s.List.AddMember(ctx, f.listID, f.bob, bookshelf.RoleViewer))
+	_, err := s.List.GetByID(ctx, f.bob, f.listID)
+	assert.NoError(t, err)
+	_, err = s.Book.GetByID(ctx, f.bob, f.bookID)
+	assert.NoError(t, err)
+	role, err := s.Book.GetRole(ctx, f.bob, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.RoleViewer, role)
+	assert.ErrorIs(t, s.Book.Update(ctx, f.bob, f.bookID, bookshelf.UpdateBookInput{Title: &title}), bookshelf.ErrNotFound)
+
+	require.NoError(t, s.List.UpdateMember(ctx, f.listID, f.bob, bookshelf.RoleEditor))
+	assert.NoError(t, s.Book.Update(ctx, f.bob, f.bookID, bookshelf.UpdateBookInput{Title: &title}))
+	// Editors change books, but only the owner changes the list itself.
+	list, err := s.List.GetByID(ctx, f.bob, f.listID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.List.Update(ctx, f.bob, f.listID, list, bookshelf.UpdateListInput{Title: &title}), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.Delete(ctx, f.bob, f.listID), bookshelf.ErrNotFound)
+
+	members, err := s.List.GetMembers(ctx, f.listID)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookshelf.ListMember{
+		{UserID: f.alice, Username: "alice", Role: bookshelf.RoleOwner},
+		{UserID: f.bob, Username: "bob", Role: bookshelf.RoleEditor},
+	}, members)
+
+	require.NoError(t, s.List.RemoveMember(ctx, f.listID, f.bob))
+	_, err = s.List.GetByID(ctx, f.bob, f.listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.Book.GetByID(ctx, f.bob, f.bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+}
+
+// testSharedBook checks that an editor of one list cannot change a book that
+// is also part of a list they cannot edit.
+func testSharedBook(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	title := "Dune Messiah"
+	privateID, err := s.List.Create(ctx, f.alice, bookshelf.List{Title: "Private"})
+	require.NoError(t, err)
+	require.NoError(t, s.Book.Attach(ctx, privateID, f.bookID))
+	require.NoError(t, s.List.AddMember(ctx, f.listID, f.bob, bookshelf.RoleEditor))
+
+	role, err := s.Book.GetRole(ctx, f.bob, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.RoleViewer, role)
+	assert.ErrorIs(t, s.Book.Update(ctx, f.bob, f.bookID, bookshelf.UpdateBookInput{Title: &title}), bookshelf.ErrNotFound)
+	book, err := s.Book.GetByID(ctx, f.alice, f.bookID)
+	require.NoError(t, err)
+	assert.Equal(t, "Dune", book.Title)
+
+	role, err = s.Book.GetRole(ctx, f.alice, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.RoleOwner, role)
+	assert.NoError(t, s.Book.Update(ctx, f.alice, f.bookID, bookshelf.UpdateBookInput{Title: &title}))
+}
+
+func testLastOwner(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+
+	assert.ErrorIs(t, s.List.UpdateMember(ctx, f.listID, f.alice, bookshelf.RoleEditor), bookshelf.ErrConflict)
+	assert.ErrorIs(t, s.List.RemoveMember(ctx, f.listID, f.alice), bookshelf.ErrConflict)
+	role, err := s.List.GetRole(ctx, f.alice, f.listID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.RoleOwner, role)
+
+	require.NoError(t, s.List.AddMember(ctx, f.listID, f.bob, bookshelf.RoleOwner))
+	assert.NoError(t, s.List.UpdateMember(ctx, f.listID, f.alice, bookshelf.RoleEditor))
+	assert.ErrorIs(t, s.List.UpdateMember(ctx, f.listID, f.bob, bookshelf.RoleViewer), bookshelf.ErrConflict)
+	assert.ErrorIs(t, s.List.RemoveMember(ctx, f.listID, f.bob), bookshelf.ErrConflict)
+	assert.NoError(t, s.List.RemoveMember(ctx, f.listID, f.alice))
+}
+
+// testMerge checks that merging only rewrites the lists the user can edit.
+// bob keeps his copy in his private list, alice's lists get the canonical
+// book.
+func testMerge(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	privateID, err := s.List.Create(ctx, f.bob, bookshelf.List{Title: "Private"})
+	require.NoError(t, err)
+	sharedID, err := s.List.Create(ctx, f.bob, bookshelf.List{Title: "Shared"})
+	require.NoError(t, err)
+	require.NoError(t, s.List.AddMember(ctx, sharedID, f.alice, bookshelf.RoleEditor))
+	copyID, err := s.Book.Create(ctx, privateID, bookshelf.Book{UserID: f.bob, Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	require.NoError(t, s.Book.Attach(ctx, sharedID, copyID))
+	require.NoError(t, s.Book.Attach(ctx, f.listID, copyID))
+
+	listIDs, err := s.Book.Merge(ctx, f.alice, f.bookID, []int{copyID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.listID, sharedID}, listIDs)
+
+	books, err := s.Book.GetAll(ctx, f.bob, privateID, bookshelf.BookFilter{})
+	require.NoError(t, err)
+	if assert.Len(t, books, 1) {
+		assert.Equal(t, copyID, books[0].ID)
+	}
+	for _, listID := range listIDs {
+		books, err := s.Book.GetAll(ctx, f.alice, listID, bookshelf.BookFilter{})
+		require.NoError(t, err)
+		if assert.Len(t, books, 1) {
+			assert.Equal(t, f.bookID, books[0].ID)
+		}
+	}
+
+	_, err = s.Book.Merge(ctx, f.alice, f.bookID, []int{copyID})
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+}
+
+func testListDeleteCascade(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	otherListID, err := s.List.Create(ctx, f.alice, bookshelf.List{Title: "Favourites"})
+	require.NoError(t, err)
+	sharedID, err := s.Book.Create(ctx, f.listID, bookshelf.Book{UserID: f.alice, Title: "Emma", Author: "Jane Austen"})
+	require.NoError(t, err)
+	require.NoError(t, s.Book.Attach(ctx, otherListID, sharedID))
+	require.NoError(t, s.List.AddMember(ctx, f.listID, f.bob, bookshelf.RoleEditor))
+
+	require.NoError(t, s.List.Delete(ctx, f.alice, f.listID))
+
+	_, err = s.List.GetByID(ctx, f.alice, f.listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.List.GetRole(ctx, f.bob, f.listID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	members, err := s.List.GetMembers(ctx, f.listID)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+
+	// The book only the deleted list held is gone, the shared one stays.
+	_, err = s.Book.GetByID(ctx, f.alice, f.bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	count, err := s.Book.CountLists(ctx, f.bookID)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	book, err := s.Book.GetByID(ctx, f.alice, sharedID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Emma", book.Title)
+	count, err = s.Book.CountLists(ctx, sharedID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// The ISBN of the deleted book is free again.
+	_, err = s.Book.Create(ctx, otherListID, bookshelf.Book{UserID: f.alice, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"})
+	assert.NoError(t, err)
+}
+
+func testBookDelete(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	bobListID, err := s.List.Create(ctx, f.bob, bookshelf.List{Title: "Bob's"})
+	require.NoError(t, err)
+	require.NoError(t, s.Book.Attach(ctx, bobListID, f.bookID))
+
+	// Deleting only removes the book from the lists of the user.
+	require.NoError(t, s.Book.Delete(ctx, f.alice, f.bookID))
+	_, err = s.Book.GetByID(ctx, f.alice, f.bookID)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	book, err := s.Book.GetByID(ctx, f.bob, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Dune", book.Title)
+
+	require.NoError(t, s.Book.Delete(ctx, f.bob, f.bookID))
+	assert.ErrorIs(t, s.Book.Delete(ctx, f.bob, f.bookID), bookshelf.ErrNotFound)
+}
+
+func testPartialUpdate(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	title := "Currently reading"
+	pageCount := 896
+	empty := ""
+
+	list, err := s.List.GetByID(ctx, f.alice, f.listID)
+	require.NoError(t, err)
+	require.NoError(t, s.List.Update(ctx, f.alice, f.listID, list, bookshelf.UpdateListInput{Title: &title}))
+	list, err = s.List.GetByID(ctx, f.alice, f.listID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.List{ID: f.listID, Title: "Currently reading", Description: "Books I am reading"}, list)
+
+	require.NoError(t, s.Book.Update(ctx, f.alice, f.bookID, bookshelf.UpdateBookInput{PageCount: &pageCount}))
+	book, err := s.Book.GetByID(ctx, f.alice, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, bookshelf.Book{
+		ID:              f.bookID,
+		Title:           "Dune",
+		Author:          "Frank Herbert",
+		Publisher:       "Chilton Books",
+		PublicationYear: 1965,
+		PageCount:       896,
+		ISBN:            "9780441172719",
+	}, book)
+
+	// An empty ISBN clears it.
+	require.NoError(t, s.Book.Update(ctx, f.alice, f.bookID, bookshelf.UpdateBookInput{ISBN: &empty}))
+	book, err = s.Book.GetByID(ctx, f.alice, f.bookID)
+	assert.NoError(t, err)
+	assert.Empty(t, book.ISBN)
+	assert.Equal(t, 896, book.PageCount)
+}
+
+func testNotFound(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+	const missing = 1 << 30
+	title := "title"
+
+	_, err := s.GetUser(ctx, "carol")
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.List.GetByID(ctx, f.alice, missing)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.Update(ctx, f.alice, missing, bookshelf.List{}, bookshelf.UpdateListInput{Title: &title}), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.Delete(ctx, f.alice, missing), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.AddMember(ctx, f.listID, missing, bookshelf.RoleViewer), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.AddMember(ctx, missing, f.bob, bookshelf.RoleViewer), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.UpdateMember(ctx, f.listID, f.bob, bookshelf.RoleEditor), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.List.RemoveMember(ctx, f.listID, f.bob), bookshelf.ErrNotFound)
+
+	_, err = s.Book.Create(ctx, missing, bookshelf.Book{UserID: f.alice, Title: "Emma", Author: "Jane Austen"})
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	_, err = s.Book.GetByID(ctx, f.alice, missing)
+	assert.ErrorIs(t, err, bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Update(ctx, f.alice, missing, bookshelf.UpdateBookInput{Title: &title}), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Delete(ctx, f.alice, missing), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Attach(ctx, f.listID, missing), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Attach(ctx, missing, f.bookID), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Detach(ctx, f.listID, missing), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Move(ctx, f.listID, missing, f.bookID), bookshelf.ErrNotFound)
+	assert.ErrorIs(t, s.Book.Move(ctx, missing, f.listID, f.bookID), bookshelf.ErrNotFound)
+
+	// A failed move leaves the book where it was.
+	count, err := s.Book.CountLists(ctx, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testConflict(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	f := newFixture(t, s)
+
+	_, err := s.CreateUser(ctx, bookshelf.User{Username: "alice", Password: "alice-hash"})
+	assert.ErrorIs(t, err, bookshelf.ErrConflict)
+	assert.ErrorIs(t, s.List.AddMember(ctx, f.listID, f.alice, bookshelf.RoleViewer), bookshelf.ErrConflict)
+	_, err = s.Book.Create(ctx, f.listID, bookshelf.Book{UserID: f.alice, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"})
+	assert.ErrorIs(t, err, bookshelf.ErrConflict)
+
+	// ISBNs are unique per user, bob can have his own copy.
+	bobListID, err := s.List.Create(ctx, f.bob, bookshelf.List{Title: "Bob's"})
+	require.NoError(t, err)
+	_, err = s.Book.Create(ctx, bobListID, bookshelf.Book{UserID: f.bob, Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"})
+	assert.NoError(t, err)
+
+	// The last list of a book cannot let go of it.
+	assert.ErrorIs(t, s.Book.Detach(ctx, f.listID, f.bookID), bookshelf.ErrConflict)
+
+	// Attaching a book twice is not an error.
+	assert.NoError(t, s.Book.Attach(ctx, f.listID, f.bookID))
+	count, err := s.Book.CountLists(ctx, f.bookID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,34 @@
+package bookshelf
+
+import "strings"
+
+const (
+	SearchKindBook = "book"
+	SearchKindList = "list"
+)
+
+// SearchHit is a book or list matching a search query. Headline is an
+// excerpt with the matched words wrapped in <mark> tags, the rest of the
+// text is HTML escaped so the headline can be rendered as is.
+type SearchHit struct {
+	Kind     string  `json:"kind"`
+	ID       int     `json:"id"`
+	Title    string  `json:"title"`
+	Headline string  `json:"headline"`
+	Rank     float64 `json:"rank"`
+}
+
+// SearchQuery is a full-text query in web search syntax: quoted phrases, OR
+// and a leading - to exclude a word.
+type SearchQuery struct {
+	Query string
+	Limit int
+	After *Cursor
+}
+
+func (q SearchQuery) Validate() error {
+	if strings.TrimSpace(q.Query) == "" {
+		return NewError(ErrValidation, "empty_query", "search query is empty")
+	}
+	return validatePage("", nil, q.Limit, q.After)
+}
@@ -0,0 +1,19 @@
+package bookshelf
+
+import "time"
+
+// ListShare is a public read-only link to a list. Only the hash of the link
+// token is stored.
+type ListShare struct {
+	ID        int        `json:"id"`
+	ListID    int        `json:"list_id"`
+	CreatedBy int        `json:"-"`
+	TokenHash string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   bool       `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type CreateListShareInput struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
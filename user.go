@@ -3,5 +3,6 @@ package bookshelf
 type User struct {
 	ID       int    `json:"-" db:"id"`
 	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	// Password is capped at the 72 bytes bcrypt can hash.
+	Password string `json:"password" validate:"required,max=72"`
 }